@@ -0,0 +1,358 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rah-0/lunar/docs"
+	"github.com/rah-0/lunar/internal/api"
+	"github.com/rah-0/lunar/internal/audit"
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/config"
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/graph"
+	"github.com/rah-0/lunar/internal/metrics"
+	"github.com/rah-0/lunar/internal/openapi"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// Secrets of the API keys used by the contract tests
+const (
+	adminSecret = "admin-secret"
+	readSecret  = "read-secret"
+)
+
+// newContractMux registers every route with authentication enabled
+func newContractMux(t *testing.T) *openapi.Mux {
+	t.Helper()
+
+	key := func(name, secret string, scope auth.Scope) auth.Key {
+		hash, err := auth.ParseHash(auth.HashKey(secret))
+		if err != nil {
+			t.Fatalf("Failed to hash key: %v", err)
+		}
+		return auth.Key{Name: name, Hash: hash, Scopes: []auth.Scope{scope}}
+	}
+	authenticator := auth.NewAuthenticator(true, []auth.Key{
+		key("admin", adminSecret, auth.ScopeAdmin),
+		key("reader", readSecret, auth.ScopeRead),
+	})
+
+	repo := storage.NewInMemoryRepository()
+	handler := api.NewHandler(repo)
+	handler.Auth = authenticator
+	handler.DeadLetters = deadletter.NewStore(deadletter.DefaultCapacity)
+
+	cfg := config.Default()
+	adminHandler := api.NewAdminHandler(config.NewStore(cfg, func() (*config.Config, error) { return cfg, nil }, slog.Default()))
+	adminHandler.Rockets = repo
+	adminHandler.Messages = handler
+	adminHandler.Audit = audit.NewTrail(audit.DefaultCapacity, slog.Default())
+	adminHandler.Auth = authenticator
+
+	graphHandler, err := graph.NewHandler(repo, graph.Limits{MaxDepth: 8, MaxComplexity: 5000})
+	if err != nil {
+		t.Fatalf("Failed to create GraphQL handler: %v", err)
+	}
+	graphHandler.Auth = authenticator
+
+	return newMux(handler, adminHandler, graphHandler, authenticator.Require(auth.ScopeRead, metrics.NewMetrics().Handler()))
+}
+
+// servedSpec fetches the document served at /openapi.json
+func servedSpec(t *testing.T, mux *openapi.Mux) map[string]any {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected /openapi.json to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var spec map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("Failed to decode the spec: %v", err)
+	}
+	return spec
+}
+
+// operations lists the "method path" of every operation of a document
+func operations(spec map[string]any) []string {
+	var ops []string
+	for path, item := range spec["paths"].(map[string]any) {
+		for method := range item.(map[string]any) {
+			ops = append(ops, method+" "+path)
+		}
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func TestSpecCoversRoutes(t *testing.T) {
+	mux := newContractMux(t)
+	spec := servedSpec(t, mux)
+
+	// Every registered route needs annotations
+	for path, item := range spec["paths"].(map[string]any) {
+		for method, operation := range item.(map[string]any) {
+			if operation.(map[string]any)["x-undocumented"] == true {
+				t.Errorf("Route %s %s has no annotations; run swag after documenting it", strings.ToUpper(method), path)
+			}
+		}
+	}
+
+	// Every annotated operation needs a route
+	var generated map[string]any
+	if err := json.Unmarshal([]byte(docs.SwaggerInfo.ReadDoc()), &generated); err != nil {
+		t.Fatalf("Failed to decode the generated document: %v", err)
+	}
+	served := operations(spec)
+	for _, op := range operations(generated) {
+		i := sort.SearchStrings(served, op)
+		if i == len(served) || served[i] != op {
+			t.Errorf("Operation %s is documented but not registered", op)
+		}
+	}
+}
+
+func TestHandlersMatchSpec(t *testing.T) {
+	mux := newContractMux(t)
+	spec := servedSpec(t, mux)
+
+	launch := `{"metadata":{"channel":"rocket-1","messageNumber":1,"messageTime":"2025-01-01T00:00:00Z","messageType":"RocketLaunched"},"message":{"type":"Falcon-9","launchSpeed":500,"mission":"ARTEMIS"}}`
+	explode := `{"metadata":{"channel":"rocket-1","messageNumber":2,"messageTime":"2025-01-01T00:00:01Z","messageType":"RocketExploded"},"message":{"reason":"PRESSURE_VESSEL_FAILURE"}}`
+	outOfOrder := `{"metadata":{"channel":"rocket-1","messageNumber":3,"messageTime":"2025-01-01T00:00:02Z","messageType":"RocketSpeedIncreased"},"message":{"by":100}}`
+
+	tests := []struct {
+		method string
+		target string
+		secret string
+		header map[string]string
+		body   string
+		want   int
+	}{
+		{"GET", "/", "", nil, "", http.StatusFound},
+		{"GET", "/health", "", nil, "", http.StatusOK},
+		{"GET", "/openapi.json", "", nil, "", http.StatusOK},
+		{"GET", "/swagger", "", nil, "", http.StatusFound},
+		{"GET", "/swagger/", "", nil, "", http.StatusOK},
+		{"GET", "/swagger/missing.js", "", nil, "", http.StatusNotFound},
+		{"GET", "/graphiql", "", nil, "", http.StatusOK},
+		{"POST", "/messages", adminSecret, nil, launch, http.StatusAccepted},
+		{"POST", "/messages", adminSecret, nil, `{"metadata":{}}`, http.StatusBadRequest},
+		{"POST", "/messages", readSecret, nil, launch, http.StatusForbidden},
+		{"POST", "/messages", "", nil, launch, http.StatusUnauthorized},
+		{"GET", "/rockets", readSecret, nil, "", http.StatusOK},
+		{"GET", "/rockets?format=csv", readSecret, nil, "", http.StatusOK},
+		{"GET", "/rockets?format=ndjson&columns=id,speed", readSecret, nil, "", http.StatusOK},
+		{"GET", "/rockets?format=xml", readSecret, nil, "", http.StatusBadRequest},
+		{"GET", "/rockets/rocket-1", readSecret, nil, "", http.StatusOK},
+		{"GET", "/rockets/rocket-1", readSecret, map[string]string{"If-None-Match": `"1"`}, "", http.StatusNotModified},
+		{"GET", "/rockets/rocket-2", readSecret, nil, "", http.StatusNotFound},
+		{"GET", "/graphql?query=%7Bstats%7Btotal%7D%7D", readSecret, nil, "", http.StatusOK},
+		{"POST", "/graphql", readSecret, nil, `{"query":"{ rocket(id: \"rocket-1\") { id speed } }"}`, http.StatusOK},
+		{"POST", "/graphql", readSecret, nil, `{"query":"{ rockets { color } }"}`, http.StatusBadRequest},
+		{"GET", "/metrics", readSecret, nil, "", http.StatusOK},
+		{"GET", "/admin/config", adminSecret, nil, "", http.StatusOK},
+		{"POST", "/admin/config/reload", adminSecret, nil, "", http.StatusOK},
+		{"POST", "/messages", adminSecret, nil, outOfOrder, http.StatusAccepted},
+		{"DELETE", "/admin/rockets/rocket-1/buffer", adminSecret, nil, "", http.StatusOK},
+		{"POST", "/admin/rockets/rocket-1/cursor/reset", adminSecret, nil, `{"messageNumber":0}`, http.StatusOK},
+		{"POST", "/admin/rockets/rocket-1/cursor/reset", adminSecret, nil, `{"messageNumber":-1}`, http.StatusBadRequest},
+		{"POST", "/admin/rockets/purge", adminSecret, nil, `{}`, http.StatusBadRequest},
+		{"POST", "/admin/rockets/purge", adminSecret, nil, `{"mission":"apollo"}`, http.StatusOK},
+		{"PATCH", "/admin/rockets/rocket-1", adminSecret, map[string]string{"If-Match": `"2"`}, `{"mission":"APOLLO","speed":0}`, http.StatusOK},
+		{"PATCH", "/admin/rockets/rocket-1", adminSecret, map[string]string{"If-Match": `"2"`}, `{"mission":"ARTEMIS"}`, http.StatusPreconditionFailed},
+		{"PATCH", "/admin/rockets/rocket-1", adminSecret, nil, `{"mission":"ARTEMIS"}`, http.StatusPreconditionRequired},
+		{"POST", "/admin/rockets/rocket-1/pause", adminSecret, nil, "", http.StatusOK},
+		{"GET", "/rockets/rocket-1", readSecret, nil, "", http.StatusOK},
+		{"POST", "/admin/rockets/rocket-1/resume", adminSecret, nil, "", http.StatusOK},
+		{"GET", "/admin/rockets/rocket-1/replay", adminSecret, nil, "", http.StatusOK},
+		{"GET", "/admin/rockets/missing/replay", adminSecret, nil, "", http.StatusNotFound},
+		{"POST", "/admin/rockets/missing/pause", adminSecret, nil, "", http.StatusNotFound},
+		{"DELETE", "/admin/rockets/rocket-1", readSecret, nil, "", http.StatusForbidden},
+		{"DELETE", "/admin/rockets/rocket-1", adminSecret, nil, "", http.StatusNoContent},
+		{"DELETE", "/admin/rockets/rocket-1", adminSecret, nil, "", http.StatusNotFound},
+		{"GET", "/admin/audit?limit=5", adminSecret, nil, "", http.StatusOK},
+		{"GET", "/admin/audit?limit=none", adminSecret, nil, "", http.StatusBadRequest},
+		{"POST", "/messages", adminSecret, nil, `{`, http.StatusBadRequest},
+		{"GET", "/admin/deadletters?stage=validate", adminSecret, nil, "", http.StatusOK},
+		{"GET", "/admin/deadletters?stage=send", adminSecret, nil, "", http.StatusBadRequest},
+		{"GET", "/admin/deadletters/1", adminSecret, nil, "", http.StatusOK},
+		{"GET", "/admin/deadletters/99", adminSecret, nil, "", http.StatusNotFound},
+		{"POST", "/admin/deadletters/1/resubmit", adminSecret, nil, "", http.StatusBadRequest},
+		{"POST", "/admin/deadletters/1/resubmit", adminSecret, nil, launch, http.StatusOK},
+		{"POST", "/admin/deadletters/1/resubmit", adminSecret, nil, "", http.StatusNotFound},
+		{"DELETE", "/admin/deadletters/2", adminSecret, nil, "", http.StatusNoContent},
+		{"DELETE", "/admin/deadletters/2", adminSecret, nil, "", http.StatusNotFound},
+		{"POST", "/messages", adminSecret, nil, explode, http.StatusAccepted},
+		{"POST", "/messages", adminSecret, nil, outOfOrder, http.StatusAccepted},
+		{"GET", "/rockets/rocket-1/quarantine", readSecret, nil, "", http.StatusOK},
+		{"GET", "/rockets/rocket-2/quarantine", readSecret, nil, "", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		name := tt.method + " " + tt.target
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		if tt.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if tt.secret != "" {
+			req.Header.Set(auth.APIKeyHeader, tt.secret)
+		}
+		for key, value := range tt.header {
+			req.Header.Set(key, value)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", name, tt.want, rec.Code, rec.Body.String())
+			continue
+		}
+
+		// Find the operation of the route that served the request
+		_, pattern := mux.Handler(req)
+		route := openapi.ParsePattern(pattern)
+		item, _ := spec["paths"].(map[string]any)[route.Path].(map[string]any)
+		operation, ok := item[route.Method].(map[string]any)
+		if !ok {
+			t.Errorf("%s: route %q is not in the spec", name, pattern)
+			continue
+		}
+
+		responses := operation["responses"].(map[string]any)
+		response, ok := responses[strconv.Itoa(rec.Code)].(map[string]any)
+		if !ok {
+			response, ok = responses["default"].(map[string]any)
+		}
+		if !ok {
+			t.Errorf("%s: status %d is not documented", name, rec.Code)
+			continue
+		}
+
+		mediaType, _, _ := mime.ParseMediaType(rec.Header().Get("Content-Type"))
+		if produces, _ := operation["produces"].([]any); rec.Code < 300 && len(produces) > 0 && !containsValue(produces, mediaType) {
+			t.Errorf("%s: content type %q is not in %v", name, mediaType, produces)
+		}
+
+		// JSON bodies must match the documented schema
+		schema, _ := response["schema"].(map[string]any)
+		if schema == nil || (mediaType != "application/json" && mediaType != "application/problem+json") {
+			continue
+		}
+		var body any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Errorf("%s: invalid JSON body: %v", name, err)
+			continue
+		}
+		for _, problem := range validate(spec, schema, body, "$") {
+			t.Errorf("%s: %s", name, problem)
+		}
+	}
+}
+
+// validate checks value against a Swagger 2.0 schema, resolving references
+// to the definitions of spec. Objects may only hold declared properties, so
+// that fields added to a model without regenerating the spec are reported.
+// Optional properties may be null.
+func validate(spec map[string]any, schema map[string]any, value any, path string) []string {
+	if ref, ok := schema["$ref"].(string); ok {
+		name := strings.TrimPrefix(ref, "#/definitions/")
+		definition, ok := spec["definitions"].(map[string]any)[name].(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: unknown definition %q", path, name)}
+		}
+		return validate(spec, definition, value, path)
+	}
+	if all, ok := schema["allOf"].([]any); ok {
+		var problems []string
+		for _, part := range all {
+			problems = append(problems, validate(spec, part.(map[string]any), value, path)...)
+		}
+		return problems
+	}
+
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		return []string{fmt.Sprintf("%s: %v is not one of %v", path, value, enum)}
+	}
+
+	switch schema["type"] {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %T", path, value)}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		required, _ := schema["required"].([]any)
+		var problems []string
+		for _, key := range required {
+			if _, ok := object[key.(string)]; !ok {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %q", path, key))
+			}
+		}
+		for key, field := range object {
+			switch {
+			case field == nil && !containsValue(required, key):
+				// Swagger 2.0 cannot mark properties as nullable, and nil
+				// slices and maps of optional properties encode as null
+			case properties[key] != nil:
+				problems = append(problems, validate(spec, properties[key].(map[string]any), field, path+"."+key)...)
+			case additional != nil:
+				problems = append(problems, validate(spec, additional, field, path+"."+key)...)
+			case properties != nil:
+				problems = append(problems, fmt.Sprintf("%s: undocumented property %q", path, key))
+			}
+		}
+		return problems
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %T", path, value)}
+		}
+		items, _ := schema["items"].(map[string]any)
+		var problems []string
+		for i, item := range array {
+			if items != nil {
+				problems = append(problems, validate(spec, items, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+		return problems
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{fmt.Sprintf("%s: expected a string, got %T", path, value)}
+		}
+	case "integer":
+		if number, ok := value.(float64); !ok || number != math.Trunc(number) {
+			return []string{fmt.Sprintf("%s: expected an integer, got %v", path, value)}
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return []string{fmt.Sprintf("%s: expected a number, got %T", path, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected a boolean, got %T", path, value)}
+		}
+	}
+	return nil
+}
+
+// containsValue reports whether values holds value
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -2,70 +2,309 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/rah-0/lunar/docs"
 	"github.com/rah-0/lunar/internal/api"
+	"github.com/rah-0/lunar/internal/audit"
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/config"
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/graph"
+	"github.com/rah-0/lunar/internal/logging"
+	"github.com/rah-0/lunar/internal/messages"
+	"github.com/rah-0/lunar/internal/metrics"
+	"github.com/rah-0/lunar/internal/openapi"
+	"github.com/rah-0/lunar/internal/ratelimit"
+	"github.com/rah-0/lunar/internal/rpc"
+	"github.com/rah-0/lunar/internal/signing"
 	"github.com/rah-0/lunar/internal/storage"
+	"github.com/rah-0/lunar/internal/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
-// Command line flags
-var (
-	port = flag.Int("port", 8088, "Port to listen on")
-)
+// @description Errors are RFC 7807 problem details (application/problem+json) with a stable code:
+// @description invalid_payload (400), validation_failed (400), unknown_message_type (400), invalid_request (400),
+// @description invalid_signature (401), unauthorized (401), forbidden (403), channel_forbidden (403), not_found (404),
+// @description version_mismatch (412), payload_too_large (413), config_rejected (422), version_required (428), rate_limited (429) and internal_error (500).
+// @description The type is urn:lunar:problem:<code>, and invalid fields are listed in errors with their JSON path.
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description API key with the scope required by the route; "Authorization: Bearer <key>" is accepted as well
 func main() {
-	flag.Parse()
+	// Load configuration from file, environment and flags
+	loadConfig := func() (*config.Config, error) { return config.Load(os.Args[1:], os.Getenv) }
+	cfg, err := loadConfig()
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Initialize structured logging
+	level, _ := logging.ParseLevel(cfg.Log.Level) // Already validated
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(level)
+	logger, err := logging.New(os.Stderr, cfg.Log.Format, levelVar)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	// Keep the configuration in a store so it can be reloaded at runtime
+	configStore := config.NewStore(cfg, loadConfig, logger)
+	configStore.OnReload(func(c *config.Config) {
+		level, _ := logging.ParseLevel(c.Log.Level)
+		levelVar.Set(level)
+	})
+
+	// Message types drive both API validation and how state is updated
+	registry := messages.Builtin()
+
+	// Decode messages strictly or leniently; the mode is swapped on reload
+	decoder := messages.NewDecoder(registry, cfg.Decoding.DecodingMode(), logger)
+	configStore.OnReload(func(c *config.Config) {
+		decoder.SetMode(c.Decoding.DecodingMode())
+	})
 
 	// Initialize the storage repository
 	repository := storage.NewInMemoryRepository()
+	repository.SetRegistry(registry)
+
+	// Initialize operational metrics and instrument the repository
+	serviceMetrics := metrics.NewMetrics()
+	repository.SetObserver(serviceMetrics)
+	serviceMetrics.RegisterRocketCount(func() int {
+		count, _ := repository.RocketCount(context.Background())
+		return count
+	})
+	serviceMetrics.RegisterUnknownFields(decoder.UnknownFields)
+	if cfg.Metrics.Domain.Enabled {
+		opts := metrics.DefaultDomainOptions()
+		opts.MaxRockets = cfg.Metrics.Domain.MaxRockets
+		opts.StaleAfter = cfg.Metrics.Domain.StaleAfter.Std()
+		domainGauges := serviceMetrics.RegisterDomainGauges(repository, opts)
+		configStore.OnReload(func(c *config.Config) {
+			opts.MaxRockets = c.Metrics.Domain.MaxRockets
+			opts.StaleAfter = c.Metrics.Domain.StaleAfter.Std()
+			domainGauges.SetOptions(opts)
+		})
+	}
+
+	// Initialize API key authentication; keys are swapped on reload
+	authenticator := auth.NewAuthenticator(cfg.Auth.Enabled, cfg.Auth.APIKeys())
+	configStore.OnReload(func(c *config.Config) {
+		authenticator.Update(c.Auth.Enabled, c.Auth.APIKeys())
+	})
 
-	// Create the API handler
+	// Restrict client certificates to their channels; lists are swapped on reload
+	channelPolicy := transport.NewChannelPolicy(cfg.Server.TLS.ChannelPolicy())
+	configStore.OnReload(func(c *config.Config) {
+		channelPolicy.Update(c.Server.TLS.ChannelPolicy())
+	})
+
+	// Verify message signatures; secrets and mode are swapped on reload
+	verifier := signing.NewVerifier(cfg.Signing.Settings(), serviceMetrics)
+	configStore.OnReload(func(c *config.Config) {
+		verifier.Update(c.Signing.Settings())
+	})
+
+	// Rate limit ingestion per client and per channel; limits are swapped on reload
+	clientLimiter := ratelimit.NewLimiter(cfg.RateLimit.ClientLimit())
+	channelLimiter := ratelimit.NewLimiter(cfg.RateLimit.ChannelLimit())
+	configStore.OnReload(func(c *config.Config) {
+		clientLimiter.Update(c.RateLimit.ClientLimit())
+		channelLimiter.Update(c.RateLimit.ChannelLimit())
+	})
+
+	// Forget limiter state of idle clients and channels
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			idle := configStore.Current().RateLimit.IdleTimeout.Std()
+			clientLimiter.Sweep(idle)
+			channelLimiter.Sweep(idle)
+		}
+	}()
+
+	// Keep the messages that could not be processed for inspection and resubmission
+	deadLetters := deadletter.NewStore(deadletter.DefaultCapacity)
+	serviceMetrics.RegisterDeadLetters(deadLetters.Len)
+	repository.SetRejectionHandler(api.DeadLetterRejections(deadLetters))
+
+	// Create the API handlers
 	handler := api.NewHandler(repository)
+	handler.Decoder = decoder
+	handler.Auth = authenticator
+	handler.Channels = channelPolicy
+	handler.Signatures = verifier
+	handler.ClientLimiter = clientLimiter
+	handler.ChannelLimiter = channelLimiter
+	handler.DeadLetters = deadLetters
+	adminHandler := api.NewAdminHandler(configStore)
+	adminHandler.Rockets = repository
+	adminHandler.Messages = handler
+	adminHandler.Audit = audit.NewTrail(audit.DefaultCapacity, logger)
+	adminHandler.Auth = authenticator
 
-	// Create a new HTTP server mux
-	mux := http.NewServeMux()
+	// Serve GraphQL queries; the query limits are swapped on reload
+	graphHandler, err := graph.NewHandler(repository, cfg.GraphQL.Limits())
+	if err != nil {
+		logger.Error("Failed to build the GraphQL schema", "error", err)
+		os.Exit(1)
+	}
+	graphHandler.Auth = authenticator
+	configStore.OnReload(func(c *config.Config) {
+		graphHandler.Update(c.GraphQL.Limits())
+	})
 
-	// Register routes
-	handler.RegisterRoutes(mux)
+	// Register routes on a mux that documents them at /openapi.json
+	mux := newMux(handler, adminHandler, graphHandler, authenticator.Require(auth.ScopeRead, serviceMetrics.Handler()))
 
 	// Create HTTP server
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%d", *port),
-		Handler: mux,
+		Addr:    fmt.Sprintf(":%d", cfg.Server.Port),
+		Handler: logging.Middleware(logger)(serviceMetrics.Middleware(mux)),
+	}
+
+	// Serve HTTPS with certificates that are reloaded when their files change
+	var certReloader *transport.CertReloader
+	if cfg.Server.TLS.Enabled {
+		tlsConfig := cfg.Server.TLS
+		certReloader, err = transport.NewCertReloader(tlsConfig.CertFile, tlsConfig.KeyFile, tlsConfig.ClientCAFile, logger)
+		if err != nil {
+			logger.Error("Failed to load TLS certificates", "error", err)
+			os.Exit(1)
+		}
+		clientAuth, _ := transport.ParseClientAuth(tlsConfig.ClientAuth) // Validated with the configuration
+		server.TLSConfig = certReloader.TLSConfig(clientAuth)
+
+		watchCtx, stopWatch := context.WithCancel(context.Background())
+		defer stopWatch()
+		go certReloader.Watch(watchCtx, tlsConfig.ReloadInterval.Std())
+	}
+
+	// Serve the gRPC API next to the REST API, sharing the repository and policies
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPC.Enabled {
+		rpcServer := rpc.NewServer(repository)
+		rpcServer.Decoder = decoder
+		rpcServer.Auth = authenticator
+		rpcServer.Channels = channelPolicy
+		rpcServer.Signatures = verifier
+		rpcServer.ClientLimiter = clientLimiter
+		rpcServer.ChannelLimiter = channelLimiter
+		rpcServer.DeadLetters = deadLetters
+
+		var opts []grpc.ServerOption
+		if certReloader != nil {
+			opts = append(opts, grpc.Creds(credentials.NewTLS(server.TLSConfig)))
+		}
+		grpcServer = rpcServer.Register(opts...)
+
+		listener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPC.Port))
+		if err != nil {
+			logger.Error("Failed to listen for gRPC", "error", err)
+			os.Exit(1)
+		}
+		go func() {
+			logger.Info("gRPC server starting", "port", cfg.Server.GRPC.Port, "tls", cfg.Server.TLS.Enabled)
+			if err := grpcServer.Serve(listener); err != nil {
+				logger.Error("gRPC server error", "error", err)
+				os.Exit(1)
+			}
+		}()
 	}
 
 	// Create a channel to listen for OS signals
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	// Reload the configuration on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			logger.Info("Received SIGHUP, reloading configuration")
+			_, _ = configStore.Reload() // Outcome is logged by the store
+			if certReloader != nil {
+				if err := certReloader.Reload(); err != nil {
+					logger.Error("TLS certificate reload failed, keeping the previous certificate", "error", err)
+				}
+			}
+		}
+	}()
+
 	// Start the server in a goroutine
 	go func() {
-		log.Printf("Server starting on port %d...", *port)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server error: %v", err)
+		logger.Info("Server starting", "port", cfg.Server.Port, "tls", cfg.Server.TLS.Enabled)
+		var err error
+		if certReloader != nil {
+			err = server.ListenAndServeTLS("", "") // Certificates come from server.TLSConfig
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			logger.Error("Server error", "error", err)
+			os.Exit(1)
 		}
 	}()
 
 	// Wait for termination signal
 	<-stop
 
-	log.Println("Shutting down server...")
+	logger.Info("Shutting down server")
 
 	// Create a context with a timeout for graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout.Std())
 	defer cancel()
 
 	// Attempt graceful shutdown
+	var grpcStopped chan struct{}
+	if grpcServer != nil {
+		grpcStopped = make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	}
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		logger.Error("Server forced to shutdown", "error", err)
+		os.Exit(1)
+	}
+	if grpcServer != nil {
+		select {
+		case <-grpcStopped:
+		case <-ctx.Done():
+			grpcServer.Stop() // Streams such as WatchRockets only end when cancelled
+		}
 	}
 
-	log.Println("Server exited gracefully")
+	logger.Info("Server exited gracefully")
+}
+
+// newMux registers every HTTP route on a mux serving their OpenAPI document
+func newMux(handler *api.Handler, adminHandler *api.AdminHandler, graphHandler *graph.Handler, metricsHandler http.HandlerFunc) *openapi.Mux {
+	mux := openapi.NewMux(docs.SwaggerInfo.ReadDoc())
+	handler.RegisterRoutes(mux)
+	adminHandler.RegisterRoutes(mux)
+	graphHandler.RegisterRoutes(mux)
+	mux.HandleFunc("GET /metrics", metricsHandler)
+	return mux
 }
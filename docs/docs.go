@@ -15,146 +15,1932 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/health": {
+        "/": {
             "get": {
-                "description": "Returns 200 OK when the service is healthy",
+                "description": "Redirects to the Swagger UI",
+                "tags": [
+                    "docs"
+                ],
+                "summary": "Root",
+                "responses": {
+                    "302": {
+                        "description": "Redirect to /swagger/"
+                    }
+                }
+            }
+        },
+        "/admin/audit": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the latest admin actions, newest first: who did what to which rocket, and when. Actions are also written to the log.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "system"
+                    "admin"
+                ],
+                "summary": "Get the audit trail",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of entries; every entry kept by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Health check",
                 "responses": {
                     "200": {
-                        "description": "Service status",
+                        "description": "entries, newest first",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
-                                "type": "string"
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/audit.Entry"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_request for an invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/config": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the configuration currently in effect, with secret values redacted",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get effective configuration",
+                "responses": {
+                    "200": {
+                        "description": "Effective configuration",
+                        "schema": {
+                            "$ref": "#/definitions/config.Config"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/config/reload": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Reloads the configuration from its sources and applies the reloadable fields. Invalid configurations are rejected and the current one is kept. Both outcomes are recorded in the audit trail.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reload configuration",
+                "responses": {
+                    "200": {
+                        "description": "Fields that changed and whether they were applied",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "422": {
+                        "description": "config_rejected when the new configuration is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/deadletters": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the messages ingestion could not process, newest first: bodies that are not JSON envelopes (decode), envelopes that failed validation (validate) and updates the repository rejected (apply). Each letter keeps the raw body, the reason, the time and the client. The latest 1000 letters are kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "List dead letters",
+                "parameters": [
+                    {
+                        "enum": [
+                            "decode",
+                            "validate",
+                            "apply"
+                        ],
+                        "type": "string",
+                        "description": "Stage at which the messages failed",
+                        "name": "stage",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Channel of the messages (case-insensitive)",
+                        "name": "channel",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Message type (case-insensitive)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Client, as key:\u003cname\u003e or ip:\u003caddress\u003e (case-insensitive)",
+                        "name": "client",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Maximum number of letters; every letter by default",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "deadLetters, newest first",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "$ref": "#/definitions/deadletter.Letter"
+                                }
                             }
                         }
+                    },
+                    "400": {
+                        "description": "invalid_request for an unknown stage or an invalid limit",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/deadletters/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns a message ingestion could not process, with its raw body and why it failed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get a dead letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Dead letter",
+                        "schema": {
+                            "$ref": "#/definitions/deadletter.Letter"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Discards a message that will not be resubmitted. The action is recorded in the audit trail.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a dead letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Dead letter deleted"
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/deadletters/{id}/resubmit": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Processes the message of a dead letter again, like POST /messages but without the signature, channel and rate limit checks. The request body, when present, is a fixed envelope that replaces the stored body; without one the stored body is resubmitted as is, for example once the rocket can accept it. The letter is deleted when the message is no longer rejected, and otherwise updated with the new body and reason and returned as deadLetter. The action is recorded in the audit trail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Resubmit a dead letter",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Dead letter ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Fixed envelope",
+                        "name": "message",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Processing status of the message as for POST /messages, with the updated deadLetter when it was rejected again",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_payload, validation_failed or unknown_message_type when the message is still invalid, which updates the letter, or invalid_request for a body that is too large",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rockets/purge": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes every rocket matching the filter, like DELETE /admin/rockets/{id} does for one. At least one criterion is required, so that nothing is purged by mistake. The action is recorded in the audit trail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Purge rockets",
+                "parameters": [
+                    {
+                        "description": "Rockets to purge",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.PurgeRequest"
+                        }
                     }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "IDs of the purged rockets and their count",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_request for an invalid body, an empty filter or an unknown status",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rockets/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Forgets a rocket with its buffered messages and message cursor, so that its next message creates it again, with a version above the deleted one's so that its old ETags never match. Long polls waiting for the rocket end with 404. The action is recorded in the audit trail.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete a rocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Rocket deleted"
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Corrects the type, speed or mission of a rocket when the emitter got them wrong, for example after a missed RocketMissionChanged. If-Match must carry the ETag of the version the correction is based on. The correction is applied as a synthetic RocketStateOverridden message, which increments the version without touching the message cursor, and each corrected field is listed in the rocket's overrides until a message from the emitter changes it again. Only the fields sent are checked, so a rocket that was not launched yet can have its speed corrected. The synthetic message is kept in the rocket's history among the emitter's messages, so replaying the rocket applies the correction again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Correct a rocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the rocket version the correction is based on, as returned by GET /rockets/{id}",
+                        "name": "If-Match",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Corrected fields",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.OverrideRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Corrected rocket",
+                        "schema": {
+                            "$ref": "#/definitions/models.RocketState"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the corrected rocket"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_request for an invalid body, no corrected field or an invalid value",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "412": {
+                        "description": "version_mismatch when the rocket changed since; the ETag header carries the current version",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "428": {
+                        "description": "version_required when If-Match is missing",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rockets/{id}/buffer": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Drops the out-of-order messages buffered for a rocket, for example when a gap will never be filled. The action is recorded in the audit trail.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Clear a rocket's buffer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Rocket ID and number of dropped messages",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rockets/{id}/cursor/reset": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sets the number of the last message processed for a rocket, 0 by default, so that numbering can restart after an emitter reset. Buffered messages at or below the new cursor are dropped, and those that became next in sequence are applied. Moving the cursor increments the rocket's version. The action is recorded in the audit trail.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Reset a rocket's message cursor",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New cursor",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/api.CursorResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cursor before and after the reset",
+                        "schema": {
+                            "$ref": "#/definitions/storage.CursorReset"
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_request for an invalid body or a negative message number",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rockets/{id}/pause": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Freezes the state of a rocket while its messages keep arriving: they are accepted and held in its buffer instead of being applied, until the rocket is resumed. The rocket shows paused: true, and pausing a paused rocket does nothing. The action is recorded in the audit trail.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Pause a rocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Paused, with the number of held messages",
+                        "schema": {
+                            "$ref": "#/definitions/storage.PauseState"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rockets/{id}/replay": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Applies the messages applied to the rocket again, in the order they were applied and with the operator corrections among them, to the state the rocket was created with, and returns the result next to whether it matches the rocket. The rocket itself is left alone. A rocket keeps its latest 1000 applied messages; folded counts the older ones, which are folded into the starting state.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Replay a rocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The rebuilt state, the number of messages replayed and folded, and whether it matches",
+                        "schema": {
+                            "$ref": "#/definitions/storage.Replay"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/rockets/{id}/resume": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Applies the messages held while the rocket was paused in message number order, and applies new messages again. Messages that are still out of order stay buffered. Resuming a running rocket does nothing. The action is recorded in the audit trail.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Resume a paused rocket",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Resumed, with the number of applied and still buffered messages",
+                        "schema": {
+                            "$ref": "#/definitions/storage.PauseState"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the admin scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/graphiql": {
+            "get": {
+                "description": "Serves the GraphiQL explorer for /graphql",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "docs"
+                ],
+                "summary": "GraphiQL explorer",
+                "responses": {
+                    "200": {
+                        "description": "GraphiQL page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Executes a read-only GraphQL query like POST /graphql, with the request in the query string",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL query over GET",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "GraphQL query",
+                        "name": "query",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Variables as a JSON object",
+                        "name": "variables",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Operation to execute",
+                        "name": "operationName",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data, with errors raised by resolvers",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "errors for invalid requests, invalid queries, and queries over the limits (extensions.code is query_too_deep or query_too_complex)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the read scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Executes a read-only GraphQL query over rocket(id), rockets(filter, sort, page) and stats(filter). Queries exceeding the depth or complexity limits are rejected before execution. The schema can be explored with GraphiQL at /graphiql.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL query",
+                "parameters": [
+                    {
+                        "description": "GraphQL request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/graph.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "data, with errors raised by resolvers",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "errors for invalid requests, invalid queries, and queries over the limits (extensions.code is query_too_deep or query_too_complex)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the read scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/health": {
+            "get": {
+                "description": "Returns 200 OK when the service is healthy",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Health check",
+                "responses": {
+                    "200": {
+                        "description": "Service status",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/messages": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Process a rocket message envelope",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "messages"
+                ],
+                "summary": "Process a rocket message",
+                "parameters": [
+                    {
+                        "description": "Message envelope",
+                        "name": "message",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/models.Envelope"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "HMAC signature of the body: t=\u003cunix\u003e,emitter=\u003cname\u003e,v1=\u003chex\u003e",
+                        "name": "X-Lunar-Signature",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Message accepted, with its processing status (applied, buffered, duplicate, conflict, ignored_after_explosion, rejected, cancelled)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "invalid_payload, validation_failed or unknown_message_type; every invalid field is listed in errors with its JSON path",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized, or invalid_signature when signatures are enforced",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the ingest scope, or channel_forbidden when the client certificate, or a client without one, may not post to the channel",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "payload_too_large when the body exceeds 1 MiB",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "rate_limited for the client or channel; see Retry-After",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/metrics": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Returns the service and domain metrics in the Prometheus text exposition format",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "system"
+                ],
+                "summary": "Prometheus metrics",
+                "responses": {
+                    "200": {
+                        "description": "Metrics in the Prometheus text format",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the read scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/openapi.json": {
+            "get": {
+                "description": "Returns the Swagger 2.0 document of every route registered on the server, with the models they use. Routes without annotations are listed with x-undocumented set.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "docs"
+                ],
+                "summary": "OpenAPI document",
+                "responses": {
+                    "200": {
+                        "description": "OpenAPI document",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "the generated document could not be read",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/rockets": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Get a list of all rockets, optionally filtered and sorted by specified field and order. The list is exported as CSV or NDJSON when requested with the Accept header or the format parameter; exports are streamed row by row.",
+                "produces": [
+                    "application/json",
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "rockets"
+                ],
+                "summary": "List all rockets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Sort field (e.g., 'id', 'speed', 'type', 'mission', 'status')",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Sort order ('asc' or 'desc')",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only rockets of this type (case-insensitive)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only rockets on this mission (case-insensitive)",
+                        "name": "mission",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only 'active' or 'exploded' rockets",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Response format ('json', 'csv' or 'ndjson'); overrides the Accept header",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated export columns, in order (id, type, speed, mission, status, updatedAt, version); all by default. CSV and NDJSON only.",
+                        "name": "columns",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached list; 304 is returned while no rocket changed",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date of a cached list; 304 is returned when no rocket was updated since",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "List of rocket summaries",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/models.RocketSummary"
+                            }
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Weak tag covering the version of every listed rocket and the representation"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "Time of the latest update of any listed rocket"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Cached list is current"
+                    },
+                    "400": {
+                        "description": "invalid_request for an unknown format or column",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the read scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/rockets/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieve the complete rocket object including all its properties",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rockets"
+                ],
+                "summary": "Get rocket by ID",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of a cached copy; 304 is returned while it is current",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Date of a cached copy; 304 is returned when the rocket was not updated since",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Long poll: wait until the rocket's version exceeds this value",
+                        "name": "waitForVersion",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Maximum wait with waitForVersion, such as 30s (default 30s, at most 60s)",
+                        "name": "timeout",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Complete rocket object",
+                        "schema": {
+                            "$ref": "#/definitions/models.RocketState"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the rocket state"
+                            },
+                            "Last-Modified": {
+                                "type": "string",
+                                "description": "Time of the last applied update"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Cached copy is current, or the wait timed out"
+                    },
+                    "400": {
+                        "description": "invalid_request when waitForVersion or timeout is invalid",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the read scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/rockets/{id}/quarantine": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists, in message number order, the messages set aside because the rocket exploded: those waiting in its buffer at the explosion (buffered_at_explosion) and newer ones that arrived afterwards (arrived_after_explosion). When the rocket is relaunched, the messages numbered after the relaunch leave the quarantine and are applied once their numbers fit; the others stay for post-mortems. A rocket keeps the latest 1000 quarantined messages; dropped counts those evicted to make room.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Rockets"
+                ],
+                "summary": "Get a rocket's quarantined messages",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Rocket ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "messages, in message number order, and the number dropped",
+                        "schema": {
+                            "$ref": "#/definitions/storage.QuarantineState"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden when the API key lacks the read scope",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "not_found",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "internal_error",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/swagger": {
+            "get": {
+                "description": "Redirects to /swagger/, where the Swagger UI is served",
+                "tags": [
+                    "docs"
+                ],
+                "summary": "Swagger UI",
+                "responses": {
+                    "302": {
+                        "description": "Redirect to /swagger/"
+                    }
+                }
+            }
+        },
+        "/swagger/{path}": {
+            "get": {
+                "description": "Serves the Swagger UI, which loads the spec from /openapi.json",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "docs"
+                ],
+                "summary": "Swagger UI assets",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Asset path; empty for the index",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Swagger UI page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "unknown asset",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "api.CursorResetRequest": {
+            "type": "object",
+            "properties": {
+                "messageNumber": {
+                    "description": "Number of the last processed message; 0 accepts numbering from 1 again",
+                    "type": "integer"
+                }
+            }
+        },
+        "api.OverrideRequest": {
+            "type": "object",
+            "properties": {
+                "mission": {
+                    "type": "string",
+                    "example": "ARTEMIS"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "RocketMissionChanged 42 was never received"
+                },
+                "speed": {
+                    "type": "integer",
+                    "example": 3000
+                },
+                "type": {
+                    "type": "string",
+                    "example": "Falcon-9"
+                }
+            }
+        },
+        "api.PurgeRequest": {
+            "type": "object",
+            "properties": {
+                "mission": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "active",
+                        "exploded"
+                    ]
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "audit.Entry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "Such as rocket.delete",
+                    "type": "string"
+                },
+                "actor": {
+                    "description": "Name of the API key, or anonymous",
+                    "type": "string"
+                },
+                "details": {
+                    "description": "Parameters and effect of the action",
+                    "type": "object",
+                    "additionalProperties": {}
+                },
+                "target": {
+                    "description": "Rocket ID, or empty for actions on many rockets",
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.APIKeyConfig": {
+            "type": "object",
+            "properties": {
+                "hash": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "config.AuthConfig": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.APIKeyConfig"
+                    }
+                },
+                "keysFile": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.ChannelLimitConfig": {
+            "type": "object",
+            "properties": {
+                "burst": {
+                    "type": "integer"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "config.ClientChannelsConfig": {
+            "type": "object",
+            "properties": {
+                "channels": {
+                    "description": "\"*\" allows every channel",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "subject": {
+                    "description": "Certificate subject common name",
+                    "type": "string"
+                }
+            }
+        },
+        "config.ClientLimitConfig": {
+            "type": "object",
+            "properties": {
+                "burst": {
+                    "type": "integer"
+                },
+                "rate": {
+                    "type": "number"
+                }
+            }
+        },
+        "config.Config": {
+            "type": "object",
+            "properties": {
+                "auth": {
+                    "$ref": "#/definitions/config.AuthConfig"
+                },
+                "decoding": {
+                    "$ref": "#/definitions/config.DecodingConfig"
+                },
+                "graphql": {
+                    "$ref": "#/definitions/config.GraphQLConfig"
+                },
+                "log": {
+                    "$ref": "#/definitions/config.LogConfig"
+                },
+                "metrics": {
+                    "$ref": "#/definitions/config.MetricsConfig"
+                },
+                "rateLimit": {
+                    "$ref": "#/definitions/config.RateLimitConfig"
+                },
+                "server": {
+                    "$ref": "#/definitions/config.ServerConfig"
+                },
+                "signing": {
+                    "$ref": "#/definitions/config.SigningConfig"
+                }
+            }
+        },
+        "config.DecodingConfig": {
+            "type": "object",
+            "properties": {
+                "mode": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.DomainMetricsConfig": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "maxRockets": {
+                    "type": "integer"
+                },
+                "staleAfter": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.EmitterConfig": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.GRPCConfig": {
+            "type": "object",
+            "properties": {
+                "enabled": {
+                    "type": "boolean"
+                },
+                "port": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.GraphQLConfig": {
+            "type": "object",
+            "properties": {
+                "maxComplexity": {
+                    "type": "integer"
+                },
+                "maxDepth": {
+                    "type": "integer"
+                }
+            }
+        },
+        "config.LogConfig": {
+            "type": "object",
+            "properties": {
+                "format": {
+                    "type": "string"
+                },
+                "level": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.MetricsConfig": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "$ref": "#/definitions/config.DomainMetricsConfig"
+                }
+            }
+        },
+        "config.RateLimitConfig": {
+            "type": "object",
+            "properties": {
+                "channel": {
+                    "$ref": "#/definitions/config.ChannelLimitConfig"
+                },
+                "client": {
+                    "$ref": "#/definitions/config.ClientLimitConfig"
+                },
+                "idleTimeout": {
+                    "type": "string"
+                }
+            }
+        },
+        "config.ServerConfig": {
+            "type": "object",
+            "properties": {
+                "grpc": {
+                    "$ref": "#/definitions/config.GRPCConfig"
+                },
+                "port": {
+                    "type": "integer"
+                },
+                "shutdownTimeout": {
+                    "type": "string"
+                },
+                "tls": {
+                    "$ref": "#/definitions/config.TLSConfig"
+                }
+            }
+        },
+        "config.SigningConfig": {
+            "type": "object",
+            "properties": {
+                "emitters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.EmitterConfig"
+                    }
+                },
+                "mode": {
+                    "type": "string"
+                },
+                "tolerance": {
+                    "type": "string"
                 }
             }
         },
-        "/messages": {
-            "post": {
-                "description": "Process a rocket message envelope",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "messages"
-                ],
-                "summary": "Process a rocket message",
-                "parameters": [
-                    {
-                        "description": "Message envelope",
-                        "name": "message",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/models.Envelope"
-                        }
-                    }
-                ],
-                "responses": {
-                    "202": {
-                        "description": "Message accepted",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
+        "config.TLSConfig": {
+            "type": "object",
+            "properties": {
+                "certFile": {
+                    "type": "string"
+                },
+                "clientAuth": {
+                    "type": "string"
+                },
+                "clientCAFile": {
+                    "type": "string"
+                },
+                "clientChannels": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/config.ClientChannelsConfig"
                     }
+                },
+                "enabled": {
+                    "type": "boolean"
+                },
+                "keyFile": {
+                    "type": "string"
+                },
+                "reloadInterval": {
+                    "type": "string"
                 }
             }
         },
-        "/rockets": {
-            "get": {
-                "description": "Get a list of all rockets, optionally sorted by specified field and order",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "rockets"
-                ],
-                "summary": "List all rockets",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Sort field (e.g., 'id', 'speed', 'type', 'mission', 'status')",
-                        "name": "sort",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Sort order ('asc' or 'desc')",
-                        "name": "order",
-                        "in": "query"
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "List of rocket summaries",
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "$ref": "#/definitions/models.RocketSummary"
-                            }
-                        }
-                    }
+        "deadletter.Letter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "1, plus every resubmission that failed again",
+                    "type": "integer",
+                    "example": 1
+                },
+                "body": {
+                    "description": "Raw body of the latest attempt",
+                    "type": "string"
+                },
+                "channel": {
+                    "description": "Empty when the body could not be decoded",
+                    "type": "string"
+                },
+                "client": {
+                    "description": "API key name as key:\u003cname\u003e, client IP as ip:\u003caddress\u003e, or buffer when rejected after buffering",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "messageNumber": {
+                    "description": "Zero when the body could not be decoded",
+                    "type": "integer"
+                },
+                "messageType": {
+                    "description": "Empty when the body could not be decoded",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Why the message failed",
+                    "type": "string"
+                },
+                "stage": {
+                    "description": "Stage at which the message failed",
+                    "type": "string",
+                    "enum": [
+                        "decode",
+                        "validate",
+                        "apply"
+                    ]
+                },
+                "time": {
+                    "description": "Time of the latest failure",
+                    "type": "string"
                 }
             }
         },
-        "/rockets/{id}": {
-            "get": {
-                "description": "Retrieve the complete rocket object including all its properties",
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Rockets"
-                ],
-                "summary": "Get rocket by ID",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Rocket ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "Complete rocket object",
-                        "schema": {
-                            "$ref": "#/definitions/models.RocketState"
-                        }
-                    },
-                    "404": {
-                        "description": "Rocket not found",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    }
+        "graph.Request": {
+            "type": "object",
+            "properties": {
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": {}
                 }
             }
-        }
-    },
-    "definitions": {
+        },
         "models.Envelope": {
             "type": "object",
             "properties": {
@@ -186,6 +1972,33 @@ const docTemplate = `{
                 }
             }
         },
+        "models.FieldOverride": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "description": "Name of the API key that made the correction",
+                    "type": "string"
+                },
+                "at": {
+                    "description": "Time of the correction",
+                    "type": "string"
+                },
+                "previous": {
+                    "description": "Value before the correction"
+                },
+                "reason": {
+                    "description": "Why the emitter's value was wrong",
+                    "type": "string"
+                },
+                "value": {
+                    "description": "Value set by the operator"
+                },
+                "version": {
+                    "description": "Version produced by the correction",
+                    "type": "integer"
+                }
+            }
+        },
         "models.MessageContent": {
             "type": "object",
             "properties": {
@@ -207,12 +2020,35 @@ const docTemplate = `{
                     "description": "RocketExploded fields",
                     "type": "string"
                 },
+                "speed": {
+                    "description": "RocketStateOverridden fields, with type and mission",
+                    "type": "integer"
+                },
                 "type": {
                     "description": "RocketLaunched fields",
                     "type": "string"
                 }
             }
         },
+        "models.QuarantinedMessage": {
+            "type": "object",
+            "properties": {
+                "envelope": {
+                    "$ref": "#/definitions/models.Envelope"
+                },
+                "quarantinedAt": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Why the message was set aside",
+                    "type": "string",
+                    "enum": [
+                        "arrived_after_explosion",
+                        "buffered_at_explosion"
+                    ]
+                }
+            }
+        },
         "models.RocketState": {
             "type": "object",
             "properties": {
@@ -232,6 +2068,17 @@ const docTemplate = `{
                     "description": "Current mission",
                     "type": "string"
                 },
+                "overrides": {
+                    "description": "Latest operator correction of each corrected field, keyed by field name",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/models.FieldOverride"
+                    }
+                },
+                "paused": {
+                    "description": "Whether an operator paused ingestion; messages are held until resumed",
+                    "type": "boolean"
+                },
                 "reason": {
                     "description": "Reason for explosion, if applicable",
                     "type": "string"
@@ -247,6 +2094,10 @@ const docTemplate = `{
                 "updatedAt": {
                     "description": "Last updated time",
                     "type": "string"
+                },
+                "version": {
+                    "description": "Incremented on every applied update",
+                    "type": "integer"
                 }
             }
         },
@@ -270,9 +2121,195 @@ const docTemplate = `{
                 },
                 "updatedAt": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "problem.Code": {
+            "type": "string",
+            "enum": [
+                "invalid_payload",
+                "validation_failed",
+                "unknown_message_type",
+                "invalid_request",
+                "invalid_signature",
+                "unauthorized",
+                "forbidden",
+                "channel_forbidden",
+                "not_found",
+                "payload_too_large",
+                "rate_limited",
+                "config_rejected",
+                "version_mismatch",
+                "version_required",
+                "internal_error"
+            ],
+            "x-enum-comments": {
+                "CodeChannelForbidden": "Client may not post to the channel under its certificate",
+                "CodeConfigRejected": "Reloaded configuration is invalid",
+                "CodeForbidden": "API key lacks the required scope",
+                "CodeInternal": "Unexpected server error",
+                "CodeInvalidPayload": "Body is not a JSON object",
+                "CodeInvalidRequest": "Path or query parameters are invalid",
+                "CodeInvalidSignature": "Message signature missing or invalid in enforce mode",
+                "CodeNotFound": "Resource does not exist",
+                "CodePayloadTooLarge": "Body exceeds the size limit",
+                "CodeRateLimited": "Client or channel rate limit exceeded",
+                "CodeUnauthorized": "API key missing or invalid",
+                "CodeUnknownMessageType": "metadata.messageType is not registered",
+                "CodeValidationFailed": "One or more fields are invalid, see errors",
+                "CodeVersionMismatch": "If-Match does not name the current version",
+                "CodeVersionRequired": "If-Match is required but missing"
+            },
+            "x-enum-varnames": [
+                "CodeInvalidPayload",
+                "CodeValidationFailed",
+                "CodeUnknownMessageType",
+                "CodeInvalidRequest",
+                "CodeInvalidSignature",
+                "CodeUnauthorized",
+                "CodeForbidden",
+                "CodeChannelForbidden",
+                "CodeNotFound",
+                "CodePayloadTooLarge",
+                "CodeRateLimited",
+                "CodeConfigRejected",
+                "CodeVersionMismatch",
+                "CodeVersionRequired",
+                "CodeInternal"
+            ]
+        },
+        "problem.FieldError": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "example": "required"
+                },
+                "path": {
+                    "type": "string",
+                    "example": "message.by"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "$ref": "#/definitions/problem.Code"
+                },
+                "detail": {
+                    "type": "string",
+                    "example": "Invalid message format"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/problem.FieldError"
+                    }
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "Validation failed"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "urn:lunar:problem:validation_failed"
+                }
+            }
+        },
+        "storage.CursorReset": {
+            "type": "object",
+            "properties": {
+                "cursor": {
+                    "description": "Last processed message number after the reset and draining",
+                    "type": "integer"
+                },
+                "drained": {
+                    "description": "Buffered messages applied since they became next in sequence",
+                    "type": "integer"
+                },
+                "dropped": {
+                    "description": "Buffered messages at or below the new cursor, which are discarded",
+                    "type": "integer"
+                },
+                "previous": {
+                    "description": "Last processed message number before the reset",
+                    "type": "integer"
+                }
+            }
+        },
+        "storage.PauseState": {
+            "type": "object",
+            "properties": {
+                "drained": {
+                    "description": "Held messages applied when the rocket was resumed",
+                    "type": "integer"
+                },
+                "held": {
+                    "description": "Buffered messages not applied yet",
+                    "type": "integer"
+                },
+                "paused": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "storage.QuarantineState": {
+            "type": "object",
+            "properties": {
+                "dropped": {
+                    "description": "Messages evicted because the quarantine was full",
+                    "type": "integer"
+                },
+                "messages": {
+                    "description": "In message number order",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/models.QuarantinedMessage"
+                    }
+                }
+            }
+        },
+        "storage.Replay": {
+            "type": "object",
+            "properties": {
+                "folded": {
+                    "description": "Oldest messages folded into the starting state to respect HistoryCapacity",
+                    "type": "integer"
+                },
+                "matches": {
+                    "description": "Whether the rebuilt type, speed, mission and explosion match the rocket's",
+                    "type": "boolean"
+                },
+                "messages": {
+                    "description": "Messages reapplied, operator corrections included",
+                    "type": "integer"
+                },
+                "rocket": {
+                    "description": "The rebuilt state; version, pause and overrides are not part of the history",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/models.RocketState"
+                        }
+                    ]
                 }
             }
         }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "API key with the scope required by the route; \"Authorization: Bearer \u003ckey\u003e\" is accepted as well",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        }
     }
 }`
 
@@ -283,7 +2320,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "",
 	Schemes:          []string{},
 	Title:            "",
-	Description:      "",
+	Description:      "Errors are RFC 7807 problem details (application/problem+json) with a stable code:\ninvalid_payload (400), validation_failed (400), unknown_message_type (400), invalid_request (400),\ninvalid_signature (401), unauthorized (401), forbidden (403), channel_forbidden (403), not_found (404),\nversion_mismatch (412), payload_too_large (413), config_rejected (422), version_required (428), rate_limited (429) and internal_error (500).\nThe type is urn:lunar:problem:<code>, and invalid fields are listed in errors with their JSON path.",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 	LeftDelim:        "{{",
@@ -0,0 +1,9 @@
+package docs
+
+import "embed"
+
+// UI holds the Swagger UI and GraphiQL pages, so that the binary serves them
+// whatever its working directory
+//
+//go:embed swagger/index.html graphiql/index.html
+var UI embed.FS
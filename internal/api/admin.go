@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/rah-0/lunar/internal/audit"
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/config"
+	"github.com/rah-0/lunar/internal/openapi"
+	"github.com/rah-0/lunar/internal/problem"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// AdminHandler contains the dependencies needed for the /admin endpoints
+type AdminHandler struct {
+	Config   *config.Store
+	Rockets  storage.Maintainer  // Optional; the /admin/rockets routes are registered when set
+	Messages *Handler            // Optional; the /admin/deadletters routes are registered when it keeps dead letters
+	Audit    *audit.Trail        // Optional; records every admin action when set
+	Auth     *auth.Authenticator // Optional; routes are open when nil or disabled
+}
+
+// NewAdminHandler creates a handler for the /admin route group
+func NewAdminHandler(cfg *config.Store) *AdminHandler {
+	return &AdminHandler{Config: cfg}
+}
+
+// RegisterRoutes registers all admin routes with the provided router
+func (a *AdminHandler) RegisterRoutes(mux openapi.Router) {
+	// GET endpoint to inspect the effective configuration
+	mux.HandleFunc("GET /admin/config", a.Auth.Require(auth.ScopeAdmin, a.HandleGetConfig))
+
+	// POST endpoint to reload the configuration without a restart
+	mux.HandleFunc("POST /admin/config/reload", a.Auth.Require(auth.ScopeAdmin, a.HandleReloadConfig))
+
+	// GET endpoint to read the audit trail of admin actions
+	mux.HandleFunc("GET /admin/audit", a.Auth.Require(auth.ScopeAdmin, a.HandleGetAudit))
+
+	// Endpoints to repair rocket data
+	if a.Rockets != nil {
+		mux.HandleFunc("PATCH /admin/rockets/{id}", a.Auth.Require(auth.ScopeAdmin, a.HandleOverrideRocket))
+		mux.HandleFunc("DELETE /admin/rockets/{id}", a.Auth.Require(auth.ScopeAdmin, a.HandleDeleteRocket))
+		mux.HandleFunc("DELETE /admin/rockets/{id}/buffer", a.Auth.Require(auth.ScopeAdmin, a.HandleClearBuffer))
+		mux.HandleFunc("POST /admin/rockets/{id}/cursor/reset", a.Auth.Require(auth.ScopeAdmin, a.HandleResetCursor))
+		mux.HandleFunc("POST /admin/rockets/{id}/pause", a.Auth.Require(auth.ScopeAdmin, a.HandlePauseRocket))
+		mux.HandleFunc("POST /admin/rockets/{id}/resume", a.Auth.Require(auth.ScopeAdmin, a.HandleResumeRocket))
+		mux.HandleFunc("GET /admin/rockets/{id}/replay", a.Auth.Require(auth.ScopeAdmin, a.HandleReplayRocket))
+		mux.HandleFunc("POST /admin/rockets/purge", a.Auth.Require(auth.ScopeAdmin, a.HandlePurgeRockets))
+	}
+
+	// Endpoints to inspect, resubmit and discard the messages that could not be processed
+	if a.Messages != nil && a.Messages.DeadLetters != nil {
+		mux.HandleFunc("GET /admin/deadletters", a.Auth.Require(auth.ScopeAdmin, a.HandleListDeadLetters))
+		mux.HandleFunc("GET /admin/deadletters/{id}", a.Auth.Require(auth.ScopeAdmin, a.HandleGetDeadLetter))
+		mux.HandleFunc("POST /admin/deadletters/{id}/resubmit", a.Auth.Require(auth.ScopeAdmin, a.HandleResubmitDeadLetter))
+		mux.HandleFunc("DELETE /admin/deadletters/{id}", a.Auth.Require(auth.ScopeAdmin, a.HandleDeleteDeadLetter))
+	}
+}
+
+// HandleGetConfig returns the effective configuration with secrets redacted
+// @Summary Get effective configuration
+// @Description Returns the configuration currently in effect, with secret values redacted
+// @Tags admin
+// @Produce json
+// @Success 200 {object} config.Config "Effective configuration"
+// @Security ApiKeyAuth
+// @Router /admin/config [get]
+func (a *AdminHandler) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
+	respondWithJSON(w, http.StatusOK, a.Config.Current().Redacted())
+}
+
+// HandleReloadConfig reloads the configuration, like SIGHUP does
+// @Summary Reload configuration
+// @Description Reloads the configuration from its sources and applies the reloadable fields. Invalid configurations are rejected and the current one is kept. Both outcomes are recorded in the audit trail.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} map[string]any "Fields that changed and whether they were applied"
+// @Failure 422 {object} problem.Problem "config_rejected when the new configuration is invalid"
+// @Security ApiKeyAuth
+// @Router /admin/config/reload [post]
+func (a *AdminHandler) HandleReloadConfig(w http.ResponseWriter, r *http.Request) {
+	changes, err := a.Config.Reload()
+	if err != nil {
+		a.Audit.Record(r.Context(), "config.reload", "", map[string]any{"error": err.Error()})
+		respondWithProblem(w, problem.CodeConfigRejected, "Configuration rejected: "+err.Error())
+		return
+	}
+
+	if changes == nil {
+		changes = []config.Change{}
+	}
+	a.Audit.Record(r.Context(), "config.reload", "", map[string]any{"changes": changes})
+	respondWithJSON(w, http.StatusOK, map[string]any{"changes": changes})
+}
@@ -0,0 +1,108 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// rocketETag returns the entity tag of a rocket's state
+func rocketETag(rocket *models.RocketState) string {
+	return fmt.Sprintf(`"%d"`, rocket.Version)
+}
+
+// listETag returns an entity tag covering the ID and version of every listed
+// rocket, so it changes whenever any rocket changes. The tag is weak since
+// an unsorted list may come back in a different order. A non-empty variant,
+// such as an export format and its columns, is covered as well so that each
+// representation has its own tag.
+func listETag(rockets []models.RocketSummary, variant string) string {
+	versions := make([]string, 0, len(rockets))
+	for _, rocket := range rockets {
+		versions = append(versions, fmt.Sprintf("%s\x00%d", rocket.ID, rocket.Version))
+	}
+	sort.Strings(versions)
+
+	hash := sha256.New()
+	if variant != "" {
+		fmt.Fprintln(hash, variant)
+	}
+	for _, version := range versions {
+		fmt.Fprintln(hash, version)
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// parseIfMatch returns the versions named by the strong entity tags of an
+// If-Match header, or any when it is "*". Weak tags never match, as
+// required by RFC 9110.
+func parseIfMatch(header string) (versions []uint64, any bool) {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return nil, true
+		}
+		if len(candidate) < 2 || candidate[0] != '"' || candidate[len(candidate)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.ParseUint(candidate[1:len(candidate)-1], 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions, false
+}
+
+// lastModified returns the latest update time of the listed rockets
+func lastModified(rockets []models.RocketSummary) time.Time {
+	var latest time.Time
+	for _, rocket := range rockets {
+		if rocket.UpdatedAt.After(latest) {
+			latest = rocket.UpdatedAt
+		}
+	}
+	return latest
+}
+
+// setValidators sets the ETag and, when known, Last-Modified headers
+func setValidators(w http.ResponseWriter, etag string, modified time.Time) {
+	w.Header().Set("ETag", etag)
+	if !modified.IsZero() {
+		w.Header().Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// notModified reports whether the request's conditional headers match the
+// current representation. If-None-Match uses the weak comparison and takes
+// precedence over If-Modified-Since, as required by RFC 9110.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if header := r.Header.Get("If-None-Match"); header != "" {
+		opaque := strings.TrimPrefix(etag, "W/")
+		for _, candidate := range strings.Split(header, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == opaque {
+				return true
+			}
+		}
+		return false
+	}
+
+	if header := r.Header.Get("If-Modified-Since"); header != "" && !modified.IsZero() {
+		since, err := http.ParseTime(header)
+		// Last-Modified has a resolution of one second
+		return err == nil && !modified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+// respondNotModified sends 304 with the validators and no body
+func respondNotModified(w http.ResponseWriter, etag string, modified time.Time) {
+	setValidators(w, etag, modified)
+	w.WriteHeader(http.StatusNotModified)
+}
@@ -0,0 +1,165 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/problem"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// Audit actions of the dead-letter endpoints
+const (
+	actionResubmitDeadLetter = "deadletter.resubmit"
+	actionDeleteDeadLetter   = "deadletter.delete"
+)
+
+// HandleListDeadLetters returns the messages that could not be processed
+// @Summary List dead letters
+// @Description Returns the messages ingestion could not process, newest first: bodies that are not JSON envelopes (decode), envelopes that failed validation (validate) and updates the repository rejected (apply). Each letter keeps the raw body, the reason, the time and the client. The latest 1000 letters are kept.
+// @Tags admin
+// @Produce json
+// @Param stage query string false "Stage at which the messages failed" Enums(decode, validate, apply)
+// @Param channel query string false "Channel of the messages (case-insensitive)"
+// @Param type query string false "Message type (case-insensitive)"
+// @Param client query string false "Client, as key:<name> or ip:<address> (case-insensitive)"
+// @Param limit query int false "Maximum number of letters; every letter by default"
+// @Success 200 {object} map[string][]deadletter.Letter "deadLetters, newest first"
+// @Failure 400 {object} problem.Problem "invalid_request for an unknown stage or an invalid limit"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Security ApiKeyAuth
+// @Router /admin/deadletters [get]
+func (a *AdminHandler) HandleListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := deadletter.Filter{
+		Stage:       strings.ToLower(query.Get("stage")),
+		Channel:     query.Get("channel"),
+		MessageType: query.Get("type"),
+		Client:      query.Get("client"),
+	}
+	switch filter.Stage {
+	case "", deadletter.StageDecode, deadletter.StageValidate, deadletter.StageApply:
+	default:
+		respondWithProblem(w, problem.CodeInvalidRequest, fmt.Sprintf("unknown stage %q (expected decode, validate or apply)", query.Get("stage")))
+		return
+	}
+	if value := query.Get("limit"); value != "" {
+		var err error
+		if filter.Limit, err = strconv.Atoi(value); err != nil || filter.Limit < 1 {
+			respondWithProblem(w, problem.CodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"deadLetters": a.Messages.DeadLetters.List(filter)})
+}
+
+// HandleGetDeadLetter returns one message that could not be processed
+// @Summary Get a dead letter
+// @Description Returns a message ingestion could not process, with its raw body and why it failed
+// @Tags admin
+// @Produce json
+// @Param id path string true "Dead letter ID"
+// @Success 200 {object} deadletter.Letter "Dead letter"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Security ApiKeyAuth
+// @Router /admin/deadletters/{id} [get]
+func (a *AdminHandler) HandleGetDeadLetter(w http.ResponseWriter, r *http.Request) {
+	letter, ok := a.Messages.DeadLetters.Get(r.PathValue("id"))
+	if !ok {
+		respondWithProblem(w, problem.CodeNotFound, fmt.Sprintf("Dead letter %s not found", r.PathValue("id")))
+		return
+	}
+	respondWithJSON(w, http.StatusOK, letter)
+}
+
+// HandleResubmitDeadLetter processes a dead letter again
+// @Summary Resubmit a dead letter
+// @Description Processes the message of a dead letter again, like POST /messages but without the signature, channel and rate limit checks. The request body, when present, is a fixed envelope that replaces the stored body; without one the stored body is resubmitted as is, for example once the rocket can accept it. The letter is deleted when the message is no longer rejected, and otherwise updated with the new body and reason and returned as deadLetter. The action is recorded in the audit trail.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Dead letter ID"
+// @Param message body models.Envelope false "Fixed envelope"
+// @Success 200 {object} map[string]any "Processing status of the message as for POST /messages, with the updated deadLetter when it was rejected again"
+// @Failure 400 {object} problem.Problem "invalid_payload, validation_failed or unknown_message_type when the message is still invalid, which updates the letter, or invalid_request for a body that is too large"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Security ApiKeyAuth
+// @Router /admin/deadletters/{id}/resubmit [post]
+func (a *AdminHandler) HandleResubmitDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	store := a.Messages.DeadLetters
+	letter, ok := store.Get(id)
+	if !ok {
+		respondWithProblem(w, problem.CodeNotFound, fmt.Sprintf("Dead letter %s not found", id))
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		respondWithProblem(w, problem.CodeInvalidRequest, "Failed to read request body: "+err.Error())
+		return
+	}
+	if len(body) == 0 {
+		body = []byte(letter.Body)
+	}
+
+	// A message that is still invalid stays a dead letter
+	envelope, _, err := a.Messages.Decoder.DecodeEnvelope(body)
+	if err != nil {
+		p, stage := decodeProblem(err)
+		store.Fail(id, newLetter(letter.Client, stage, errorText(err), body, envelope))
+		a.Audit.Record(r.Context(), actionResubmitDeadLetter, id, map[string]any{"stage": stage, "reason": errorText(err)})
+		problem.Write(w, p)
+		return
+	}
+
+	outcome := a.Messages.Repository.ProcessMessage(r.Context(), envelope)
+	response := ingestionResponse(envelope, outcome)
+	switch outcome.Status {
+	case storage.StatusRejected, storage.StatusCancelled:
+		if failed, ok := store.Fail(id, newLetter(letter.Client, deadletter.StageApply, outcome.Reason, body, envelope)); ok {
+			response["deadLetter"] = failed
+		}
+	default:
+		store.Delete(id)
+	}
+
+	a.Audit.Record(r.Context(), actionResubmitDeadLetter, id, map[string]any{
+		"channel":       envelope.GetChannel(),
+		"messageNumber": envelope.GetMessageNumber(),
+		"status":        outcome.Status,
+		"reason":        outcome.Reason,
+	})
+	respondWithJSON(w, http.StatusOK, response)
+}
+
+// HandleDeleteDeadLetter discards a message that could not be processed
+// @Summary Delete a dead letter
+// @Description Discards a message that will not be resubmitted. The action is recorded in the audit trail.
+// @Tags admin
+// @Param id path string true "Dead letter ID"
+// @Success 204 "Dead letter deleted"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Security ApiKeyAuth
+// @Router /admin/deadletters/{id} [delete]
+func (a *AdminHandler) HandleDeleteDeadLetter(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if !a.Messages.DeadLetters.Delete(id) {
+		respondWithProblem(w, problem.CodeNotFound, fmt.Sprintf("Dead letter %s not found", id))
+		return
+	}
+
+	a.Audit.Record(r.Context(), actionDeleteDeadLetter, id, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
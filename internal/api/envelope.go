@@ -1,54 +1,85 @@
 package api
 
 import (
+	"encoding/json"
 	"errors"
+	"strings"
 
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/messages"
 	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/problem"
+	"github.com/rah-0/lunar/internal/storage"
 )
 
-// validateEnvelope ensures an incoming message envelope contains all required fields
-func validateEnvelope(envelope models.Envelope) error {
-	// Validate metadata fields
-	if envelope.Metadata.Channel == "" {
-		return errors.New("missing or empty channel")
+// decodeProblem describes an envelope that could not be decoded, and returns
+// the stage at which it failed
+func decodeProblem(err error) (*problem.Problem, string) {
+	var fieldErr *messages.FieldError
+	if !errors.As(err, &fieldErr) {
+		return problem.New(problem.CodeInvalidPayload, "Invalid request payload: "+err.Error()), deadletter.StageDecode
 	}
+	return validationProblem(err), deadletter.StageValidate
+}
 
-	if envelope.Metadata.MessageNumber <= 0 {
-		return errors.New("invalid message number")
+// newLetter describes a message that failed at stage, with whatever the
+// envelope could be decoded into
+func newLetter(client, stage, reason string, body []byte, envelope models.Envelope) deadletter.Letter {
+	return deadletter.Letter{
+		Client:        client,
+		Stage:         stage,
+		Reason:        reason,
+		Channel:       envelope.GetChannel(),
+		MessageNumber: envelope.GetMessageNumber(),
+		MessageType:   envelope.GetMessageType(),
+		Body:          string(body),
 	}
+}
+
+// BufferedClient is the client recorded for buffered messages that were
+// rejected once their turn came, since the emitter is no longer known then
+const BufferedClient = "buffer"
 
-	if envelope.Metadata.MessageTime.IsZero() {
-		return errors.New("missing message time")
+// DeadLetterRejections returns a storage.RejectionHandler that keeps the
+// buffered messages the repository drops as dead letters, whichever request
+// drained them
+func DeadLetterRejections(store *deadletter.Store) storage.RejectionHandler {
+	return func(envelope models.Envelope, err error) {
+		body, _ := json.Marshal(envelope)
+		store.Add(newLetter(BufferedClient, deadletter.StageApply, errorText(err), body, envelope))
 	}
+}
 
-	// Ensure message type is valid
-	switch envelope.Metadata.MessageType {
-	case models.MessageTypeRocketLaunched:
-		if envelope.Message.Type == "" {
-			return errors.New("missing or invalid rocket type")
-		}
-		if envelope.Message.Mission == "" {
-			return errors.New("missing or invalid mission")
-		}
-
-	case models.MessageTypeRocketSpeedIncreased, models.MessageTypeRocketSpeedDecreased:
-		// By field should be present for speed changes
-		// The MessageAny struct will default to 0 which is a valid value,
-		// but we'll check if it was actually present in the original JSON
-
-	case models.MessageTypeRocketExploded:
-		if envelope.Message.Reason == "" {
-			return errors.New("missing or invalid explosion reason")
-		}
-
-	case models.MessageTypeRocketMissionChanged:
-		if envelope.Message.NewMission == "" {
-			return errors.New("missing or invalid new mission")
-		}
-
-	default:
-		return errors.New("unknown message type")
+// validationProblem describes field errors, using the unknown message type
+// code when the type is not registered
+func validationProblem(err error) *problem.Problem {
+	code := problem.CodeValidationFailed
+	if errors.Is(err, messages.ErrUnknownType) {
+		code = problem.CodeUnknownMessageType
+	}
+	p := problem.New(code, "Invalid message format: "+errorText(err))
+	for _, fieldErr := range messages.FieldErrors(err) {
+		p.Errors = append(p.Errors, problem.FieldError{Path: fieldErr.Path, Message: fieldErr.Message})
 	}
+	return p
+}
+
+// errorText flattens joined errors onto one line
+func errorText(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", "; ")
+}
 
-	return nil
+// ingestionResponse describes what was done with a message
+func ingestionResponse(envelope models.Envelope, outcome storage.ProcessOutcome) map[string]any {
+	response := map[string]any{
+		"processed":     outcome.Processed(),
+		"status":        outcome.Status,
+		"drained":       outcome.Drained,
+		"channel":       envelope.GetChannel(),
+		"messageNumber": envelope.GetMessageNumber(),
+	}
+	if outcome.Reason != "" {
+		response["reason"] = outcome.Reason
+	}
+	return response
 }
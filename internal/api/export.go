@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// Export formats of GET /rockets
+const (
+	formatJSON   = "json"
+	formatCSV    = "csv"
+	formatNDJSON = "ndjson"
+)
+
+// Media types of the export formats
+const (
+	mediaTypeCSV    = "text/csv"
+	mediaTypeNDJSON = "application/x-ndjson"
+)
+
+// flushEvery is the number of exported rows after which the response is
+// flushed, so large exports reach the client while they are written
+const flushEvery = 100
+
+// exportColumn is a column of a CSV or NDJSON export
+type exportColumn struct {
+	name  string
+	value func(models.RocketSummary) any
+}
+
+// exportColumns lists every exportable column in its default order
+var exportColumns = []exportColumn{
+	{"id", func(r models.RocketSummary) any { return r.ID }},
+	{"type", func(r models.RocketSummary) any { return r.Type }},
+	{"speed", func(r models.RocketSummary) any { return r.Speed }},
+	{"mission", func(r models.RocketSummary) any { return r.Mission }},
+	{"status", func(r models.RocketSummary) any { return r.Status }},
+	{"updatedAt", func(r models.RocketSummary) any { return r.UpdatedAt }},
+	{"version", func(r models.RocketSummary) any { return r.Version }},
+}
+
+// negotiateFormat picks the list format from the format parameter, or else
+// from the Accept header. JSON is used when nothing else is acceptable.
+func negotiateFormat(r *http.Request) (string, error) {
+	if format := strings.ToLower(r.URL.Query().Get("format")); format != "" {
+		switch format {
+		case formatJSON, formatCSV, formatNDJSON:
+			return format, nil
+		default:
+			return "", fmt.Errorf("unknown format %q (expected json, csv or ndjson)", format)
+		}
+	}
+
+	best, bestQ := formatJSON, 0.0
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		var format string
+		switch mediaType {
+		case mediaTypeCSV:
+			format = formatCSV
+		case mediaTypeNDJSON, "application/ndjson":
+			format = formatNDJSON
+		case "application/json":
+			format = formatJSON
+		default:
+			continue
+		}
+		if q > bestQ {
+			best, bestQ = format, q
+		}
+	}
+	return best, nil
+}
+
+// parseColumns selects the export columns from a comma-separated list of
+// names, keeping the requested order. An empty list selects every column.
+func parseColumns(list string) ([]exportColumn, error) {
+	if list == "" {
+		return exportColumns, nil
+	}
+	var columns []exportColumn
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		found := false
+		for _, column := range exportColumns {
+			if strings.EqualFold(column.name, name) {
+				columns, found = append(columns, column), true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+	}
+	return columns, nil
+}
+
+// columnNames returns the names of columns
+func columnNames(columns []exportColumn) []string {
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// streamExport writes the rockets row by row in the CSV or NDJSON format,
+// flushing the response every flushEvery rows instead of building it in memory
+func streamExport(w http.ResponseWriter, format string, columns []exportColumn, rockets []models.RocketSummary) {
+	controller := http.NewResponseController(w)
+	buffered := bufio.NewWriter(w)
+
+	var writeRow func(models.RocketSummary) error
+	switch format {
+	case formatCSV:
+		w.Header().Set("Content-Type", mediaTypeCSV+"; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="rockets.csv"`)
+		writer := csv.NewWriter(buffered)
+		writer.Write(columnNames(columns))
+		record := make([]string, len(columns))
+		writeRow = func(rocket models.RocketSummary) error {
+			for i, column := range columns {
+				record[i] = csvValue(column.value(rocket))
+			}
+			writer.Write(record)
+			writer.Flush()
+			return writer.Error()
+		}
+	default:
+		w.Header().Set("Content-Type", mediaTypeNDJSON)
+		writeRow = func(rocket models.RocketSummary) error {
+			// Keys are written in column order, which a map would not keep
+			buffered.WriteByte('{')
+			for i, column := range columns {
+				if i > 0 {
+					buffered.WriteByte(',')
+				}
+				key, _ := json.Marshal(column.name)
+				value, _ := json.Marshal(column.value(rocket))
+				buffered.Write(key)
+				buffered.WriteByte(':')
+				buffered.Write(value)
+			}
+			_, err := buffered.WriteString("}\n")
+			return err
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	for i, rocket := range rockets {
+		if err := writeRow(rocket); err != nil {
+			return // The client went away
+		}
+		if (i+1)%flushEvery == 0 {
+			if buffered.Flush() != nil {
+				return
+			}
+			controller.Flush()
+		}
+	}
+	buffered.Flush()
+}
+
+// csvValue formats a column value for CSV
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
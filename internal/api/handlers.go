@@ -1,37 +1,70 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
-
-	"github.com/rah-0/lunar/internal/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rah-0/lunar/docs"
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/logging"
+	"github.com/rah-0/lunar/internal/messages"
+	"github.com/rah-0/lunar/internal/openapi"
+	"github.com/rah-0/lunar/internal/problem"
+	"github.com/rah-0/lunar/internal/ratelimit"
+	"github.com/rah-0/lunar/internal/signing"
 	"github.com/rah-0/lunar/internal/storage"
+	"github.com/rah-0/lunar/internal/transport"
 )
 
+// maxMessageSize bounds the size of a message body
+const maxMessageSize = 1 << 20
+
 // Handler contains the dependencies needed for the API handlers
 type Handler struct {
-	Repository storage.RocketRepository
+	Repository  storage.RocketRepository
+	Decoder     *messages.Decoder        // Decodes the message types accepted by /messages
+	Auth        *auth.Authenticator      // Optional; routes are open when nil or disabled
+	Channels    *transport.ChannelPolicy // Optional; restricts client certificates to channels
+	Signatures  *signing.Verifier        // Optional; verifies message signatures when set
+	DeadLetters *deadletter.Store        // Optional; keeps the messages that could not be processed
+
+	// Optional ingestion rate limits; unlimited when nil
+	ClientLimiter  *ratelimit.Limiter // Keyed by API key name, or client IP without one
+	ChannelLimiter *ratelimit.Limiter // Keyed by message channel
 }
 
 
 func NewHandler(repo storage.RocketRepository) *Handler {
-	return &Handler{Repository: repo}
+	return &Handler{Repository: repo, Decoder: messages.NewDecoder(messages.Builtin(), messages.ModeStrict, slog.Default())}
 }
 
-// RegisterRoutes registers all API routes with the provided http.ServeMux
-func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
+// RegisterRoutes registers all API routes with the provided router
+func (h *Handler) RegisterRoutes(mux openapi.Router) {
 	// Root path redirects to Swagger
 	mux.HandleFunc("GET /", h.HandleRoot)
 
 	// POST endpoint to receive rocket messages
-	mux.HandleFunc("POST /messages", h.HandleMessages)
+	mux.HandleFunc("POST /messages", h.Auth.Require(auth.ScopeIngest, h.limitClient(h.HandleMessages)))
 
 	// GET endpoint to retrieve a specific rocket by ID
-	mux.HandleFunc("GET /rockets/{id}", h.HandleGetRocket)
+	mux.HandleFunc("GET /rockets/{id}", h.Auth.Require(auth.ScopeRead, h.HandleGetRocket))
+
+	// GET endpoint to list the messages set aside because a rocket exploded
+	mux.HandleFunc("GET /rockets/{id}/quarantine", h.Auth.Require(auth.ScopeRead, h.HandleGetQuarantine))
 
 	// GET endpoint to list all rockets
-	mux.HandleFunc("GET /rockets", h.HandleListRockets)
+	mux.HandleFunc("GET /rockets", h.Auth.Require(auth.ScopeRead, h.HandleListRockets))
 
 	// Health check endpoint
 	mux.HandleFunc("GET /health", h.HandleHealth)
@@ -39,6 +72,9 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	// Serve Swagger documentation
 	mux.HandleFunc("GET /swagger", h.HandleSwagger)
 	mux.HandleFunc("GET /swagger/{path...}", h.HandleSwaggerAssets)
+
+	// Serve the GraphiQL explorer for /graphql
+	mux.HandleFunc("GET /graphiql", h.HandleGraphiQL)
 }
 
 // @Summary Process a rocket message
@@ -47,38 +83,121 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 // @Accept json
 // @Produce json
 // @Param message body models.Envelope true "Message envelope"
-// @Success 202 {object} map[string]any "Message accepted"
-// @Failure 400 {object} map[string]any "Bad request"
+// @Param X-Lunar-Signature header string false "HMAC signature of the body: t=<unix>,emitter=<name>,v1=<hex>"
+// @Success 202 {object} map[string]any "Message accepted, with its processing status (applied, buffered, duplicate, conflict, ignored_after_explosion, rejected, cancelled)"
+// @Failure 400 {object} problem.Problem "invalid_payload, validation_failed or unknown_message_type; every invalid field is listed in errors with its JSON path"
+// @Failure 401 {object} problem.Problem "unauthorized, or invalid_signature when signatures are enforced"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the ingest scope, or channel_forbidden when the client certificate, or a client without one, may not post to the channel"
+// @Failure 413 {object} problem.Problem "payload_too_large when the body exceeds 1 MiB"
+// @Failure 429 {object} problem.Problem "rate_limited for the client or channel; see Retry-After"
+// @Security ApiKeyAuth
 // @Router /messages [post]
 func (h *Handler) HandleMessages(w http.ResponseWriter, r *http.Request) {
-	// Parse the incoming JSON message
-	var envelope models.Envelope
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields() // Strict mode to catch malformed JSON
-
-	if err := decoder.Decode(&envelope); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid request payload: "+err.Error())
+	// Read the raw body so its signature can be checked before decoding
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		if tooLarge := new(http.MaxBytesError); errors.As(err, &tooLarge) {
+			respondWithProblem(w, problem.CodePayloadTooLarge, fmt.Sprintf("Message body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		respondWithProblem(w, problem.CodeInvalidPayload, "Failed to read request body: "+err.Error())
 		return
 	}
+	signature := r.Header.Get(signing.Header)
+	signed := false
+	if h.Signatures.Mode() != signing.ModeOff {
+		emitter, err := h.Signatures.Verify(signature, body)
+		if h.refuseSignature(w, r, emitter, err) {
+			return
+		}
+		signed = err == nil
+	}
+
+	// Parse and validate the message, decoding its typed payload once its type is known
+	envelope, unknown, err := h.Decoder.DecodeEnvelope(body)
 	defer r.Body.Close()
+	if err != nil {
+		p, stage := decodeProblem(err)
+		if stage == deadletter.StageDecode {
+			logging.FromContext(r.Context()).Debug("ingestion decision", "status", "dropped", "reason", err.Error())
+		} else {
+			logging.FromContext(r.Context()).Debug("ingestion decision",
+				"channel", envelope.GetChannel(),
+				"messageNumber", envelope.GetMessageNumber(),
+				"status", "dropped",
+				"reason", errorText(err),
+			)
+		}
+		h.DeadLetters.Add(newLetter(clientOf(r), stage, errorText(err), body, envelope))
+		problem.Write(w, p)
+		return
+	}
 
-	// Validate the message
-	if err := validateEnvelope(envelope); err != nil {
-		respondWithError(w, http.StatusBadRequest, "Invalid message format: "+err.Error())
+	// Fields ignored in lenient mode are recorded once the type is known to be registered
+	h.Decoder.RecordUnknown(envelope.GetMessageType(), unknown)
+
+	// Client certificates may be limited to some channels
+	if subject, ok := h.Channels.Allows(r, envelope.GetChannel()); !ok {
+		logging.FromContext(r.Context()).Debug("ingestion decision",
+			"channel", envelope.GetChannel(),
+			"messageNumber", envelope.GetMessageNumber(),
+			"status", "dropped",
+			"reason", "channel not allowed for client certificate",
+			"subject", subject,
+		)
+		respondWithProblem(w, problem.CodeChannelForbidden, fmt.Sprintf("Client certificate %q may not post to channel %q", subject, envelope.GetChannel()))
 		return
 	}
 
-	// Process the message with request context
-	processed := h.Repository.ProcessMessage(r.Context(), envelope)
+	// A flooded channel must not starve the others
+	if ok, wait := h.ChannelLimiter.Allow(envelope.GetChannel()); !ok {
+		logging.FromContext(r.Context()).Debug("ingestion decision",
+			"channel", envelope.GetChannel(),
+			"messageNumber", envelope.GetMessageNumber(),
+			"status", "dropped",
+			"reason", "channel rate limit exceeded",
+		)
+		respondTooManyRequests(w, wait, fmt.Sprintf("Rate limit exceeded for channel %q", envelope.GetChannel()))
+		return
+	}
 
-	// Respond with success status
-	response := map[string]any{
-		"processed":     processed,
-		"channel":       envelope.GetChannel(),
-		"messageNumber": envelope.GetMessageNumber(),
+	// The signature is used up only now that the message is accepted, so
+	// that a message refused above can be sent again unchanged
+	if signed && h.refuseSignature(w, r, "", h.Signatures.Commit(signature)) {
+		return
+	}
+
+	// Process the message with request context
+	outcome := h.Repository.ProcessMessage(r.Context(), envelope)
+	logging.FromContext(r.Context()).Debug("ingestion decision",
+		"channel", envelope.GetChannel(),
+		"messageNumber", envelope.GetMessageNumber(),
+		"messageType", envelope.GetMessageType(),
+		"status", outcome.Status,
+		"reason", outcome.Reason,
+		"drained", outcome.Drained,
+	)
+	if outcome.Status == storage.StatusRejected {
+		h.DeadLetters.Add(newLetter(clientOf(r), deadletter.StageApply, outcome.Reason, body, envelope))
 	}
 
-	respondWithJSON(w, http.StatusAccepted, response)
+	// Respond with success status and the processing outcome
+	respondWithJSON(w, http.StatusAccepted, ingestionResponse(envelope, outcome))
+}
+
+// refuseSignature handles a failed signature check: it responds and reports
+// true when signatures are enforced, and only logs the failure otherwise
+func (h *Handler) refuseSignature(w http.ResponseWriter, r *http.Request, emitter string, err error) bool {
+	if err == nil {
+		return false
+	}
+	if h.Signatures.Mode() == signing.ModeEnforce {
+		logging.FromContext(r.Context()).Debug("ingestion decision", "status", "dropped", "reason", err.Error(), "emitter", emitter)
+		respondWithProblem(w, problem.CodeInvalidSignature, "Invalid message signature: "+err.Error())
+		return true
+	}
+	logging.FromContext(r.Context()).Warn("Message signature verification failed", "reason", err.Error(), "emitter", emitter)
+	return false
 }
 
 // HandleGetRocket retrieves a specific rocket by ID
@@ -87,80 +206,219 @@ func (h *Handler) HandleMessages(w http.ResponseWriter, r *http.Request) {
 // @Tags Rockets
 // @Produce json
 // @Param id path string true "Rocket ID"
+// @Param If-None-Match header string false "ETag of a cached copy; 304 is returned while it is current"
+// @Param If-Modified-Since header string false "Date of a cached copy; 304 is returned when the rocket was not updated since"
+// @Param waitForVersion query integer false "Long poll: wait until the rocket's version exceeds this value"
+// @Param timeout query string false "Maximum wait with waitForVersion, such as 30s (default 30s, at most 60s)"
 // @Success 200 {object} models.RocketState "Complete rocket object"
-// @Failure 404 {object} map[string]any "Rocket not found"
+// @Header 200 {string} ETag "Version of the rocket state"
+// @Header 200 {string} Last-Modified "Time of the last applied update"
+// @Success 304 "Cached copy is current, or the wait timed out"
+// @Failure 400 {object} problem.Problem "invalid_request when waitForVersion or timeout is invalid"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the read scope"
+// @Security ApiKeyAuth
 // @Router /rockets/{id} [get]
 func (h *Handler) HandleGetRocket(w http.ResponseWriter, r *http.Request) {
 	// Extract the rocket ID from the path parameter
 	rocketID := r.PathValue("id")
 
 	if rocketID == "" {
-		respondWithError(w, http.StatusBadRequest, "Missing rocket ID")
+		respondWithProblem(w, problem.CodeInvalidRequest, "Missing rocket ID")
+		return
+	}
+
+	// Long poll when the client waits for a newer version
+	if r.URL.Query().Has("waitForVersion") {
+		h.waitForRocket(w, r, rocketID)
 		return
 	}
 
 	// Find the rocket with request context
 	rocket, exists := h.Repository.GetRocket(r.Context(), rocketID)
 	if !exists {
-		respondWithError(w, http.StatusNotFound, fmt.Sprintf("Rocket with ID %s not found", rocketID))
+		respondWithProblem(w, problem.CodeNotFound, fmt.Sprintf("Rocket with ID %s not found", rocketID))
 		return
 	}
 
+	// Let clients revalidate cached copies
+	etag := rocketETag(rocket)
+	if notModified(r, etag, rocket.UpdatedAt) {
+		respondNotModified(w, etag, rocket.UpdatedAt)
+		return
+	}
+	setValidators(w, etag, rocket.UpdatedAt)
+
 	// Return the rocket state
 	respondWithJSON(w, http.StatusOK, rocket)
 }
 
+// Bounds of the long-poll wait of GET /rockets/{id}
+const (
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = 60 * time.Second
+)
+
+// waitForRocket answers GET /rockets/{id}?waitForVersion=N once the rocket's
+// version exceeds N, or with 304 when the timeout fires first
+func (h *Handler) waitForRocket(w http.ResponseWriter, r *http.Request, rocketID string) {
+	query := r.URL.Query()
+	version, err := strconv.ParseUint(query.Get("waitForVersion"), 10, 64)
+	if err != nil {
+		respondWithProblem(w, problem.CodeInvalidRequest, "waitForVersion must be a non-negative integer")
+		return
+	}
+	timeout := defaultWaitTimeout
+	if value := query.Get("timeout"); value != "" {
+		timeout, err = time.ParseDuration(value)
+		if err != nil || timeout <= 0 || timeout > maxWaitTimeout {
+			respondWithProblem(w, problem.CodeInvalidRequest, fmt.Sprintf("timeout must be a positive duration of at most %s", maxWaitTimeout))
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+	rocket, err := h.Repository.WaitForVersion(ctx, rocketID, version)
+	switch {
+	case errors.Is(err, storage.ErrRocketNotFound):
+		respondWithProblem(w, problem.CodeNotFound, fmt.Sprintf("Rocket with ID %s not found", rocketID))
+	case err != nil && r.Context().Err() == nil:
+		// Timed out without a newer version
+		if rocket, exists := h.Repository.GetRocket(r.Context(), rocketID); exists {
+			respondNotModified(w, rocketETag(rocket), rocket.UpdatedAt)
+		} else {
+			w.WriteHeader(http.StatusNotModified)
+		}
+	case err == nil:
+		setValidators(w, rocketETag(rocket), rocket.UpdatedAt)
+		respondWithJSON(w, http.StatusOK, rocket)
+	}
+	// Otherwise the client went away and nobody reads the response
+}
+
 // HandleListRockets handles the GET /rockets endpoint
 // @Summary List all rockets
-// @Description Get a list of all rockets, optionally sorted by specified field and order
+// @Description Get a list of all rockets, optionally filtered and sorted by specified field and order. The list is exported as CSV or NDJSON when requested with the Accept header or the format parameter; exports are streamed row by row.
 // @Tags rockets
 // @Produce json
+// @Produce text/csv
+// @Produce application/x-ndjson
 // @Param sort query string false "Sort field (e.g., 'id', 'speed', 'type', 'mission', 'status')"
 // @Param order query string false "Sort order ('asc' or 'desc')"
+// @Param type query string false "Only rockets of this type (case-insensitive)"
+// @Param mission query string false "Only rockets on this mission (case-insensitive)"
+// @Param status query string false "Only 'active' or 'exploded' rockets"
+// @Param format query string false "Response format ('json', 'csv' or 'ndjson'); overrides the Accept header"
+// @Param columns query string false "Comma-separated export columns, in order (id, type, speed, mission, status, updatedAt, version); all by default. CSV and NDJSON only."
+// @Param If-None-Match header string false "ETag of a cached list; 304 is returned while no rocket changed"
+// @Param If-Modified-Since header string false "Date of a cached list; 304 is returned when no rocket was updated since"
 // @Success 200 {array} models.RocketSummary "List of rocket summaries"
+// @Header 200 {string} ETag "Weak tag covering the version of every listed rocket and the representation"
+// @Header 200 {string} Last-Modified "Time of the latest update of any listed rocket"
+// @Success 304 "Cached list is current"
+// @Failure 400 {object} problem.Problem "invalid_request for an unknown format or column"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the read scope"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
 // @Router /rockets [get]
 func (h *Handler) HandleListRockets(w http.ResponseWriter, r *http.Request) {
-	// Get the sort and order parameters
-	sortField := r.URL.Query().Get("sort")
-	order := r.URL.Query().Get("order")
+	// Get the sort, order and filter parameters
+	query := r.URL.Query()
+	sortField := query.Get("sort")
+	order := query.Get("order")
+	filter := storage.RocketFilter{Type: query.Get("type"), Mission: query.Get("mission"), Status: query.Get("status")}
+
+	// Pick the representation before doing any work
+	format, err := negotiateFormat(r)
+	if err != nil {
+		respondWithProblem(w, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+	var columns []exportColumn
+	variant := ""
+	if format != formatJSON {
+		if columns, err = parseColumns(query.Get("columns")); err != nil {
+			respondWithProblem(w, problem.CodeInvalidRequest, err.Error())
+			return
+		}
+		variant = format + ":" + strings.Join(columnNames(columns), ",")
+	}
 
 	// Get the list of rockets with sort options and request context
 	rockets, err := h.Repository.ListRockets(r.Context(), sortField, order)
 	if err != nil {
-		respondWithError(w, http.StatusInternalServerError, "Failed to list rockets: "+err.Error())
+		respondWithProblem(w, problem.CodeInternal, "Failed to list rockets: "+err.Error())
+		return
+	}
+	rockets = storage.FilterRocketSummaries(rockets, filter)
+
+	// Let clients revalidate cached lists
+	w.Header().Set("Vary", "Accept")
+	etag, modified := listETag(rockets, variant), lastModified(rockets)
+	if notModified(r, etag, modified) {
+		respondNotModified(w, etag, modified)
 		return
 	}
+	setValidators(w, etag, modified)
 
-	// Return the rocket list
+	// Stream exports, and return the rocket list otherwise
+	if format != formatJSON {
+		streamExport(w, format, columns, rockets)
+		return
+	}
 	respondWithJSON(w, http.StatusOK, rockets)
 }
 
 // HandleSwagger serves the Swagger UI index
+// @Summary Swagger UI
+// @Description Redirects to /swagger/, where the Swagger UI is served
+// @Tags docs
+// @Success 302 "Redirect to /swagger/"
+// @Router /swagger [get]
 func (h *Handler) HandleSwagger(w http.ResponseWriter, r *http.Request) {
 	// Redirect to swagger/ (with trailing slash) to ensure relative paths resolve correctly
-	if r.URL.Path == "/swagger" {
-		http.Redirect(w, r, "/swagger/", http.StatusFound)
-		return
-	}
-
-	http.ServeFile(w, r, "./docs/swagger/index.html")
+	http.Redirect(w, r, "/swagger/", http.StatusFound)
 }
 
-// HandleSwaggerAssets serves static Swagger assets
+// HandleSwaggerAssets serves the embedded Swagger UI
+// @Summary Swagger UI assets
+// @Description Serves the Swagger UI, which loads the spec from /openapi.json
+// @Tags docs
+// @Produce html
+// @Param path path string true "Asset path; empty for the index"
+// @Success 200 {string} string "Swagger UI page"
+// @Failure 404 {string} string "unknown asset"
+// @Router /swagger/{path} [get]
 func (h *Handler) HandleSwaggerAssets(w http.ResponseWriter, r *http.Request) {
 	filePath := r.PathValue("path")
 
 	// If it's the root of /swagger/ directory, serve index.html
 	if filePath == "" {
-		http.ServeFile(w, r, "./docs/swagger/index.html")
-		return
+		filePath = "index.html"
 	}
+	serveEmbedded(w, r, "swagger/"+filePath)
+}
 
-	// Serve the Swagger UI assets
-	http.ServeFile(w, r, "./docs/swagger/"+filePath)
+// HandleGraphiQL serves the GraphiQL explorer
+// @Summary GraphiQL explorer
+// @Description Serves the GraphiQL explorer for /graphql
+// @Tags docs
+// @Produce html
+// @Success 200 {string} string "GraphiQL page"
+// @Router /graphiql [get]
+func (h *Handler) HandleGraphiQL(w http.ResponseWriter, r *http.Request) {
+	serveEmbedded(w, r, "graphiql/index.html")
 }
 
 // HandleRoot redirects to the Swagger UI
+// @Summary Root
+// @Description Redirects to the Swagger UI
+// @Tags docs
+// @Success 302 "Redirect to /swagger/"
+// @Router / [get]
 func (h *Handler) HandleRoot(w http.ResponseWriter, r *http.Request) {
 	// Redirect to Swagger UI
 	http.Redirect(w, r, "/swagger/", http.StatusFound)
@@ -182,9 +440,58 @@ func (h *Handler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 
 // Helper functions for HTTP responses
 
-// respondWithError sends an error response
-func respondWithError(w http.ResponseWriter, code int, message string) {
-	respondWithJSON(w, code, map[string]string{"error": message})
+// serveEmbedded serves a page embedded in the binary. http.ServeFileFS
+// is not used since it redirects requests for index.html.
+func serveEmbedded(w http.ResponseWriter, r *http.Request, name string) {
+	content, err := docs.UI.ReadFile(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(content))
+}
+
+// respondWithProblem sends a problem details response for code
+func respondWithProblem(w http.ResponseWriter, code problem.Code, detail string) {
+	problem.Write(w, problem.New(code, detail))
+}
+
+// limitClient applies the per-client rate limit before next runs.
+// Clients are identified by their API key, or by IP address without one.
+func (h *Handler) limitClient(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		client := clientOf(r)
+		if ok, wait := h.ClientLimiter.Allow(client); !ok {
+			logging.FromContext(r.Context()).Debug("ingestion decision", "status", "dropped", "reason", "client rate limit exceeded", "client", client)
+			respondTooManyRequests(w, wait, "Rate limit exceeded for this client")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// clientOf identifies the client of r by its API key, or by IP address
+// without one
+func clientOf(r *http.Request) string {
+	if principal := auth.PrincipalFromContext(r.Context()); principal != "" {
+		return "key:" + principal
+	}
+	return "ip:" + clientIP(r)
+}
+
+// clientIP returns the host part of the request's remote address
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// respondTooManyRequests sends 429 with a Retry-After header
+func respondTooManyRequests(w http.ResponseWriter, wait time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(ratelimit.RetryAfter(wait)))
+	respondWithProblem(w, problem.CodeRateLimited, message)
 }
 
 // respondWithJSON sends a JSON response
@@ -6,12 +6,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/rah-0/lunar/internal/audit"
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/config"
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/messages"
 	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/problem"
+	"github.com/rah-0/lunar/internal/ratelimit"
+	"github.com/rah-0/lunar/internal/signing"
 	"github.com/rah-0/lunar/internal/storage"
 )
 
@@ -79,6 +89,12 @@ func TestHandleMessages(t *testing.T) {
 		t.Errorf("Expected status code %d, got %d", http.StatusAccepted, resp.StatusCode)
 	}
 
+	// Check the processing outcome in the response body
+	body := decodeJSON[map[string]any](t, resp.Body)
+	if body["status"] != "applied" || body["processed"] != true {
+		t.Errorf("Expected applied outcome, got status=%v processed=%v", body["status"], body["processed"])
+	}
+
 	// Verify the message was processed
 	rocket, exists := repo.GetRocket(context.Background(), rocketID)
 	if !exists {
@@ -158,6 +174,122 @@ func TestHandleGetRocket(t *testing.T) {
 	}
 }
 
+func TestHandleRocketsConditional(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	testServer := setupTestServer(NewHandler(repo))
+	defer testServer.Close()
+
+	launchTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var env models.Envelope
+	env.Metadata.Channel = "cached-rocket"
+	env.Metadata.MessageNumber = 1
+	env.Metadata.MessageTime = launchTime
+	env.Metadata.MessageType = models.MessageTypeRocketLaunched
+	env.Message = models.MessageContent{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}
+	repo.ProcessMessage(context.Background(), env)
+
+	get := func(path string, headers map[string]string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, testServer.URL+path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, path := range []string{"/rockets/cached-rocket", "/rockets"} {
+		resp := get(path, nil)
+		etag := resp.Header.Get("ETag")
+		if etag == "" || resp.Header.Get("Last-Modified") != launchTime.Format(http.TimeFormat) {
+			t.Fatalf("%s: expected validators, got ETag %q and Last-Modified %q", path, etag, resp.Header.Get("Last-Modified"))
+		}
+
+		if resp := get(path, map[string]string{"If-None-Match": etag}); resp.StatusCode != http.StatusNotModified || resp.Header.Get("ETag") != etag {
+			t.Errorf("%s: expected 304 for a current ETag, got %d", path, resp.StatusCode)
+		}
+		if resp := get(path, map[string]string{"If-Modified-Since": launchTime.Format(http.TimeFormat)}); resp.StatusCode != http.StatusNotModified {
+			t.Errorf("%s: expected 304 when not modified since, got %d", path, resp.StatusCode)
+		}
+		// If-None-Match takes precedence over If-Modified-Since
+		if resp := get(path, map[string]string{"If-None-Match": `"stale"`, "If-Modified-Since": launchTime.Format(http.TimeFormat)}); resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: expected 200 for a stale ETag, got %d", path, resp.StatusCode)
+		}
+
+		// An applied update changes the ETag
+		env.Metadata.MessageNumber++
+		env.Metadata.MessageTime = launchTime.Add(time.Minute)
+		env.Metadata.MessageType = models.MessageTypeRocketSpeedIncreased
+		env.Message = models.MessageContent{By: 100}
+		repo.ProcessMessage(context.Background(), env)
+		resp = get(path, map[string]string{"If-None-Match": etag})
+		if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+			t.Errorf("%s: expected 200 with a new ETag after an update, got %d %q", path, resp.StatusCode, resp.Header.Get("ETag"))
+		}
+		launchTime = env.Metadata.MessageTime
+	}
+}
+
+func TestHandleGetRocketLongPoll(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	testServer := setupTestServer(NewHandler(repo))
+	defer testServer.Close()
+
+	var env models.Envelope
+	env.Metadata.Channel = "polled-rocket"
+	env.Metadata.MessageNumber = 1
+	env.Metadata.MessageTime = time.Now()
+	env.Metadata.MessageType = models.MessageTypeRocketLaunched
+	env.Message = models.MessageContent{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}
+	repo.ProcessMessage(context.Background(), env)
+
+	get := func(query string) *http.Response {
+		t.Helper()
+		resp, err := http.Get(testServer.URL + "/rockets/polled-rocket?" + query)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		return resp
+	}
+
+	// Times out without a newer version
+	resp := get("waitForVersion=1&timeout=20ms")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified || resp.Header.Get("ETag") != `"1"` {
+		t.Errorf("Expected 304 with the current ETag, got %d %q", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	// Returns as soon as the rocket changes
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		env.Metadata.MessageNumber = 2
+		env.Metadata.MessageType = models.MessageTypeRocketSpeedIncreased
+		env.Message = models.MessageContent{By: 100}
+		repo.ProcessMessage(context.Background(), env)
+	}()
+	resp = get("waitForVersion=1&timeout=5s")
+	rocket := decodeJSON[*models.RocketState](t, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || rocket.Version != 2 || rocket.Speed != 600 {
+		t.Errorf("Expected version 2 at speed 600, got %d %+v", resp.StatusCode, rocket)
+	}
+
+	for _, query := range []string{"waitForVersion=-1", "waitForVersion=1&timeout=1h", "waitForVersion=1&timeout=soon"} {
+		resp := get(query)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %d, got %d", query, http.StatusBadRequest, resp.StatusCode)
+		}
+	}
+	if resp, err := http.Get(testServer.URL + "/rockets/unknown?waitForVersion=0"); err != nil || resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown rocket, got %v (%v)", resp.StatusCode, err)
+	}
+}
+
 func TestHandleListRockets(t *testing.T) {
 	repo := storage.NewInMemoryRepository()
 	handler := NewHandler(repo)
@@ -241,3 +373,736 @@ func TestHandleListRockets(t *testing.T) {
 		}
 	}
 }
+
+func TestHandleListRocketsExport(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	testServer := setupTestServer(NewHandler(repo))
+	defer testServer.Close()
+
+	launchTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, rocket := range []struct{ id, rocketType, mission string }{
+		{"export-1", "Falcon-9", "ARTEMIS"},
+		{"export-2", "Falcon-Heavy", "ARTEMIS"},
+		{"export-3", "Falcon-9", "APOLLO, PHASE 2"},
+	} {
+		var env models.Envelope
+		env.Metadata.Channel = rocket.id
+		env.Metadata.MessageNumber = 1
+		env.Metadata.MessageTime = launchTime
+		env.Metadata.MessageType = models.MessageTypeRocketLaunched
+		env.Message = models.MessageContent{Type: rocket.rocketType, LaunchSpeed: 100 * (i + 1), Mission: rocket.mission}
+		repo.ProcessMessage(context.Background(), env)
+	}
+
+	get := func(path, accept string) (*http.Response, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, testServer.URL+path, nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	// CSV with the same filter and sort as JSON, quoting where needed
+	resp, body := get("/rockets?type=falcon-9&sort=speed&order=desc&columns=id,mission,speed", "text/csv")
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/csv") {
+		t.Fatalf("Expected CSV, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if want := "id,mission,speed\nexport-3,\"APOLLO, PHASE 2\",300\nexport-1,ARTEMIS,100\n"; body != want {
+		t.Errorf("Unexpected CSV:\n%s", body)
+	}
+	csvETag := resp.Header.Get("ETag")
+
+	// NDJSON from the format parameter, which overrides Accept
+	resp, body = get("/rockets?format=ndjson&mission=artemis&sort=id&columns=id,updatedAt", "text/csv")
+	if resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("Expected NDJSON, got %q", resp.Header.Get("Content-Type"))
+	}
+	if want := `{"id":"export-1","updatedAt":"2024-01-01T12:00:00Z"}` + "\n" + `{"id":"export-2","updatedAt":"2024-01-01T12:00:00Z"}` + "\n"; body != want {
+		t.Errorf("Unexpected NDJSON:\n%s", body)
+	}
+
+	// Each representation has its own ETag
+	resp, _ = get("/rockets?type=falcon-9&sort=speed&order=desc", "application/json;q=0.9, text/csv;q=0.5")
+	if resp.Header.Get("Content-Type") != "application/json" || resp.Header.Get("ETag") == csvETag || resp.Header.Get("Vary") != "Accept" {
+		t.Errorf("Expected JSON with its own ETag, got %q %q", resp.Header.Get("Content-Type"), resp.Header.Get("ETag"))
+	}
+
+	// Unknown formats and columns are rejected
+	for _, path := range []string{"/rockets?format=xml", "/rockets?format=csv&columns=id,color"} {
+		if resp, _ := get(path, ""); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestHandleMessagesSignature(t *testing.T) {
+	secret := []byte("emitter-secret")
+	handler := NewHandler(storage.NewInMemoryRepository())
+	handler.Signatures = signing.NewVerifier(signing.Settings{
+		Mode:      signing.ModeEnforce,
+		Tolerance: time.Minute,
+		Secrets:   map[string][]byte{"eu-1": secret},
+	}, nil)
+
+	testServer := setupTestServer(handler)
+	defer testServer.Close()
+
+	post := func(channel string, number int, sign func([]byte) string) int {
+		t.Helper()
+		payload := fmt.Sprintf(`{"metadata":{"channel":%q,"messageNumber":%d,"messageTime":"2024-01-01T00:00:00Z","messageType":"RocketLaunched"},`+
+			`"message":{"type":"Falcon-9","launchSpeed":500,"mission":"ARTEMIS"}}`, channel, number)
+		req, _ := http.NewRequest(http.MethodPost, testServer.URL+"/messages", bytes.NewBufferString(payload))
+		if header := sign([]byte(payload)); header != "" {
+			req.Header.Set(signing.Header, header)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	signed := func(body []byte) string { return signing.Sign("eu-1", secret, body, time.Now()) }
+	unsigned := func([]byte) string { return "" }
+
+	if code := post("signed", 1, signed); code != http.StatusAccepted {
+		t.Errorf("Expected signed message to be accepted, got %d", code)
+	}
+	if code := post("unsigned", 1, unsigned); code != http.StatusUnauthorized {
+		t.Errorf("Expected unsigned message to be rejected, got %d", code)
+	}
+
+	// A message refused with 429 can be retried with the same signature,
+	// which is used up once the message is accepted
+	var header string
+	signOnce := func(body []byte) string { header = signed(body); return header }
+	sameHeader := func([]byte) string { return header }
+	handler.ChannelLimiter = ratelimit.NewLimiter(ratelimit.Limit{Rate: 0.001, Burst: 1})
+	post("limited", 1, signed)
+	if code := post("limited", 2, signOnce); code != http.StatusTooManyRequests {
+		t.Fatalf("Expected the channel limit to apply, got %d", code)
+	}
+	handler.ChannelLimiter = nil
+	if code := post("limited", 2, sameHeader); code != http.StatusAccepted {
+		t.Errorf("Expected the retry to be accepted, got %d", code)
+	}
+	if code := post("limited", 2, sameHeader); code != http.StatusUnauthorized {
+		t.Errorf("Expected a replay of the accepted message to be rejected, got %d", code)
+	}
+
+	// Log-only mode accepts the message anyway
+	handler.Signatures.Update(signing.Settings{Mode: signing.ModeLog, Tolerance: time.Minute})
+	if code := post("logged", 1, unsigned); code != http.StatusAccepted {
+		t.Errorf("Expected unsigned message to be accepted in log mode, got %d", code)
+	}
+}
+
+func TestHandleMessagesValidation(t *testing.T) {
+	testServer := setupTestServer(NewHandler(storage.NewInMemoryRepository()))
+	defer testServer.Close()
+
+	tests := []struct {
+		name    string
+		payload string
+		code    problem.Code
+		errors  []string // Expected "path: message" prefixes, in order
+	}{
+		{
+			name:    "metadata",
+			payload: `{"metadata":{"channel":"","messageNumber":0,"messageType":"RocketSpeedIncreased"},"message":{"by":10}}`,
+			code:    problem.CodeValidationFailed,
+			errors:  []string{"metadata.channel", "metadata.messageNumber", "metadata.messageTime"},
+		},
+		{
+			name:    "missing by",
+			payload: `{"metadata":{"channel":"a","messageNumber":1,"messageTime":"2024-01-01T00:00:00Z","messageType":"RocketSpeedIncreased"},"message":{}}`,
+			code:    problem.CodeValidationFailed,
+			errors:  []string{"message.by: required"},
+		},
+		{
+			name:    "foreign field",
+			payload: `{"metadata":{"channel":"a","messageNumber":1,"messageTime":"2024-01-01T00:00:00Z","messageType":"RocketExploded"},"message":{"reason":"x","newMission":"y"}}`,
+			code:    problem.CodeValidationFailed,
+			errors:  []string{"message.newMission: belongs to RocketMissionChanged"},
+		},
+		{
+			name:    "unknown type",
+			payload: `{"metadata":{"channel":"a","messageNumber":1,"messageTime":"2024-01-01T00:00:00Z","messageType":"RocketTeleported"},"message":{}}`,
+			code:    problem.CodeUnknownMessageType,
+			errors:  []string{"metadata.messageType: unknown message type"},
+		},
+		{
+			name:    "every field",
+			payload: `{"metadata":{"channel":"a","messageNumber":"one","messageTime":"2024-01-01T00:00:00Z","messageType":"RocketLaunched","region":"eu"},"message":{"type":1,"launchSpeed":"fast"},"trace":"abc"}`,
+			code:    problem.CodeValidationFailed,
+			errors: []string{
+				"trace: unknown field",
+				"metadata.region: unknown field",
+				"metadata.messageNumber: expected integer, got string",
+				"message.launchSpeed: expected integer, got string",
+				"message.type: expected string, got number",
+				"message.mission: required",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(testServer.URL+"/messages", "application/json", bytes.NewBufferString(tt.payload))
+			if err != nil {
+				t.Fatalf("Error making request: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, resp.StatusCode)
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != problem.ContentType {
+				t.Errorf("Expected content type %q, got %q", problem.ContentType, contentType)
+			}
+			body := decodeJSON[problem.Problem](t, resp.Body)
+			if body.Code != tt.code || body.Type != problem.TypeURI(tt.code) || body.Status != http.StatusBadRequest {
+				t.Errorf("Expected a %s problem, got %+v", tt.code, body)
+			}
+			if len(body.Errors) != len(tt.errors) {
+				t.Fatalf("Expected %d field errors, got %+v", len(tt.errors), body.Errors)
+			}
+			for i, expected := range tt.errors {
+				if got := body.Errors[i].Path + ": " + body.Errors[i].Message; !strings.HasPrefix(got, expected) {
+					t.Errorf("Expected error %d to start with %q, got %q", i, expected, got)
+				}
+			}
+		})
+	}
+
+	// A body that is not JSON has no field errors
+	resp, err := http.Post(testServer.URL+"/messages", "application/json", bytes.NewBufferString(`{"metadata":`))
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	if body := decodeJSON[problem.Problem](t, resp.Body); body.Code != problem.CodeInvalidPayload || len(body.Errors) != 0 {
+		t.Errorf("Expected an invalid_payload problem, got %+v", body)
+	}
+
+	// A body over the size limit is refused before it is read in full
+	oversized := `{"metadata":{},"message":{"reason":"` + strings.Repeat("x", maxMessageSize) + `"}}`
+	resp, err = http.Post(testServer.URL+"/messages", "application/json", strings.NewReader(oversized))
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	if body := decodeJSON[problem.Problem](t, resp.Body); resp.StatusCode != http.StatusRequestEntityTooLarge || body.Code != problem.CodePayloadTooLarge {
+		t.Errorf("Expected a payload_too_large problem, got %d %+v", resp.StatusCode, body)
+	}
+}
+
+func TestHandleMessagesRateLimit(t *testing.T) {
+	handler := NewHandler(storage.NewInMemoryRepository())
+	handler.ChannelLimiter = ratelimit.NewLimiter(ratelimit.Limit{Rate: 0.001, Burst: 1})
+
+	testServer := setupTestServer(handler)
+	defer testServer.Close()
+
+	post := func(channel string, number int) *http.Response {
+		t.Helper()
+		payload := fmt.Sprintf(`{"metadata":{"channel":%q,"messageNumber":%d,"messageTime":"2024-01-01T00:00:00Z","messageType":"RocketLaunched"},`+
+			`"message":{"type":"Falcon-9","launchSpeed":500,"mission":"ARTEMIS"}}`, channel, number)
+		resp, err := http.Post(testServer.URL+"/messages", "application/json", bytes.NewBufferString(payload))
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := post("flooded", 1); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected the first message to be accepted, got %d", resp.StatusCode)
+	}
+	resp := post("flooded", 2)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("Expected 429 with Retry-After, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+
+	// Other channels are not affected
+	if resp := post("quiet", 1); resp.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected another channel to be accepted, got %d", resp.StatusCode)
+	}
+
+	// The client limit applies across channels
+	handler.ClientLimiter = ratelimit.NewLimiter(ratelimit.Limit{Rate: 0.001, Burst: 1})
+	post("client-a", 1)
+	if resp := post("client-b", 1); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected the client limit to apply, got %d", resp.StatusCode)
+	}
+}
+
+func TestHandleMessagesLenient(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	handler := NewHandler(repo)
+	testServer := setupTestServer(handler)
+	defer testServer.Close()
+
+	payload := `{"metadata":{"channel":"lenient","messageNumber":"1","messageTime":"2024-01-01T00:00:00Z","messageType":"RocketLaunched","region":"eu"},` +
+		`"message":{"type":"Falcon-9","launchSpeed":"500","mission":"ARTEMIS","stage":2},"trace":"abc"}`
+	post := func() *http.Response {
+		t.Helper()
+		resp, err := http.Post(testServer.URL+"/messages", "application/json", bytes.NewBufferString(payload))
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// Strict mode rejects fields it does not know
+	if resp := post(); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d in strict mode, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	handler.Decoder.SetMode(messages.ModeLenient)
+	if resp := post(); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status code %d in lenient mode, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+	rocket, exists := repo.GetRocket(context.Background(), "lenient")
+	if !exists || rocket.Speed != 500 {
+		t.Errorf("Expected the rocket to be launched at speed 500, got %+v", rocket)
+	}
+
+	counts := handler.Decoder.UnknownFields()[models.MessageTypeRocketLaunched]
+	for _, field := range []string{"trace", "metadata.region", "message.stage"} {
+		if counts[field] != 1 {
+			t.Errorf("Expected %s to be recorded once, got %v", field, counts)
+		}
+	}
+}
+
+func TestHandleAdminConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.Server.Port = 9999
+
+	store := config.NewStore(cfg, func() (*config.Config, error) { return cfg, nil }, slog.Default())
+
+	mux := http.NewServeMux()
+	NewAdminHandler(store).RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	response, err := http.Get(testServer.URL + "/admin/config")
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+
+	body := decodeJSON[map[string]any](t, response.Body)
+	server, _ := body["server"].(map[string]any)
+	if server["port"] != float64(9999) {
+		t.Errorf("Expected port 9999 in config output, got %v", server["port"])
+	}
+}
+
+func TestHandleAdminReloadConfig(t *testing.T) {
+	current := config.Default()
+	next := config.Default()
+	next.Log.Level = "debug"
+	next.Server.Port = 9000
+
+	var loadErr error
+	store := config.NewStore(current, func() (*config.Config, error) { return next, loadErr }, slog.Default())
+
+	mux := http.NewServeMux()
+	NewAdminHandler(store).RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	response, err := http.Post(testServer.URL+"/admin/config/reload", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, response.StatusCode)
+	}
+	body := decodeJSON[map[string][]config.Change](t, response.Body)
+	if len(body["changes"]) != 2 {
+		t.Errorf("Expected 2 changes, got %v", body["changes"])
+	}
+	if store.Current().Log.Level != "debug" || store.Current().Server.Port != current.Server.Port {
+		t.Errorf("Expected only the reloadable field to change, got %+v", store.Current())
+	}
+
+	// An invalid configuration is rejected
+	loadErr = fmt.Errorf("server.port: must be between 1 and 65535")
+	response, err = http.Post(testServer.URL+"/admin/config/reload", "application/json", nil)
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status code %d, got %d", http.StatusUnprocessableEntity, response.StatusCode)
+	}
+}
+
+func TestHandleAdminRockets(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	launchTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, rocket := range []struct{ id, mission string }{{"admin-1", "ARTEMIS"}, {"admin-2", "APOLLO"}, {"admin-3", "APOLLO"}} {
+		var env models.Envelope
+		env.Metadata.Channel = rocket.id
+		env.Metadata.MessageNumber = 1
+		env.Metadata.MessageTime = launchTime
+		env.Metadata.MessageType = models.MessageTypeRocketLaunched
+		env.Message = models.MessageContent{Type: "Falcon-9", LaunchSpeed: 100, Mission: rocket.mission}
+		repo.ProcessMessage(context.Background(), env)
+	}
+
+	hash, _ := auth.ParseHash(auth.HashKey("ops-secret"))
+	admin := NewAdminHandler(config.NewStore(config.Default(), func() (*config.Config, error) { return config.Default(), nil }, slog.Default()))
+	admin.Rockets = repo
+	admin.Audit = audit.NewTrail(10, slog.Default())
+	admin.Auth = auth.NewAuthenticator(true, []auth.Key{{Name: "ops", Hash: hash, Scopes: []auth.Scope{auth.ScopeAdmin}}})
+	mux := http.NewServeMux()
+	admin.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, testServer.URL+path, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, "ops-secret")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := do(http.MethodDelete, "/admin/rockets/admin-1", ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if resp := do(http.MethodDelete, "/admin/rockets/admin-1", ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d for a deleted rocket, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+
+	resp := do(http.MethodPost, "/admin/rockets/admin-2/cursor/reset", `{"messageNumber": 4}`)
+	if reset := decodeJSON[storage.CursorReset](t, resp.Body); resp.StatusCode != http.StatusOK || reset.Previous != 1 || reset.Cursor != 4 {
+		t.Errorf("Unexpected reset: %d %+v", resp.StatusCode, reset)
+	}
+	if resp := do(http.MethodPost, "/admin/rockets/admin-2/cursor/reset", `{"messageNumber": "4"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid body, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	resp = do(http.MethodPost, "/admin/rockets/admin-2/pause", "")
+	if state := decodeJSON[storage.PauseState](t, resp.Body); resp.StatusCode != http.StatusOK || !state.Paused {
+		t.Errorf("Unexpected pause: %d %+v", resp.StatusCode, state)
+	}
+	resp = do(http.MethodPost, "/admin/rockets/admin-2/resume", "")
+	if state := decodeJSON[storage.PauseState](t, resp.Body); resp.StatusCode != http.StatusOK || state.Paused {
+		t.Errorf("Unexpected resume: %d %+v", resp.StatusCode, state)
+	}
+
+	if resp := do(http.MethodPost, "/admin/rockets/purge", `{}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an empty filter, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	resp = do(http.MethodPost, "/admin/rockets/purge", `{"mission": "apollo"}`)
+	if body := decodeJSON[map[string]any](t, resp.Body); resp.StatusCode != http.StatusOK || body["count"] != 2.0 {
+		t.Errorf("Expected 2 purged rockets, got %d %v", resp.StatusCode, body)
+	}
+
+	// Every action is audited with the name of the key, newest first
+	resp = do(http.MethodGet, "/admin/audit", "")
+	entries := decodeJSON[map[string][]audit.Entry](t, resp.Body)["entries"]
+	var actions []string
+	for _, entry := range entries {
+		if entry.Actor != "ops" {
+			t.Errorf("Expected actor ops, got %+v", entry)
+		}
+		actions = append(actions, entry.Action+" "+entry.Target)
+	}
+	want := []string{"rockets.purge ", "rocket.resume admin-2", "rocket.pause admin-2", "rocket.reset_cursor admin-2", "rocket.delete admin-1"}
+	if strings.Join(actions, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected actions %v, got %v", want, actions)
+	}
+}
+
+func TestHandleOverrideRocket(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	var env models.Envelope
+	env.Metadata.Channel = "override-1"
+	env.Metadata.MessageNumber = 1
+	env.Metadata.MessageTime = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	env.Metadata.MessageType = models.MessageTypeRocketLaunched
+	env.Message = models.MessageContent{Type: "Falcon-9", LaunchSpeed: 100, Mission: "ARTEMIS"}
+	repo.ProcessMessage(context.Background(), env)
+
+	admin := NewAdminHandler(config.NewStore(config.Default(), func() (*config.Config, error) { return config.Default(), nil }, slog.Default()))
+	admin.Rockets = repo
+	admin.Audit = audit.NewTrail(10, slog.Default())
+	mux := http.NewServeMux()
+	admin.RegisterRoutes(mux)
+	NewHandler(repo).RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	patch := func(id, ifMatch, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPatch, testServer.URL+"/admin/rockets/"+id, strings.NewReader(body))
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	if resp := patch("override-1", "", `{"mission": "APOLLO"}`); resp.StatusCode != http.StatusPreconditionRequired {
+		t.Errorf("Expected status code %d without If-Match, got %d", http.StatusPreconditionRequired, resp.StatusCode)
+	}
+	if resp := patch("override-1", `"1"`, `{"reason": "nothing to correct"}`); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d without corrected fields, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+	if resp := patch("override-1", `"0", W/"1"`, `{"mission": "APOLLO"}`); resp.StatusCode != http.StatusPreconditionFailed || resp.Header.Get("ETag") != `"1"` {
+		t.Errorf("Expected status code %d with the current ETag, got %d %q", http.StatusPreconditionFailed, resp.StatusCode, resp.Header.Get("ETag"))
+	}
+
+	resp := patch("override-1", `"0", "1"`, `{"mission": "APOLLO", "reason": "wrong manifest"}`)
+	if rocket := decodeJSON[*models.RocketState](t, resp.Body); resp.StatusCode != http.StatusOK || rocket.Mission != "APOLLO" || rocket.Version != 2 || resp.Header.Get("ETag") != `"2"` {
+		t.Errorf("Unexpected override: %d %+v", resp.StatusCode, rocket)
+	}
+
+	// The override is visible to every reader of the rocket
+	resp, err := http.Get(testServer.URL + "/rockets/override-1")
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	override := decodeJSON[*models.RocketState](t, resp.Body).Overrides["mission"]
+	if override.Value != "APOLLO" || override.Previous != "ARTEMIS" || override.Actor != audit.Anonymous || override.Reason != "wrong manifest" || override.Version != 2 {
+		t.Errorf("Unexpected mission override: %+v", override)
+	}
+
+	entries := admin.Audit.Entries(0)
+	if len(entries) != 1 || entries[0].Action != "rocket.override" || entries[0].Target != "override-1" {
+		t.Errorf("Expected one rocket.override entry, got %+v", entries)
+	}
+
+	// Replaying the rocket applies the correction again
+	resp, err = http.Get(testServer.URL + "/admin/rockets/override-1/replay")
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	if replay := decodeJSON[storage.Replay](t, resp.Body); resp.StatusCode != http.StatusOK || replay.Rocket.Mission != "APOLLO" || replay.Messages != 2 || !replay.Matches {
+		t.Errorf("Expected the replay to end with the corrected mission, got %d %+v", resp.StatusCode, replay)
+	}
+
+	// A rocket created by a buffered message has no type or mission yet,
+	// and its speed can still be corrected
+	env.Metadata.Channel = "override-2"
+	env.Metadata.MessageNumber = 2
+	env.Metadata.MessageType = models.MessageTypeRocketSpeedIncreased
+	env.Message = models.MessageContent{By: 100}
+	repo.ProcessMessage(context.Background(), env)
+	resp = patch("override-2", `"0"`, `{"speed": 300}`)
+	if rocket := decodeJSON[*models.RocketState](t, resp.Body); resp.StatusCode != http.StatusOK || rocket.Speed != 300 || rocket.Type != "" {
+		t.Errorf("Unexpected override of an unlaunched rocket: %d %+v", resp.StatusCode, rocket)
+	}
+}
+
+// rejectingRepository rejects every message while reject is set, as a
+// reducer refusing an update would
+type rejectingRepository struct {
+	*storage.InMemoryRepository
+	reject bool
+}
+
+func (r *rejectingRepository) ProcessMessage(ctx context.Context, envelope models.Envelope) storage.ProcessOutcome {
+	if r.reject {
+		return storage.ProcessOutcome{Status: storage.StatusRejected, Reason: "refused"}
+	}
+	return r.InMemoryRepository.ProcessMessage(ctx, envelope)
+}
+
+func TestHandleDeadLetters(t *testing.T) {
+	repo := &rejectingRepository{InMemoryRepository: storage.NewInMemoryRepository(), reject: true}
+	handler := NewHandler(repo)
+	handler.DeadLetters = deadletter.NewStore(10)
+	admin := NewAdminHandler(config.NewStore(config.Default(), func() (*config.Config, error) { return config.Default(), nil }, slog.Default()))
+	admin.Messages = handler
+	admin.Audit = audit.NewTrail(10, slog.Default())
+	mux := http.NewServeMux()
+	handler.RegisterRoutes(mux)
+	admin.RegisterRoutes(mux)
+	testServer := httptest.NewServer(mux)
+	defer testServer.Close()
+
+	do := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, testServer.URL+path, strings.NewReader(body))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+	launch := func(channel, messageType string) string {
+		return fmt.Sprintf(`{"metadata":{"channel":%q,"messageNumber":1,"messageTime":"2024-01-01T12:00:00Z","messageType":%q},"message":{"type":"Falcon-9","launchSpeed":100,"mission":"ARTEMIS"}}`, channel, messageType)
+	}
+
+	// Messages failing at each stage are kept with their raw body
+	do(http.MethodPost, "/messages", `{"metadata":`)
+	do(http.MethodPost, "/messages", launch("dead-2", "RocketTeleported"))
+	if resp := do(http.MethodPost, "/messages", launch("dead-1", "RocketLaunched")); resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("Expected status code %d for a rejected launch, got %d", http.StatusAccepted, resp.StatusCode)
+	}
+	letters := decodeJSON[map[string][]deadletter.Letter](t, do(http.MethodGet, "/admin/deadletters", "").Body)["deadLetters"]
+	var stages []string
+	for _, letter := range letters {
+		stages = append(stages, letter.Stage)
+	}
+	if want := []string{deadletter.StageApply, deadletter.StageValidate, deadletter.StageDecode}; strings.Join(stages, ",") != strings.Join(want, ",") {
+		t.Fatalf("Expected letters at stages %v, got %+v", want, letters)
+	}
+	if letter := letters[0]; letter.Channel != "dead-1" || letter.MessageType != "RocketLaunched" || letter.Reason != "refused" || letter.Body != launch("dead-1", "RocketLaunched") || !strings.HasPrefix(letter.Client, "ip:") {
+		t.Errorf("Unexpected letter: %+v", letter)
+	}
+	filtered := decodeJSON[map[string][]deadletter.Letter](t, do(http.MethodGet, "/admin/deadletters?stage=decode", "").Body)["deadLetters"]
+	if len(filtered) != 1 || filtered[0].Body != `{"metadata":` {
+		t.Errorf("Expected the decode letter, got %+v", filtered)
+	}
+
+	// Resubmitting a message that is rejected again counts the attempt
+	applyID, validateID, decodeID := letters[0].ID, letters[1].ID, letters[2].ID
+	resp := do(http.MethodPost, "/admin/deadletters/"+applyID+"/resubmit", "")
+	if body := decodeJSON[map[string]any](t, resp.Body); resp.StatusCode != http.StatusOK || body["status"] != "rejected" || body["deadLetter"].(map[string]any)["attempts"] != 2.0 {
+		t.Errorf("Expected the letter to be rejected again, got %d %v", resp.StatusCode, body)
+	}
+	if resp := do(http.MethodPost, "/admin/deadletters/"+validateID+"/resubmit", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for a message that is still invalid, got %d", http.StatusBadRequest, resp.StatusCode)
+	}
+
+	// Once the repository accepts it, the stored body is applied and the letter deleted
+	repo.reject = false
+	resp = do(http.MethodPost, "/admin/deadletters/"+applyID+"/resubmit", "")
+	if body := decodeJSON[map[string]any](t, resp.Body); resp.StatusCode != http.StatusOK || body["status"] != "applied" || body["deadLetter"] != nil {
+		t.Errorf("Expected the message to be applied, got %d %v", resp.StatusCode, body)
+	}
+	if resp := do(http.MethodGet, "/admin/deadletters/"+applyID, ""); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected the letter to be deleted, got %d", resp.StatusCode)
+	}
+
+	// A fixed body replaces the stored one
+	resp = do(http.MethodPost, "/admin/deadletters/"+validateID+"/resubmit", launch("dead-2", "RocketLaunched"))
+	if body := decodeJSON[map[string]any](t, resp.Body); resp.StatusCode != http.StatusOK || body["status"] != "applied" {
+		t.Errorf("Expected the fixed message to be applied, got %d %v", resp.StatusCode, body)
+	}
+	if rocket, ok := repo.GetRocket(context.Background(), "dead-2"); !ok || rocket.Mission != "ARTEMIS" {
+		t.Errorf("Expected the fixed launch to create the rocket, got %+v", rocket)
+	}
+
+	if resp := do(http.MethodDelete, "/admin/deadletters/"+decodeID, ""); resp.StatusCode != http.StatusNoContent {
+		t.Errorf("Expected status code %d, got %d", http.StatusNoContent, resp.StatusCode)
+	}
+	if n := handler.DeadLetters.Len(); n != 0 {
+		t.Errorf("Expected no letters left, got %d", n)
+	}
+	if entries := admin.Audit.Entries(0); len(entries) != 5 || entries[0].Action != "deadletter.delete" {
+		t.Errorf("Expected four resubmissions and a deletion in the audit trail, got %+v", entries)
+	}
+}
+
+func TestDeadLetterRejections(t *testing.T) {
+	store := deadletter.NewStore(10)
+	repo := storage.NewInMemoryRepository()
+	repo.SetRejectionHandler(DeadLetterRejections(store))
+	ctx := context.Background()
+
+	// A buffered message that fails once drained is kept like any rejection
+	var invalid models.Envelope
+	invalid.Metadata.Channel = "drained"
+	invalid.Metadata.MessageNumber = 2
+	invalid.Metadata.MessageTime = time.Now()
+	invalid.Metadata.MessageType = models.MessageTypeRocketSpeedIncreased
+	repo.ProcessMessage(ctx, invalid)
+	launch := invalid
+	launch.Metadata.MessageNumber = 1
+	launch.Metadata.MessageType = models.MessageTypeRocketLaunched
+	launch.Message = models.MessageContent{Type: "Falcon-9", LaunchSpeed: 100, Mission: "ARTEMIS"}
+	repo.ProcessMessage(ctx, launch)
+
+	letters := store.List(deadletter.Filter{})
+	if len(letters) != 1 {
+		t.Fatalf("Expected one dead letter, got %+v", letters)
+	}
+	letter := letters[0]
+	if letter.Client != BufferedClient || letter.Stage != deadletter.StageApply || letter.Channel != "drained" || letter.MessageNumber != 2 || letter.Reason == "" {
+		t.Errorf("Unexpected letter: %+v", letter)
+	}
+	if body := decodeJSON[models.Envelope](t, strings.NewReader(letter.Body)); body.GetMessageType() != models.MessageTypeRocketSpeedIncreased {
+		t.Errorf("Expected the envelope as the body, got %s", letter.Body)
+	}
+}
+
+func TestHandleGetQuarantine(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	testServer := setupTestServer(NewHandler(repo))
+	defer testServer.Close()
+
+	launchTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, message := range []struct {
+		messageType string
+		content     models.MessageContent
+	}{
+		{models.MessageTypeRocketLaunched, models.MessageContent{Type: "Falcon-9", LaunchSpeed: 100, Mission: "ARTEMIS"}},
+		{models.MessageTypeRocketExploded, models.MessageContent{Reason: "ENGINE_FAILURE"}},
+		{models.MessageTypeRocketSpeedIncreased, models.MessageContent{By: 300}},
+	} {
+		var env models.Envelope
+		env.Metadata.Channel = "quarantine-1"
+		env.Metadata.MessageNumber = i + 1
+		env.Metadata.MessageTime = launchTime
+		env.Metadata.MessageType = message.messageType
+		env.Message = message.content
+		repo.ProcessMessage(context.Background(), env)
+	}
+
+	resp, err := http.Get(testServer.URL + "/rockets/quarantine-1/quarantine")
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	quarantine := decodeJSON[storage.QuarantineState](t, resp.Body)
+	messages := quarantine.Messages
+	if resp.StatusCode != http.StatusOK || len(messages) != 1 || quarantine.Dropped != 0 {
+		t.Fatalf("Expected one quarantined message, got %d %+v", resp.StatusCode, quarantine)
+	}
+	if got := messages[0]; got.Envelope.GetMessageNumber() != 3 || got.Envelope.Message.By != 300 || got.Reason != storage.QuarantineArrivedAfterExplosion {
+		t.Errorf("Unexpected quarantined message: %+v", got)
+	}
+
+	resp, err = http.Get(testServer.URL + "/rockets/missing/quarantine")
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected status code %d, got %d", http.StatusNotFound, resp.StatusCode)
+	}
+}
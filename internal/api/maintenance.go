@@ -0,0 +1,377 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/rah-0/lunar/internal/audit"
+	"github.com/rah-0/lunar/internal/problem"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// Audit actions of the rocket maintenance endpoints
+const (
+	actionDeleteRocket = "rocket.delete"
+	actionClearBuffer  = "rocket.clear_buffer"
+	actionResetCursor  = "rocket.reset_cursor"
+	actionPurgeRockets = "rockets.purge"
+	actionOverride     = "rocket.override"
+	actionPause        = "rocket.pause"
+	actionResume       = "rocket.resume"
+)
+
+// maxAdminBodySize bounds the size of admin request bodies
+const maxAdminBodySize = 1 << 16
+
+// CursorResetRequest is the body of POST /admin/rockets/{id}/cursor/reset
+type CursorResetRequest struct {
+	MessageNumber int `json:"messageNumber"` // Number of the last processed message; 0 accepts numbering from 1 again
+}
+
+// PurgeRequest is the body of POST /admin/rockets/purge. At least one field
+// must be set; comparisons ignore case.
+type PurgeRequest struct {
+	Type    string `json:"type,omitempty"`
+	Mission string `json:"mission,omitempty"`
+	Status  string `json:"status,omitempty" enums:"active,exploded"`
+}
+
+// OverrideRequest is the body of PATCH /admin/rockets/{id}. At least one of
+// type, speed and mission is required.
+type OverrideRequest struct {
+	Type    *string `json:"type,omitempty" example:"Falcon-9"`
+	Speed   *int    `json:"speed,omitempty" example:"3000"`
+	Mission *string `json:"mission,omitempty" example:"ARTEMIS"`
+	Reason  string  `json:"reason,omitempty" example:"RocketMissionChanged 42 was never received"`
+}
+
+// HandleDeleteRocket deletes a rocket
+// @Summary Delete a rocket
+// @Description Forgets a rocket with its buffered messages and message cursor, so that its next message creates it again, with a version above the deleted one's so that its old ETags never match. Long polls waiting for the rocket end with 404. The action is recorded in the audit trail.
+// @Tags admin
+// @Param id path string true "Rocket ID"
+// @Success 204 "Rocket deleted"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/{id} [delete]
+func (a *AdminHandler) HandleDeleteRocket(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	if err := a.Rockets.DeleteRocket(r.Context(), rocketID); err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+
+	a.Audit.Record(r.Context(), actionDeleteRocket, rocketID, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// HandleClearBuffer drops the buffered messages of a rocket
+// @Summary Clear a rocket's buffer
+// @Description Drops the out-of-order messages buffered for a rocket, for example when a gap will never be filled. The action is recorded in the audit trail.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Rocket ID"
+// @Success 200 {object} map[string]any "Rocket ID and number of dropped messages"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/{id}/buffer [delete]
+func (a *AdminHandler) HandleClearBuffer(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	dropped, err := a.Rockets.ClearBuffer(r.Context(), rocketID)
+	if err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+
+	a.Audit.Record(r.Context(), actionClearBuffer, rocketID, map[string]any{"dropped": dropped})
+	respondWithJSON(w, http.StatusOK, map[string]any{"id": rocketID, "dropped": dropped})
+}
+
+// HandleResetCursor moves the message cursor of a rocket
+// @Summary Reset a rocket's message cursor
+// @Description Sets the number of the last message processed for a rocket, 0 by default, so that numbering can restart after an emitter reset. Buffered messages at or below the new cursor are dropped, and those that became next in sequence are applied. Moving the cursor increments the rocket's version. The action is recorded in the audit trail.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Rocket ID"
+// @Param request body api.CursorResetRequest false "New cursor"
+// @Success 200 {object} storage.CursorReset "Cursor before and after the reset"
+// @Failure 400 {object} problem.Problem "invalid_request for an invalid body or a negative message number"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/{id}/cursor/reset [post]
+func (a *AdminHandler) HandleResetCursor(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	var req CursorResetRequest
+	if err := readAdminBody(r, &req); err != nil {
+		respondWithProblem(w, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+	if req.MessageNumber < 0 {
+		respondWithProblem(w, problem.CodeInvalidRequest, "messageNumber must not be negative")
+		return
+	}
+
+	reset, err := a.Rockets.ResetCursor(r.Context(), rocketID, req.MessageNumber)
+	if err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+
+	a.Audit.Record(r.Context(), actionResetCursor, rocketID, map[string]any{
+		"previous": reset.Previous,
+		"cursor":   reset.Cursor,
+		"dropped":  reset.Dropped,
+		"drained":  reset.Drained,
+	})
+	respondWithJSON(w, http.StatusOK, reset)
+}
+
+// HandlePauseRocket stops applying the messages of a rocket
+// @Summary Pause a rocket
+// @Description Freezes the state of a rocket while its messages keep arriving: they are accepted and held in its buffer instead of being applied, until the rocket is resumed. The rocket shows paused: true, and pausing a paused rocket does nothing. The action is recorded in the audit trail.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Rocket ID"
+// @Success 200 {object} storage.PauseState "Paused, with the number of held messages"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/{id}/pause [post]
+func (a *AdminHandler) HandlePauseRocket(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	state, err := a.Rockets.PauseRocket(r.Context(), rocketID)
+	if err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+
+	a.Audit.Record(r.Context(), actionPause, rocketID, map[string]any{"held": state.Held})
+	respondWithJSON(w, http.StatusOK, state)
+}
+
+// HandleResumeRocket applies the messages held while a rocket was paused
+// @Summary Resume a paused rocket
+// @Description Applies the messages held while the rocket was paused in message number order, and applies new messages again. Messages that are still out of order stay buffered. Resuming a running rocket does nothing. The action is recorded in the audit trail.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Rocket ID"
+// @Success 200 {object} storage.PauseState "Resumed, with the number of applied and still buffered messages"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/{id}/resume [post]
+func (a *AdminHandler) HandleResumeRocket(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	state, err := a.Rockets.ResumeRocket(r.Context(), rocketID)
+	if err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+
+	a.Audit.Record(r.Context(), actionResume, rocketID, map[string]any{"drained": state.Drained, "held": state.Held})
+	respondWithJSON(w, http.StatusOK, state)
+}
+
+// HandlePurgeRockets deletes the rockets matching a filter
+// @Summary Purge rockets
+// @Description Deletes every rocket matching the filter, like DELETE /admin/rockets/{id} does for one. At least one criterion is required, so that nothing is purged by mistake. The action is recorded in the audit trail.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param request body api.PurgeRequest true "Rockets to purge"
+// @Success 200 {object} map[string]any "IDs of the purged rockets and their count"
+// @Failure 400 {object} problem.Problem "invalid_request for an invalid body, an empty filter or an unknown status"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/purge [post]
+func (a *AdminHandler) HandlePurgeRockets(w http.ResponseWriter, r *http.Request) {
+	var req PurgeRequest
+	if err := readAdminBody(r, &req); err != nil {
+		respondWithProblem(w, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+	filter := storage.RocketFilter{Type: req.Type, Mission: req.Mission, Status: req.Status}
+	if filter == (storage.RocketFilter{}) {
+		respondWithProblem(w, problem.CodeInvalidRequest, "at least one of type, mission or status is required")
+		return
+	}
+	if status := strings.ToLower(req.Status); status != "" && status != "active" && status != "exploded" {
+		respondWithProblem(w, problem.CodeInvalidRequest, fmt.Sprintf("unknown status %q (expected active or exploded)", req.Status))
+		return
+	}
+
+	purged, err := a.Rockets.PurgeRockets(r.Context(), filter)
+	if err != nil {
+		respondWithProblem(w, problem.CodeInternal, "Failed to purge rockets: "+err.Error())
+		return
+	}
+
+	a.Audit.Record(r.Context(), actionPurgeRockets, "", map[string]any{"filter": req, "purged": purged})
+	respondWithJSON(w, http.StatusOK, map[string]any{"purged": purged, "count": len(purged)})
+}
+
+// HandleOverrideRocket corrects fields of a rocket
+// @Summary Correct a rocket
+// @Description Corrects the type, speed or mission of a rocket when the emitter got them wrong, for example after a missed RocketMissionChanged. If-Match must carry the ETag of the version the correction is based on. The correction is applied as a synthetic RocketStateOverridden message, which increments the version without touching the message cursor, and each corrected field is listed in the rocket's overrides until a message from the emitter changes it again. Only the fields sent are checked, so a rocket that was not launched yet can have its speed corrected. The synthetic message is kept in the rocket's history among the emitter's messages, so replaying the rocket applies the correction again.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param id path string true "Rocket ID"
+// @Param If-Match header string true "ETag of the rocket version the correction is based on, as returned by GET /rockets/{id}"
+// @Param request body api.OverrideRequest true "Corrected fields"
+// @Success 200 {object} models.RocketState "Corrected rocket"
+// @Header 200 {string} ETag "Version of the corrected rocket"
+// @Failure 400 {object} problem.Problem "invalid_request for an invalid body, no corrected field or an invalid value"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 412 {object} problem.Problem "version_mismatch when the rocket changed since; the ETag header carries the current version"
+// @Failure 428 {object} problem.Problem "version_required when If-Match is missing"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/{id} [patch]
+func (a *AdminHandler) HandleOverrideRocket(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		respondWithProblem(w, problem.CodeVersionRequired, "If-Match with the ETag of the rocket is required")
+		return
+	}
+	versions, anyVersion := parseIfMatch(header)
+
+	var req OverrideRequest
+	if err := readAdminBody(r, &req); err != nil {
+		respondWithProblem(w, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+	switch {
+	case req.Type == nil && req.Speed == nil && req.Mission == nil:
+		respondWithProblem(w, problem.CodeInvalidRequest, "at least one of type, speed or mission is required")
+		return
+	case req.Type != nil && *req.Type == "", req.Mission != nil && *req.Mission == "":
+		respondWithProblem(w, problem.CodeInvalidRequest, "type and mission must not be empty")
+		return
+	case req.Speed != nil && *req.Speed < 0:
+		respondWithProblem(w, problem.CodeInvalidRequest, "speed must not be negative")
+		return
+	}
+	match := func(version uint64) bool { return anyVersion || slices.Contains(versions, version) }
+
+	override := storage.Override{Type: req.Type, Speed: req.Speed, Mission: req.Mission, Actor: audit.Actor(r.Context()), Reason: req.Reason}
+	rocket, err := a.Rockets.OverrideRocket(r.Context(), rocketID, match, override)
+	if errors.Is(err, storage.ErrVersionMismatch) {
+		w.Header().Set("ETag", rocketETag(rocket))
+		respondWithProblem(w, problem.CodeVersionMismatch, fmt.Sprintf("Rocket %s is at version %d", rocketID, rocket.Version))
+		return
+	}
+	if err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+
+	// Record the corrections with their previous values
+	details := map[string]any{"version": rocket.Version}
+	for field, correction := range rocket.Overrides {
+		if correction.Version == rocket.Version {
+			details[field] = map[string]any{"value": correction.Value, "previous": correction.Previous}
+		}
+	}
+	if req.Reason != "" {
+		details["reason"] = req.Reason
+	}
+	a.Audit.Record(r.Context(), actionOverride, rocketID, details)
+
+	setValidators(w, rocketETag(rocket), rocket.UpdatedAt)
+	respondWithJSON(w, http.StatusOK, rocket)
+}
+
+// HandleReplayRocket rebuilds the state of a rocket from its history
+// @Summary Replay a rocket
+// @Description Applies the messages applied to the rocket again, in the order they were applied and with the operator corrections among them, to the state the rocket was created with, and returns the result next to whether it matches the rocket. The rocket itself is left alone. A rocket keeps its latest 1000 applied messages; folded counts the older ones, which are folded into the starting state.
+// @Tags admin
+// @Produce json
+// @Param id path string true "Rocket ID"
+// @Success 200 {object} storage.Replay "The rebuilt state, the number of messages replayed and folded, and whether it matches"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /admin/rockets/{id}/replay [get]
+func (a *AdminHandler) HandleReplayRocket(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	replay, err := a.Rockets.ReplayRocket(r.Context(), rocketID)
+	if err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, replay)
+}
+
+// HandleGetAudit returns the audit trail
+// @Summary Get the audit trail
+// @Description Returns the latest admin actions, newest first: who did what to which rocket, and when. Actions are also written to the log.
+// @Tags admin
+// @Produce json
+// @Param limit query int false "Maximum number of entries; every entry kept by default"
+// @Success 200 {object} map[string][]audit.Entry "entries, newest first"
+// @Failure 400 {object} problem.Problem "invalid_request for an invalid limit"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the admin scope"
+// @Security ApiKeyAuth
+// @Router /admin/audit [get]
+func (a *AdminHandler) HandleGetAudit(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 1 {
+			respondWithProblem(w, problem.CodeInvalidRequest, "limit must be a positive integer")
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]any{"entries": a.Audit.Entries(limit)})
+}
+
+// readAdminBody decodes an optional JSON body into v, rejecting unknown fields
+func readAdminBody(r *http.Request, v any) error {
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxAdminBodySize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+	return nil
+}
+
+// respondWithRepositoryError sends the problem matching a repository error
+func respondWithRepositoryError(w http.ResponseWriter, rocketID string, err error) {
+	switch {
+	case errors.Is(err, storage.ErrRocketNotFound):
+		respondWithProblem(w, problem.CodeNotFound, fmt.Sprintf("Rocket with ID %s not found", rocketID))
+	case errors.Is(err, storage.ErrInvalidOverride):
+		respondWithProblem(w, problem.CodeInvalidRequest, err.Error())
+	default:
+		respondWithProblem(w, problem.CodeInternal, err.Error())
+	}
+}
@@ -0,0 +1,26 @@
+package api
+
+import "net/http"
+
+// HandleGetQuarantine lists the messages set aside because a rocket exploded
+// @Summary Get a rocket's quarantined messages
+// @Description Lists, in message number order, the messages set aside because the rocket exploded: those waiting in its buffer at the explosion (buffered_at_explosion) and newer ones that arrived afterwards (arrived_after_explosion). When the rocket is relaunched, the messages numbered after the relaunch leave the quarantine and are applied once their numbers fit; the others stay for post-mortems. A rocket keeps the latest 1000 quarantined messages; dropped counts those evicted to make room.
+// @Tags Rockets
+// @Produce json
+// @Param id path string true "Rocket ID"
+// @Success 200 {object} storage.QuarantineState "messages, in message number order, and the number dropped"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the read scope"
+// @Failure 404 {object} problem.Problem "not_found"
+// @Failure 500 {object} problem.Problem "internal_error"
+// @Security ApiKeyAuth
+// @Router /rockets/{id}/quarantine [get]
+func (h *Handler) HandleGetQuarantine(w http.ResponseWriter, r *http.Request) {
+	rocketID := r.PathValue("id")
+	quarantine, err := h.Repository.Quarantine(r.Context(), rocketID)
+	if err != nil {
+		respondWithRepositoryError(w, rocketID, err)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, quarantine)
+}
@@ -0,0 +1,97 @@
+// Package audit records the actions operators take through the admin API
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/rah-0/lunar/internal/auth"
+)
+
+// DefaultCapacity is the number of entries a trail keeps by default
+const DefaultCapacity = 1000
+
+// Anonymous is the actor of actions taken while authentication is disabled
+const Anonymous = "anonymous"
+
+// Entry records one action: who did what to which target, and when
+type Entry struct {
+	Time    time.Time      `json:"time"`
+	Actor   string         `json:"actor"`             // Name of the API key, or anonymous
+	Action  string         `json:"action"`            // Such as rocket.delete
+	Target  string         `json:"target,omitempty"`  // Rocket ID, or empty for actions on many rockets
+	Details map[string]any `json:"details,omitempty"` // Parameters and effect of the action
+}
+
+// Trail keeps the latest entries in memory and writes every entry to the log,
+// where it outlives the process
+type Trail struct {
+	logger *slog.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	entries  []Entry // Ring buffer of at most capacity entries
+	next     int     // Position of the next entry once the buffer is full
+	capacity int
+}
+
+// NewTrail creates a trail keeping the latest capacity entries
+func NewTrail(capacity int, logger *slog.Logger) *Trail {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &Trail{logger: logger, now: time.Now, capacity: capacity}
+}
+
+// Record adds an entry whose actor is the principal of ctx. A nil trail
+// records nothing.
+func (t *Trail) Record(ctx context.Context, action, target string, details map[string]any) {
+	if t == nil {
+		return
+	}
+	actor := Actor(ctx)
+	entry := Entry{Time: t.now().UTC(), Actor: actor, Action: action, Target: target, Details: details}
+
+	t.mu.Lock()
+	if len(t.entries) < t.capacity {
+		t.entries = append(t.entries, entry)
+	} else {
+		t.entries[t.next] = entry
+		t.next = (t.next + 1) % t.capacity
+	}
+	t.mu.Unlock()
+
+	t.logger.Info("Audit", "actor", actor, "action", action, "target", target, "details", details)
+}
+
+// Actor returns the name of the API key that authenticated the request of
+// ctx, or Anonymous
+func Actor(ctx context.Context) string {
+	if name := auth.PrincipalFromContext(ctx); name != "" {
+		return name
+	}
+	return Anonymous
+}
+
+// Entries returns up to limit entries, newest first. A limit below 1
+// returns every entry kept.
+func (t *Trail) Entries(limit int) []Entry {
+	if t == nil {
+		return []Entry{}
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := len(t.entries)
+	if limit < 1 || limit > n {
+		limit = n
+	}
+	entries := make([]Entry, 0, limit)
+	for i := 0; i < limit; i++ {
+		// The newest entry is just before next, wrapping around
+		entries = append(entries, t.entries[(t.next+n-1-i)%n])
+	}
+	return entries
+}
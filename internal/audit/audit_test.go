@@ -0,0 +1,48 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rah-0/lunar/internal/auth"
+)
+
+func TestTrail(t *testing.T) {
+	var logs bytes.Buffer
+	trail := NewTrail(2, slog.New(slog.NewTextHandler(&logs, nil)))
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	trail.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	ctx := auth.WithPrincipal(context.Background(), "ops")
+	trail.Record(context.Background(), "rocket.delete", "rocket-1", nil)
+	trail.Record(ctx, "rocket.clear_buffer", "rocket-2", map[string]any{"dropped": 3})
+	trail.Record(ctx, "rocket.delete", "rocket-3", nil)
+
+	// Only the latest entries are kept, newest first
+	entries := trail.Entries(0)
+	if len(entries) != 2 || entries[0].Target != "rocket-3" || entries[1].Target != "rocket-2" {
+		t.Fatalf("Unexpected entries: %+v", entries)
+	}
+	if entries[1].Actor != "ops" || entries[1].Details["dropped"] != 3 || !entries[1].Time.Equal(now.Add(-time.Second)) {
+		t.Errorf("Unexpected entry: %+v", entries[1])
+	}
+	if limited := trail.Entries(1); len(limited) != 1 || limited[0].Target != "rocket-3" {
+		t.Errorf("Expected the newest entry only, got %+v", limited)
+	}
+
+	// Every entry is logged, including those no longer kept
+	if !strings.Contains(logs.String(), "actor=anonymous action=rocket.delete target=rocket-1") {
+		t.Errorf("Expected the first action in the log, got %q", logs.String())
+	}
+
+	// A nil trail records nothing
+	var disabled *Trail
+	disabled.Record(ctx, "rocket.delete", "rocket-1", nil)
+	if entries := disabled.Entries(0); len(entries) != 0 {
+		t.Errorf("Expected no entries, got %+v", entries)
+	}
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rah-0/lunar/internal/problem"
+)
+
+// Scope is a permission carried by an API key
+type Scope string
+
+// Scope constants
+const (
+	ScopeIngest Scope = "ingest" // Post rocket messages
+	ScopeRead   Scope = "read"   // Read rocket state
+	ScopeAdmin  Scope = "admin"  // Operate the service; implies every other scope
+)
+
+// hashPrefix identifies the hashing scheme of stored key hashes
+const hashPrefix = "sha256:"
+
+// APIKeyHeader is an alternative to "Authorization: Bearer <key>"
+const APIKeyHeader = "X-API-Key"
+
+// ParseScope validates a scope name
+func ParseScope(name string) (Scope, error) {
+	switch s := Scope(strings.ToLower(strings.TrimSpace(name))); s {
+	case ScopeIngest, ScopeRead, ScopeAdmin:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown scope %q (expected ingest, read or admin)", name)
+	}
+}
+
+// HashKey returns the at-rest representation of a plaintext API key
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hashPrefix + hex.EncodeToString(sum[:])
+}
+
+// ParseHash validates a stored hash of the form "sha256:<64 hex digits>"
+func ParseHash(hash string) ([]byte, error) {
+	digest, ok := strings.CutPrefix(strings.TrimSpace(hash), hashPrefix)
+	if !ok {
+		return nil, fmt.Errorf("hash must start with %q", hashPrefix)
+	}
+	raw, err := hex.DecodeString(digest)
+	if err != nil || len(raw) != sha256.Size {
+		return nil, fmt.Errorf("hash must be %d hex digits after %q", sha256.Size*2, hashPrefix)
+	}
+	return raw, nil
+}
+
+// Key is an API key known to the server, identified by the hash of its secret
+type Key struct {
+	Name   string
+	Hash   []byte
+	Scopes []Scope
+}
+
+// Allows reports whether the key grants scope
+func (k *Key) Allows(scope Scope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator checks API keys on incoming requests. Its key set can be
+// swapped at any time, which is how keys are rotated without a restart.
+type Authenticator struct {
+	enabled atomic.Bool
+	keys    atomic.Pointer[[]Key]
+}
+
+// NewAuthenticator creates an authenticator; when disabled every request is allowed
+func NewAuthenticator(enabled bool, keys []Key) *Authenticator {
+	a := &Authenticator{}
+	a.Update(enabled, keys)
+	return a
+}
+
+// Update atomically replaces the enabled flag and the key set
+func (a *Authenticator) Update(enabled bool, keys []Key) {
+	keys = append([]Key(nil), keys...)
+	a.keys.Store(&keys)
+	a.enabled.Store(enabled)
+}
+
+// Enabled reports whether requests must carry a valid key
+func (a *Authenticator) Enabled() bool {
+	return a != nil && a.enabled.Load()
+}
+
+// Authenticate returns the key matching the request credentials, if any
+func (a *Authenticator) Authenticate(r *http.Request) (*Key, bool) {
+	return a.Lookup(Credentials(r.Header.Get("Authorization"), r.Header.Get(APIKeyHeader)))
+}
+
+// Lookup returns the key whose secret is secret, if any
+func (a *Authenticator) Lookup(secret string) (*Key, bool) {
+	if secret == "" {
+		return nil, false
+	}
+
+	sum := sha256.Sum256([]byte(secret))
+	keys := *a.keys.Load()
+	for i := range keys {
+		if subtle.ConstantTimeCompare(sum[:], keys[i].Hash) == 1 {
+			return &keys[i], true
+		}
+	}
+	return nil, false
+}
+
+// Require wraps next so it only runs for requests whose key grants scope.
+// Missing or unknown keys get 401 and keys without the scope get 403.
+func (a *Authenticator) Require(scope Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() {
+			next(w, r)
+			return
+		}
+
+		key, ok := a.Authenticate(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="lunar"`)
+			problem.Write(w, problem.New(problem.CodeUnauthorized, "Missing or invalid API key"))
+			return
+		}
+		if !key.Allows(scope) {
+			problem.Write(w, problem.New(problem.CodeForbidden, fmt.Sprintf("API key %q lacks the %q scope", key.Name, scope)))
+			return
+		}
+
+		next(w, r.WithContext(WithPrincipal(r.Context(), key.Name)))
+	}
+}
+
+// Credentials extracts the API key from the values of the Authorization and
+// X-API-Key headers. A bearer token takes precedence.
+func Credentials(authorization, apiKey string) string {
+	if authorization != "" {
+		scheme, token, ok := strings.Cut(authorization, " ")
+		if ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	return strings.TrimSpace(apiKey)
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a context carrying the name of the authenticated key
+func WithPrincipal(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, contextKey{}, name)
+}
+
+// PrincipalFromContext returns the name of the authenticated key, if any
+func PrincipalFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(contextKey{}).(string)
+	return name
+}
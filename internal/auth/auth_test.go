@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func mustKey(t *testing.T, name, secret string, scopes ...Scope) Key {
+	t.Helper()
+
+	hash, err := ParseHash(HashKey(secret))
+	if err != nil {
+		t.Fatalf("Failed to parse hash: %v", err)
+	}
+	return Key{Name: name, Hash: hash, Scopes: scopes}
+}
+
+func TestRequire(t *testing.T) {
+	a := NewAuthenticator(true, []Key{
+		mustKey(t, "emitter", "ingest-secret", ScopeIngest),
+		mustKey(t, "operator", "admin-secret", ScopeAdmin),
+	})
+
+	var principal string
+	handler := a.Require(ScopeIngest, func(w http.ResponseWriter, r *http.Request) {
+		principal = PrincipalFromContext(r.Context())
+		w.WriteHeader(http.StatusAccepted)
+	})
+	readHandler := a.Require(ScopeRead, func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		header  string
+		value   string
+		want    int
+	}{
+		{"missing key", handler, "", "", http.StatusUnauthorized},
+		{"unknown key", handler, APIKeyHeader, "wrong", http.StatusUnauthorized},
+		{"bearer key", handler, "Authorization", "Bearer ingest-secret", http.StatusAccepted},
+		{"header key", handler, APIKeyHeader, "ingest-secret", http.StatusAccepted},
+		{"missing scope", readHandler, APIKeyHeader, "ingest-secret", http.StatusForbidden},
+		{"admin implies all", readHandler, APIKeyHeader, "admin-secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/messages", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rec := httptest.NewRecorder()
+			tt.handler(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, rec.Code)
+			}
+			if tt.want == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("Expected a WWW-Authenticate header on 401")
+			}
+		})
+	}
+
+	if principal != "emitter" {
+		t.Errorf("Expected the key name in the request context, got %q", principal)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	a := NewAuthenticator(true, []Key{mustKey(t, "old", "old-secret", ScopeRead)})
+	handler := a.Require(ScopeRead, func(w http.ResponseWriter, r *http.Request) {})
+
+	status := func(secret string) int {
+		req := httptest.NewRequest(http.MethodGet, "/rockets", nil)
+		req.Header.Set(APIKeyHeader, secret)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec.Code
+	}
+
+	if got := status("old-secret"); got != http.StatusOK {
+		t.Fatalf("Expected old key to work, got %d", got)
+	}
+
+	a.Update(true, []Key{mustKey(t, "new", "new-secret", ScopeRead)})
+	if got := status("old-secret"); got != http.StatusUnauthorized {
+		t.Errorf("Expected rotated-out key to be rejected, got %d", got)
+	}
+	if got := status("new-secret"); got != http.StatusOK {
+		t.Errorf("Expected new key to work, got %d", got)
+	}
+
+	// Disabling auth opens the routes
+	a.Update(false, nil)
+	if got := status(""); got != http.StatusOK {
+		t.Errorf("Expected open access when disabled, got %d", got)
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	if _, err := ParseHash("md5:abc"); err == nil {
+		t.Errorf("Expected an error for an unsupported scheme")
+	}
+	if _, err := ParseHash("sha256:abc"); err == nil {
+		t.Errorf("Expected an error for a short digest")
+	}
+	if _, err := ParseScope("write"); err == nil {
+		t.Errorf("Expected an error for an unknown scope")
+	}
+}
@@ -0,0 +1,416 @@
+package config
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/graph"
+	"github.com/rah-0/lunar/internal/logging"
+	"github.com/rah-0/lunar/internal/messages"
+	"github.com/rah-0/lunar/internal/ratelimit"
+	"github.com/rah-0/lunar/internal/signing"
+	"github.com/rah-0/lunar/internal/transport"
+)
+
+// Config is the complete service configuration.
+//
+// Each leaf field can be set from a YAML or JSON file, overridden by the
+// environment variable named in its env tag, and finally by the command line
+// flag named in its flag tag. Fields tagged secret:"true" are redacted when the
+// configuration is printed, and fields tagged reload:"true" can change without
+// a restart.
+type Config struct {
+	Server    ServerConfig    `json:"server" yaml:"server"`
+	Log       LogConfig       `json:"log" yaml:"log"`
+	Metrics   MetricsConfig   `json:"metrics" yaml:"metrics"`
+	Auth      AuthConfig      `json:"auth" yaml:"auth"`
+	Signing   SigningConfig   `json:"signing" yaml:"signing"`
+	RateLimit RateLimitConfig `json:"rateLimit" yaml:"rateLimit"`
+	Decoding  DecodingConfig  `json:"decoding" yaml:"decoding"`
+	GraphQL   GraphQLConfig   `json:"graphql" yaml:"graphql"`
+}
+
+// ServerConfig configures the HTTP listener
+type ServerConfig struct {
+	Port            int        `json:"port" yaml:"port" env:"LUNAR_SERVER_PORT" flag:"port" help:"Port to listen on"`
+	ShutdownTimeout Duration   `json:"shutdownTimeout" yaml:"shutdownTimeout" swaggertype:"string" env:"LUNAR_SERVER_SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" help:"Time allowed for graceful shutdown"`
+	TLS             TLSConfig  `json:"tls" yaml:"tls"`
+	GRPC            GRPCConfig `json:"grpc" yaml:"grpc"`
+}
+
+// GRPCConfig configures the gRPC listener, which serves the same rockets as
+// the REST API and uses the TLS settings of the server when TLS is enabled
+type GRPCConfig struct {
+	Enabled bool `json:"enabled" yaml:"enabled" env:"LUNAR_GRPC_ENABLED" flag:"grpc" help:"Serve the gRPC API"`
+	Port    int  `json:"port" yaml:"port" env:"LUNAR_GRPC_PORT" flag:"grpc-port" help:"Port the gRPC API listens on"`
+}
+
+// TLSConfig configures HTTPS and client certificate verification
+type TLSConfig struct {
+	Enabled        bool                   `json:"enabled" yaml:"enabled" env:"LUNAR_TLS_ENABLED" flag:"tls" help:"Serve HTTPS instead of HTTP"`
+	CertFile       string                 `json:"certFile" yaml:"certFile" env:"LUNAR_TLS_CERT_FILE" flag:"tls-cert" help:"PEM certificate chain served to clients"`
+	KeyFile        string                 `json:"keyFile" yaml:"keyFile" env:"LUNAR_TLS_KEY_FILE" flag:"tls-key" help:"PEM private key for the certificate"`
+	ClientCAFile   string                 `json:"clientCAFile" yaml:"clientCAFile" env:"LUNAR_TLS_CLIENT_CA_FILE" flag:"tls-client-ca" help:"PEM bundle of CAs trusted to sign client certificates"`
+	ClientAuth     string                 `json:"clientAuth" yaml:"clientAuth" env:"LUNAR_TLS_CLIENT_AUTH" flag:"tls-client-auth" help:"Client certificate mode (none, optional or require)"`
+	ReloadInterval Duration               `json:"reloadInterval" yaml:"reloadInterval" swaggertype:"string" env:"LUNAR_TLS_RELOAD_INTERVAL" flag:"tls-reload-interval" help:"How often certificate files are checked for changes"`
+	ClientChannels []ClientChannelsConfig `json:"clientChannels" yaml:"clientChannels" reload:"true"`
+}
+
+// ClientChannelsConfig restricts a client certificate subject to a set of channels
+type ClientChannelsConfig struct {
+	Subject  string   `json:"subject" yaml:"subject"`   // Certificate subject common name
+	Channels []string `json:"channels" yaml:"channels"` // "*" allows every channel
+}
+
+// ChannelPolicy converts the allow-lists for the channel policy
+func (c TLSConfig) ChannelPolicy() map[string][]string {
+	allowed := make(map[string][]string, len(c.ClientChannels))
+	for _, entry := range c.ClientChannels {
+		allowed[entry.Subject] = append(allowed[entry.Subject], entry.Channels...)
+	}
+	return allowed
+}
+
+// LogConfig configures structured logging
+type LogConfig struct {
+	Format string `json:"format" yaml:"format" env:"LUNAR_LOG_FORMAT" flag:"log-format" help:"Log output format (text or json)"`
+	Level  string `json:"level" yaml:"level" reload:"true" env:"LUNAR_LOG_LEVEL" flag:"log-level" help:"Minimum log level (debug, info, warn or error)"`
+}
+
+// MetricsConfig configures the /metrics endpoint
+type MetricsConfig struct {
+	Domain DomainMetricsConfig `json:"domain" yaml:"domain"`
+}
+
+// DomainMetricsConfig configures the per-rocket state gauges
+type DomainMetricsConfig struct {
+	Enabled    bool     `json:"enabled" yaml:"enabled" env:"LUNAR_METRICS_DOMAIN_ENABLED" flag:"domain-metrics" help:"Export per-rocket state gauges on /metrics"`
+	MaxRockets int      `json:"maxRockets" yaml:"maxRockets" reload:"true" env:"LUNAR_METRICS_DOMAIN_MAX_ROCKETS" flag:"domain-metrics-max-rockets" help:"Maximum rockets exported as per-rocket series (0 for no cap)"`
+	StaleAfter Duration `json:"staleAfter" yaml:"staleAfter" swaggertype:"string" reload:"true" env:"LUNAR_METRICS_DOMAIN_STALE_AFTER" flag:"domain-metrics-stale-after" help:"Stop exporting rockets not updated for this long (0 to keep all)"`
+}
+
+// AuthConfig configures API key authentication
+type AuthConfig struct {
+	Enabled  bool           `json:"enabled" yaml:"enabled" reload:"true" env:"LUNAR_AUTH_ENABLED" flag:"auth" help:"Require an API key on protected routes"`
+	KeysFile string         `json:"keysFile" yaml:"keysFile" reload:"true" env:"LUNAR_AUTH_KEYS_FILE" flag:"auth-keys-file" help:"YAML or JSON file with additional API keys"`
+	Keys     []APIKeyConfig `json:"keys" yaml:"keys" reload:"true"`
+}
+
+// APIKeyConfig describes one API key. Only the hash of the key is stored,
+// as "sha256:<hex digest>" of the plaintext key.
+type APIKeyConfig struct {
+	Name   string   `json:"name" yaml:"name"`
+	Hash   string   `json:"hash" yaml:"hash" secret:"true"`
+	Scopes []string `json:"scopes" yaml:"scopes"`
+}
+
+// APIKeys converts the configured keys for the authenticator.
+// It assumes the configuration has been validated.
+func (c AuthConfig) APIKeys() []auth.Key {
+	keys := make([]auth.Key, 0, len(c.Keys))
+	for _, k := range c.Keys {
+		hash, _ := auth.ParseHash(k.Hash)
+		scopes := make([]auth.Scope, 0, len(k.Scopes))
+		for _, name := range k.Scopes {
+			scope, _ := auth.ParseScope(name)
+			scopes = append(scopes, scope)
+		}
+		keys = append(keys, auth.Key{Name: k.Name, Hash: hash, Scopes: scopes})
+	}
+	return keys
+}
+
+// SigningConfig configures HMAC signature verification of message bodies
+type SigningConfig struct {
+	Mode      string          `json:"mode" yaml:"mode" reload:"true" env:"LUNAR_SIGNING_MODE" flag:"signing-mode" help:"Signature verification mode (off, log or enforce)"`
+	Tolerance Duration        `json:"tolerance" yaml:"tolerance" swaggertype:"string" reload:"true" env:"LUNAR_SIGNING_TOLERANCE" flag:"signing-tolerance" help:"Maximum age and clock skew of a signature timestamp"`
+	Emitters  []EmitterConfig `json:"emitters" yaml:"emitters" reload:"true"`
+}
+
+// EmitterConfig holds the shared secret an emitter signs messages with
+type EmitterConfig struct {
+	Name   string `json:"name" yaml:"name"`
+	Secret string `json:"secret" yaml:"secret" secret:"true"`
+}
+
+// Settings converts the configuration for the verifier.
+// It assumes the configuration has been validated.
+func (c SigningConfig) Settings() signing.Settings {
+	mode, _ := signing.ParseMode(c.Mode)
+	secrets := make(map[string][]byte, len(c.Emitters))
+	for _, e := range c.Emitters {
+		secrets[e.Name] = []byte(e.Secret)
+	}
+	return signing.Settings{Mode: mode, Tolerance: c.Tolerance.Std(), Secrets: secrets}
+}
+
+// RateLimitConfig configures token bucket limits on message ingestion.
+// A rate of 0 disables the corresponding limit.
+type RateLimitConfig struct {
+	Client      ClientLimitConfig  `json:"client" yaml:"client"`
+	Channel     ChannelLimitConfig `json:"channel" yaml:"channel"`
+	IdleTimeout Duration           `json:"idleTimeout" yaml:"idleTimeout" swaggertype:"string" reload:"true" env:"LUNAR_RATE_LIMIT_IDLE_TIMEOUT" flag:"rate-limit-idle-timeout" help:"Forget limiter state of keys idle for this long"`
+}
+
+// ClientLimitConfig limits requests per API key, or per IP without one
+type ClientLimitConfig struct {
+	Rate  float64 `json:"rate" yaml:"rate" reload:"true" env:"LUNAR_RATE_LIMIT_CLIENT_RATE" flag:"rate-limit-client-rate" help:"Messages per second allowed per client (0 for no limit)"`
+	Burst int     `json:"burst" yaml:"burst" reload:"true" env:"LUNAR_RATE_LIMIT_CLIENT_BURST" flag:"rate-limit-client-burst" help:"Messages a client may send at once above its rate"`
+}
+
+// ChannelLimitConfig limits messages per rocket channel
+type ChannelLimitConfig struct {
+	Rate  float64 `json:"rate" yaml:"rate" reload:"true" env:"LUNAR_RATE_LIMIT_CHANNEL_RATE" flag:"rate-limit-channel-rate" help:"Messages per second allowed per channel (0 for no limit)"`
+	Burst int     `json:"burst" yaml:"burst" reload:"true" env:"LUNAR_RATE_LIMIT_CHANNEL_BURST" flag:"rate-limit-channel-burst" help:"Messages a channel may receive at once above its rate"`
+}
+
+// ClientLimit returns the per-client limit
+func (c RateLimitConfig) ClientLimit() ratelimit.Limit {
+	return ratelimit.Limit{Rate: c.Client.Rate, Burst: c.Client.Burst}
+}
+
+// ChannelLimit returns the per-channel limit
+func (c RateLimitConfig) ChannelLimit() ratelimit.Limit {
+	return ratelimit.Limit{Rate: c.Channel.Rate, Burst: c.Channel.Burst}
+}
+
+// DecodingConfig configures how incoming messages are decoded
+type DecodingConfig struct {
+	Mode string `json:"mode" yaml:"mode" reload:"true" env:"LUNAR_DECODING_MODE" flag:"decoding-mode" help:"Message decoding mode (strict or lenient)"`
+}
+
+// DecodingMode returns the decoding mode.
+// It assumes the configuration has been validated.
+func (c DecodingConfig) DecodingMode() messages.Mode {
+	mode, _ := messages.ParseMode(c.Mode)
+	return mode
+}
+
+// GraphQLConfig bounds the cost of queries to /graphql
+type GraphQLConfig struct {
+	MaxDepth      int `json:"maxDepth" yaml:"maxDepth" reload:"true" env:"LUNAR_GRAPHQL_MAX_DEPTH" flag:"graphql-max-depth" help:"Deepest field nesting allowed in a GraphQL query"`
+	MaxComplexity int `json:"maxComplexity" yaml:"maxComplexity" reload:"true" env:"LUNAR_GRAPHQL_MAX_COMPLEXITY" flag:"graphql-max-complexity" help:"Highest estimated number of fields a GraphQL query may resolve"`
+}
+
+// Limits converts the settings for the GraphQL handler
+func (c GraphQLConfig) Limits() graph.Limits {
+	return graph.Limits{MaxDepth: c.MaxDepth, MaxComplexity: c.MaxComplexity}
+}
+
+// Default returns the configuration used when nothing is overridden
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:            8088,
+			ShutdownTimeout: Duration(5 * time.Second),
+			TLS: TLSConfig{
+				ClientAuth:     transport.ClientAuthNone,
+				ReloadInterval: Duration(time.Minute),
+			},
+			GRPC: GRPCConfig{
+				Port: 9090,
+			},
+		},
+		Log: LogConfig{
+			Format: logging.FormatText,
+			Level:  "info",
+		},
+		Metrics: MetricsConfig{
+			Domain: DomainMetricsConfig{
+				MaxRockets: 1000,
+			},
+		},
+		Signing: SigningConfig{
+			Mode:      string(signing.ModeOff),
+			Tolerance: Duration(5 * time.Minute),
+		},
+		RateLimit: RateLimitConfig{
+			Client:      ClientLimitConfig{Burst: 1},
+			Channel:     ChannelLimitConfig{Burst: 1},
+			IdleTimeout: Duration(10 * time.Minute),
+		},
+		Decoding: DecodingConfig{
+			Mode: string(messages.ModeStrict),
+		},
+		GraphQL: GraphQLConfig{
+			MaxDepth:      8,
+			MaxComplexity: 5000,
+		},
+	}
+}
+
+// Validate checks the configuration and reports every invalid field
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		fail("server.port", "must be between 1 and 65535, got %d", c.Server.Port)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		fail("server.shutdownTimeout", "must be positive")
+	}
+	if c.Server.GRPC.Enabled {
+		if c.Server.GRPC.Port < 1 || c.Server.GRPC.Port > 65535 {
+			fail("server.grpc.port", "must be between 1 and 65535, got %d", c.Server.GRPC.Port)
+		} else if c.Server.GRPC.Port == c.Server.Port {
+			fail("server.grpc.port", "must differ from server.port")
+		}
+	}
+
+	tlsConfig := c.Server.TLS
+	if tlsConfig.Enabled {
+		if tlsConfig.CertFile == "" {
+			fail("server.tls.certFile", "required when TLS is enabled")
+		}
+		if tlsConfig.KeyFile == "" {
+			fail("server.tls.keyFile", "required when TLS is enabled")
+		}
+	}
+	if clientAuth, err := transport.ParseClientAuth(tlsConfig.ClientAuth); err != nil {
+		fail("server.tls.clientAuth", "%v", err)
+	} else if clientAuth != tls.NoClientCert {
+		if !tlsConfig.Enabled {
+			fail("server.tls.clientAuth", "requires TLS to be enabled")
+		}
+		if tlsConfig.ClientCAFile == "" {
+			fail("server.tls.clientCAFile", "required when client certificates are verified")
+		}
+	}
+	if tlsConfig.ReloadInterval <= 0 {
+		fail("server.tls.reloadInterval", "must be positive")
+	}
+	for i, entry := range tlsConfig.ClientChannels {
+		path := fmt.Sprintf("server.tls.clientChannels[%d]", i)
+		if entry.Subject == "" {
+			fail(path+".subject", "must not be empty")
+		}
+		if len(entry.Channels) == 0 {
+			fail(path+".channels", "at least one channel is required")
+		}
+	}
+
+	switch strings.ToLower(c.Log.Format) {
+	case logging.FormatText, logging.FormatJSON:
+	default:
+		fail("log.format", "must be %q or %q, got %q", logging.FormatText, logging.FormatJSON, c.Log.Format)
+	}
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		fail("log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	}
+
+	if c.Metrics.Domain.MaxRockets < 0 {
+		fail("metrics.domain.maxRockets", "must not be negative")
+	}
+	if c.Metrics.Domain.StaleAfter < 0 {
+		fail("metrics.domain.staleAfter", "must not be negative")
+	}
+
+	if c.Auth.Enabled && len(c.Auth.Keys) == 0 {
+		fail("auth.keys", "at least one key is required when auth is enabled")
+	}
+	names := make(map[string]bool)
+	for i, key := range c.Auth.Keys {
+		path := fmt.Sprintf("auth.keys[%d]", i)
+		if key.Name == "" {
+			fail(path+".name", "must not be empty")
+		} else if names[key.Name] {
+			fail(path+".name", "duplicate key name %q", key.Name)
+		}
+		names[key.Name] = true
+		if _, err := auth.ParseHash(key.Hash); err != nil {
+			fail(path+".hash", "%v", err)
+		}
+		if len(key.Scopes) == 0 {
+			fail(path+".scopes", "at least one scope is required")
+		}
+		for _, scope := range key.Scopes {
+			if _, err := auth.ParseScope(scope); err != nil {
+				fail(path+".scopes", "%v", err)
+			}
+		}
+	}
+
+	mode, err := signing.ParseMode(c.Signing.Mode)
+	if err != nil {
+		fail("signing.mode", "%v", err)
+	} else if mode != signing.ModeOff && len(c.Signing.Emitters) == 0 {
+		fail("signing.emitters", "at least one emitter is required when signatures are verified")
+	}
+	if c.Signing.Tolerance <= 0 {
+		fail("signing.tolerance", "must be positive")
+	}
+	emitters := make(map[string]bool)
+	for i, emitter := range c.Signing.Emitters {
+		path := fmt.Sprintf("signing.emitters[%d]", i)
+		if emitter.Name == "" {
+			fail(path+".name", "must not be empty")
+		} else if emitters[emitter.Name] {
+			fail(path+".name", "duplicate emitter name %q", emitter.Name)
+		}
+		emitters[emitter.Name] = true
+		if emitter.Secret == "" {
+			fail(path+".secret", "must not be empty")
+		}
+	}
+
+	for _, limit := range []struct {
+		path  string
+		limit ratelimit.Limit
+	}{
+		{"rateLimit.client", c.RateLimit.ClientLimit()},
+		{"rateLimit.channel", c.RateLimit.ChannelLimit()},
+	} {
+		if limit.limit.Rate < 0 {
+			fail(limit.path+".rate", "must not be negative")
+		}
+		if limit.limit.Burst < 1 {
+			fail(limit.path+".burst", "must be at least 1")
+		}
+	}
+	if c.RateLimit.IdleTimeout <= 0 {
+		fail("rateLimit.idleTimeout", "must be positive")
+	}
+
+	if _, err := messages.ParseMode(c.Decoding.Mode); err != nil {
+		fail("decoding.mode", "%v", err)
+	}
+
+	if c.GraphQL.MaxDepth < 1 {
+		fail("graphql.maxDepth", "must be at least 1")
+	}
+	if c.GraphQL.MaxComplexity < 1 {
+		fail("graphql.maxComplexity", "must be at least 1")
+	}
+
+	return errors.Join(errs...)
+}
+
+// Duration is a time.Duration that reads and writes as a string such as "5s"
+type Duration time.Duration
+
+// Std returns the value as a time.Duration
+func (d Duration) Std() time.Duration {
+	return time.Duration(d)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (d *Duration) UnmarshalText(text []byte) error {
+	parsed, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
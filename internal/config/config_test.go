@@ -0,0 +1,321 @@
+package config
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/messages"
+	"github.com/rah-0/lunar/internal/signing"
+)
+
+// envMap returns a getenv function backed by a map
+func envMap(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := Load(nil, envMap(nil))
+	if err != nil {
+		t.Fatalf("Expected defaults to load, got %v", err)
+	}
+	if cfg.Server.Port != 8088 || cfg.Log.Level != "info" || cfg.Server.ShutdownTimeout.Std() != 5*time.Second {
+		t.Errorf("Unexpected defaults: %+v", cfg)
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "lunar.yaml", `
+server:
+  port: 9000
+  shutdownTimeout: 10s
+log:
+  level: warn
+  format: json
+metrics:
+  domain:
+    enabled: true
+    maxRockets: 50
+`)
+
+	env := envMap(map[string]string{
+		"LUNAR_LOG_LEVEL":   "debug",
+		"LUNAR_SERVER_PORT": "9100",
+	})
+	cfg, err := Load([]string{"-config", path, "-port", "9200"}, env)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Flag beats environment beats file
+	if cfg.Server.Port != 9200 {
+		t.Errorf("Expected flag to win for port, got %d", cfg.Server.Port)
+	}
+	// Environment beats file
+	if cfg.Log.Level != "debug" {
+		t.Errorf("Expected env to win for log level, got %q", cfg.Log.Level)
+	}
+	// File beats defaults
+	if cfg.Log.Format != "json" || !cfg.Metrics.Domain.Enabled || cfg.Metrics.Domain.MaxRockets != 50 {
+		t.Errorf("Expected file values to apply, got %+v", cfg)
+	}
+	if cfg.Server.ShutdownTimeout.Std() != 10*time.Second {
+		t.Errorf("Expected shutdown timeout from file, got %v", cfg.Server.ShutdownTimeout.Std())
+	}
+}
+
+func TestLoadJSONFileFromEnv(t *testing.T) {
+	path := writeFile(t, "lunar.json", `{"server": {"port": 9300}}`)
+
+	cfg, err := Load(nil, envMap(map[string]string{ConfigFileEnv: path}))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Server.Port != 9300 {
+		t.Errorf("Expected port from JSON file, got %d", cfg.Server.Port)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	unknown := writeFile(t, "lunar.yaml", "server:\n  prot: 9000\n")
+	if _, err := Load([]string{"-config", unknown}, envMap(nil)); err == nil {
+		t.Errorf("Expected an error for an unknown key")
+	}
+
+	if _, err := Load(nil, envMap(map[string]string{"LUNAR_SERVER_PORT": "abc"})); err == nil ||
+		!strings.Contains(err.Error(), "LUNAR_SERVER_PORT") {
+		t.Errorf("Expected the env variable to be named in the error, got %v", err)
+	}
+
+	// Every invalid field is reported
+	_, err := Load([]string{"-port", "70000", "-log-format", "xml"}, envMap(nil))
+	if err == nil {
+		t.Fatalf("Expected a validation error")
+	}
+	for _, path := range []string{"server.port", "log.format"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("Expected %s in validation error, got %v", path, err)
+		}
+	}
+}
+
+func TestStoreReload(t *testing.T) {
+	path := writeFile(t, "lunar.yaml", "log:\n  level: info\n")
+	load := func() (*Config, error) { return Load([]string{"-config", path}, envMap(nil)) }
+
+	initial, err := load()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	store := NewStore(initial, load, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var notified *Config
+	store.OnReload(func(c *Config) { notified = c })
+
+	// Reloadable and restart-only changes
+	if err := os.WriteFile(path, []byte("log:\n  level: warn\n  format: json\n"), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	changes, err := store.Reload()
+	if err != nil {
+		t.Fatalf("Expected reload to succeed, got %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changes, got %+v", changes)
+	}
+	for _, change := range changes {
+		if applied := change.Path == "log.level"; change.Applied != applied {
+			t.Errorf("Unexpected applied flag for %s: %v", change.Path, change.Applied)
+		}
+	}
+	if store.Current().Log.Level != "warn" || store.Current().Log.Format != "text" {
+		t.Errorf("Expected only the level to change, got %+v", store.Current().Log)
+	}
+	if notified != store.Current() {
+		t.Errorf("Expected listeners to receive the new configuration")
+	}
+
+	// Invalid configurations are rejected and the current one kept
+	if err := os.WriteFile(path, []byte("log:\n  level: loud\n"), 0o600); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+	if _, err := store.Reload(); err == nil {
+		t.Errorf("Expected invalid configuration to be rejected")
+	}
+	if store.Current().Log.Level != "warn" {
+		t.Errorf("Expected the previous configuration to be kept, got %q", store.Current().Log.Level)
+	}
+}
+
+func TestAuthKeys(t *testing.T) {
+	hash := auth.HashKey("s3cret")
+	keysFile := writeFile(t, "keys.yaml", "keys:\n  - name: emitter\n    hash: "+hash+"\n    scopes: [ingest]\n")
+	path := writeFile(t, "lunar.yaml", "auth:\n  enabled: true\n  keys:\n    - name: operator\n      hash: "+hash+"\n      scopes: [admin]\n")
+
+	cfg, err := Load([]string{"-config", path, "-auth-keys-file", keysFile}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	keys := cfg.Auth.APIKeys()
+	if len(keys) != 2 || keys[0].Name != "operator" || keys[1].Name != "emitter" {
+		t.Fatalf("Expected keys from config and keys file, got %+v", keys)
+	}
+	if !keys[1].Allows(auth.ScopeIngest) || keys[1].Allows(auth.ScopeRead) {
+		t.Errorf("Unexpected scopes for emitter key: %v", keys[1].Scopes)
+	}
+
+	// Hashes never appear in printed configuration
+	redacted := cfg.Redacted()
+	for _, key := range redacted.Auth.Keys {
+		if key.Hash != redactedValue {
+			t.Errorf("Expected hash to be redacted, got %q", key.Hash)
+		}
+	}
+	if cfg.Auth.Keys[0].Hash != hash {
+		t.Errorf("Redacting must not modify the original configuration")
+	}
+
+	// Invalid keys are reported
+	bad := writeFile(t, "bad.yaml", "auth:\n  enabled: true\n  keys:\n    - name: x\n      hash: plain\n      scopes: [write]\n")
+	_, err = Load([]string{"-config", bad}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "auth.keys[0].hash") || !strings.Contains(err.Error(), "auth.keys[0].scopes") {
+		t.Errorf("Expected hash and scope errors, got %v", err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	path := writeFile(t, "lunar.yaml", `
+server:
+  tls:
+    enabled: true
+    certFile: server.pem
+    keyFile: server.key
+    clientCAFile: ca.pem
+    clientAuth: require
+    clientChannels:
+      - subject: emitter-1
+        channels: [channel-a, channel-b]
+`)
+	cfg, err := Load([]string{"-config", path}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if policy := cfg.Server.TLS.ChannelPolicy(); len(policy["emitter-1"]) != 2 {
+		t.Errorf("Expected channels for emitter-1, got %v", policy)
+	}
+
+	// Client certificates need TLS and a CA bundle
+	_, err = Load([]string{"-tls-client-auth", "require"}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "server.tls.clientAuth") || !strings.Contains(err.Error(), "server.tls.clientCAFile") {
+		t.Errorf("Expected client auth errors, got %v", err)
+	}
+	_, err = Load([]string{"-tls"}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "server.tls.certFile") || !strings.Contains(err.Error(), "server.tls.keyFile") {
+		t.Errorf("Expected missing certificate errors, got %v", err)
+	}
+}
+
+func TestSigningConfig(t *testing.T) {
+	path := writeFile(t, "lunar.yaml", "signing:\n  mode: enforce\n  emitters:\n    - name: eu-1\n      secret: s3cret\n")
+	cfg, err := Load([]string{"-config", path}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	settings := cfg.Signing.Settings()
+	if settings.Mode != signing.ModeEnforce || string(settings.Secrets["eu-1"]) != "s3cret" || settings.Tolerance != 5*time.Minute {
+		t.Errorf("Unexpected signing settings: %+v", settings)
+	}
+	if cfg.Redacted().Signing.Emitters[0].Secret != redactedValue {
+		t.Errorf("Expected emitter secret to be redacted")
+	}
+
+	// Verification needs at least one emitter
+	_, err = Load([]string{"-signing-mode", "log"}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "signing.emitters") {
+		t.Errorf("Expected an emitters error, got %v", err)
+	}
+	_, err = Load([]string{"-signing-mode", "strict"}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "signing.mode") {
+		t.Errorf("Expected a mode error, got %v", err)
+	}
+}
+
+func TestRateLimitConfig(t *testing.T) {
+	cfg, err := Load([]string{"-rate-limit-channel-rate", "2.5", "-rate-limit-channel-burst", "5"}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if limit := cfg.RateLimit.ChannelLimit(); limit.Rate != 2.5 || limit.Burst != 5 {
+		t.Errorf("Unexpected channel limit: %+v", limit)
+	}
+	if !cfg.RateLimit.ClientLimit().Unlimited() {
+		t.Errorf("Expected no client limit by default")
+	}
+
+	_, err = Load([]string{"-rate-limit-client-rate", "-1", "-rate-limit-client-burst", "0"}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "rateLimit.client.rate") || !strings.Contains(err.Error(), "rateLimit.client.burst") {
+		t.Errorf("Expected rate and burst errors, got %v", err)
+	}
+}
+
+func TestDecodingConfig(t *testing.T) {
+	cfg, err := Load(nil, envMap(map[string]string{"LUNAR_DECODING_MODE": "Lenient"}))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if mode := cfg.Decoding.DecodingMode(); mode != messages.ModeLenient {
+		t.Errorf("Expected lenient mode, got %q", mode)
+	}
+
+	_, err = Load([]string{"-decoding-mode", "loose"}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "decoding.mode") {
+		t.Errorf("Expected a mode error, got %v", err)
+	}
+}
+
+func TestGRPCConfig(t *testing.T) {
+	cfg, err := Load([]string{"-grpc", "-grpc-port", "9191"}, envMap(nil))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if !cfg.Server.GRPC.Enabled || cfg.Server.GRPC.Port != 9191 {
+		t.Errorf("Expected gRPC on port 9191, got %+v", cfg.Server.GRPC)
+	}
+
+	_, err = Load(nil, envMap(map[string]string{"LUNAR_GRPC_ENABLED": "true", "LUNAR_GRPC_PORT": "8088"}))
+	if err == nil || !strings.Contains(err.Error(), "server.grpc.port") {
+		t.Errorf("Expected a port clash error, got %v", err)
+	}
+}
+
+func TestGraphQLConfig(t *testing.T) {
+	cfg, err := Load([]string{"-graphql-max-depth", "4"}, envMap(map[string]string{"LUNAR_GRAPHQL_MAX_COMPLEXITY": "200"}))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if limits := cfg.GraphQL.Limits(); limits.MaxDepth != 4 || limits.MaxComplexity != 200 {
+		t.Errorf("Unexpected limits: %+v", limits)
+	}
+
+	_, err = Load([]string{"-graphql-max-depth", "0"}, envMap(nil))
+	if err == nil || !strings.Contains(err.Error(), "graphql.maxDepth") {
+		t.Errorf("Expected a depth error, got %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv names the environment variable that points at a config file
+const ConfigFileEnv = "LUNAR_CONFIG"
+
+// redactedValue replaces secret values when the configuration is printed
+const redactedValue = "[REDACTED]"
+
+// Load builds the configuration from defaults, an optional YAML or JSON file,
+// LUNAR_* environment variables and command line flags, in increasing order of
+// precedence, and validates the result.
+func Load(args []string, getenv func(string) string) (*Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("lunar", flag.ContinueOnError)
+	configPath := fs.String("config", getenv(ConfigFileEnv), "Path to a YAML or JSON configuration file")
+
+	// Flags only record their raw value here; they are applied last so
+	// that they override both the file and the environment
+	var flagged []*flagValue
+	for _, f := range fields(cfg) {
+		if f.flag == "" {
+			continue
+		}
+		fv := &flagValue{field: f}
+		fs.Var(fv, f.flag, f.help)
+		flagged = append(flagged, fv)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *configPath != "" {
+		if err := loadFile(cfg, *configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range fields(cfg) {
+		if f.env == "" {
+			continue
+		}
+		if raw, ok := lookup(getenv, f.env); ok {
+			if err := setFromString(f.value, raw); err != nil {
+				return nil, fmt.Errorf("%s (from %s): %w", f.path, f.env, err)
+			}
+		}
+	}
+
+	for _, fv := range flagged {
+		if !fv.set {
+			continue
+		}
+		if err := setFromString(fv.field.value, fv.raw); err != nil {
+			return nil, fmt.Errorf("%s (from -%s): %w", fv.field.path, fv.field.flag, err)
+		}
+	}
+
+	if cfg.Auth.KeysFile != "" {
+		if err := loadKeysFile(cfg, cfg.Auth.KeysFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// lookup treats an empty environment variable as unset
+func lookup(getenv func(string) string, name string) (string, bool) {
+	value := getenv(name)
+	return value, value != ""
+}
+
+// loadFile decodes a YAML or JSON file over cfg, rejecting unknown keys
+func loadFile(cfg *Config, path string) error {
+	if err := decodeFile(path, cfg); err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	return nil
+}
+
+// loadKeysFile appends the API keys listed in a separate YAML or JSON file
+func loadKeysFile(cfg *Config, path string) error {
+	var file struct {
+		Keys []APIKeyConfig `json:"keys" yaml:"keys"`
+	}
+	if err := decodeFile(path, &file); err != nil {
+		return fmt.Errorf("auth keys file: %w", err)
+	}
+	cfg.Auth.Keys = append(cfg.Auth.Keys, file.Keys...)
+	return nil
+}
+
+// decodeFile decodes a YAML or JSON file, chosen by extension, into v
+func decodeFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(v); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		// An empty document decodes as io.EOF and leaves v untouched
+		if err := decoder.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("%s: unsupported extension (use .yaml, .yml or .json)", path)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secret values hidden
+func (c *Config) Redacted() *Config {
+	cp := c.clone()
+	redact(reflect.ValueOf(cp).Elem())
+	return cp
+}
+
+// redact hides every field tagged secret:"true" in a struct, including
+// fields of structs nested in slices
+func redact(v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		fv := v.Field(i)
+		switch {
+		case t.Field(i).Tag.Get("secret") == "true":
+			redactValue(fv)
+		case fv.Kind() == reflect.Struct:
+			redact(fv)
+		case fv.Kind() == reflect.Slice && fv.Type().Elem().Kind() == reflect.Struct:
+			for j := 0; j < fv.Len(); j++ {
+				redact(fv.Index(j))
+			}
+		}
+	}
+}
+
+// redactValue replaces a non-empty string, or each string of a list
+func redactValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.String:
+		if v.String() != "" {
+			v.SetString(redactedValue)
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		redacted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			redacted.Index(i).SetString(redactedValue)
+		}
+		v.Set(redacted)
+	}
+}
+
+// clone deep-copies the configuration through its JSON form
+func (c *Config) clone() *Config {
+	data, _ := json.Marshal(c)
+	cp := &Config{}
+	_ = json.Unmarshal(data, cp)
+	return cp
+}
+
+// field describes one configurable leaf of Config
+type field struct {
+	path   string // Dotted path using the JSON names, e.g. "server.port"
+	env    string
+	flag   string
+	help   string
+	reload bool // Whether the field may change on a configuration reload
+	value  reflect.Value
+}
+
+// fields lists the leaf fields of cfg in declaration order
+func fields(cfg *Config) []field {
+	var out []field
+	walk(reflect.ValueOf(cfg).Elem(), "", &out)
+	return out
+}
+
+func walk(v reflect.Value, prefix string, out *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && sf.Tag.Get("env") == "" && sf.Tag.Get("flag") == "" {
+			walk(fv, path, out)
+			continue
+		}
+
+		*out = append(*out, field{
+			path:   path,
+			env:    sf.Tag.Get("env"),
+			flag:   sf.Tag.Get("flag"),
+			help:   sf.Tag.Get("help"),
+			reload: sf.Tag.Get("reload") == "true",
+			value:  fv,
+		})
+	}
+}
+
+// setFromString parses raw into v according to v's type
+func setFromString(v reflect.Value, raw string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(raw))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		v.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported list type %s", v.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items).Convert(v.Type()))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// flagValue is a flag.Value that records the raw value for a config field
+type flagValue struct {
+	field field
+	raw   string
+	set   bool
+}
+
+func (f *flagValue) String() string {
+	// Zero values print as empty so flag usage omits their default
+	if f == nil || !f.field.value.IsValid() || f.field.value.IsZero() {
+		return ""
+	}
+	return format(f.field.value)
+}
+
+func (f *flagValue) Set(raw string) error {
+	// Validate early so flag parsing reports a helpful error
+	probe := reflect.New(f.field.value.Type()).Elem()
+	if err := setFromString(probe, raw); err != nil {
+		return err
+	}
+	f.raw = raw
+	f.set = true
+	return nil
+}
+
+// IsBoolFlag lets boolean fields be set with a bare -flag
+func (f *flagValue) IsBoolFlag() bool {
+	return f.field.value.Kind() == reflect.Bool
+}
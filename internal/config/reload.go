@@ -0,0 +1,125 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sync"
+	"sync/atomic"
+)
+
+// Change describes one field that differs between two configurations
+type Change struct {
+	Path    string `json:"path"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Applied bool   `json:"applied"` // False when the field needs a restart to take effect
+}
+
+// Store holds the configuration in effect and swaps it atomically on reload.
+// Only fields tagged reload:"true" change on reload; other differences are
+// reported as requiring a restart and keep their current value.
+type Store struct {
+	current atomic.Pointer[Config]
+	load    func() (*Config, error)
+	logger  *slog.Logger
+
+	mu        sync.Mutex // Serializes reloads and subscriber registration
+	listeners []func(*Config)
+}
+
+// NewStore creates a store holding initial; load produces the next configuration on reload
+func NewStore(initial *Config, load func() (*Config, error), logger *slog.Logger) *Store {
+	s := &Store{load: load, logger: logger}
+	s.current.Store(initial)
+	return s
+}
+
+// Current returns the configuration in effect. The result must not be modified.
+func (s *Store) Current() *Config {
+	return s.current.Load()
+}
+
+// OnReload registers fn to be called with the new configuration after each successful reload
+func (s *Store) OnReload(fn func(*Config)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// Reload loads and validates a new configuration and applies its reloadable
+// fields. An invalid configuration is rejected and the current one is kept.
+func (s *Store) Reload() ([]Change, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	next, err := s.load()
+	if err != nil {
+		s.logger.Error("Configuration reload rejected", "error", err)
+		return nil, err
+	}
+
+	current := s.Current()
+	merged := current.clone()
+	changes := diff(current, next)
+
+	// Copy only the reloadable fields over the current configuration
+	mergedFields := fields(merged)
+	nextFields := fields(next)
+	for i := range changes {
+		for j, f := range nextFields {
+			if f.path != changes[i].Path {
+				continue
+			}
+			if f.reload {
+				mergedFields[j].value.Set(f.value)
+				changes[i].Applied = true
+			}
+		}
+	}
+
+	s.current.Store(merged)
+	for _, change := range changes {
+		if change.Applied {
+			s.logger.Info("Configuration changed", "path", change.Path, "old", change.Old, "new", change.New)
+		} else {
+			s.logger.Warn("Configuration change requires a restart", "path", change.Path, "old", change.Old, "new", change.New)
+		}
+	}
+	if len(changes) == 0 {
+		s.logger.Info("Configuration reloaded without changes")
+	}
+
+	for _, fn := range s.listeners {
+		fn(merged)
+	}
+	return changes, nil
+}
+
+// diff lists the leaf fields whose values differ, formatted with secrets redacted
+func diff(old, next *Config) []Change {
+	var changes []Change
+	oldFields, nextFields := fields(old), fields(next)
+	oldRedacted, nextRedacted := fields(old.Redacted()), fields(next.Redacted())
+	for i, f := range oldFields {
+		if reflect.DeepEqual(f.value.Interface(), nextFields[i].value.Interface()) {
+			continue
+		}
+		changes = append(changes, Change{
+			Path: f.path,
+			Old:  format(oldRedacted[i].value),
+			New:  format(nextRedacted[i].value),
+		})
+	}
+	return changes
+}
+
+// format renders a field value for diffs
+func format(v reflect.Value) string {
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, _ := m.MarshalText()
+		return string(text)
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,192 @@
+// Package deadletter keeps the messages that ingestion could not process, so
+// that operators can inspect them and resubmit them once fixed
+package deadletter
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCapacity is the number of letters a store keeps by default
+const DefaultCapacity = 1000
+
+// Stages at which a message can fail
+const (
+	StageDecode   = "decode"   // The body is not a JSON envelope
+	StageValidate = "validate" // The envelope or its message failed validation
+	StageApply    = "apply"    // The repository rejected the update
+)
+
+// Letter is a message that could not be processed, with the raw body as
+// received and why it failed
+type Letter struct {
+	ID            string    `json:"id"`
+	Time          time.Time `json:"time"`                                // Time of the latest failure
+	Client        string    `json:"client"`                              // API key name as key:<name>, client IP as ip:<address>, or buffer when rejected after buffering
+	Stage         string    `json:"stage" enums:"decode,validate,apply"` // Stage at which the message failed
+	Reason        string    `json:"reason"`                              // Why the message failed
+	Channel       string    `json:"channel,omitempty"`                   // Empty when the body could not be decoded
+	MessageNumber int       `json:"messageNumber,omitempty"`             // Zero when the body could not be decoded
+	MessageType   string    `json:"messageType,omitempty"`               // Empty when the body could not be decoded
+	Body          string    `json:"body"`                                // Raw body of the latest attempt
+	Attempts      int       `json:"attempts" example:"1"`                // 1, plus every resubmission that failed again
+}
+
+// Filter selects letters; empty fields match every letter and text fields
+// ignore case
+type Filter struct {
+	Stage       string
+	Channel     string
+	MessageType string
+	Client      string
+	Limit       int // Maximum number of letters; every letter when below 1
+}
+
+// Matches reports whether the letter passes every criterion of the filter
+func (f Filter) Matches(l Letter) bool {
+	return matches(f.Stage, l.Stage) &&
+		matches(f.Channel, l.Channel) &&
+		matches(f.MessageType, l.MessageType) &&
+		matches(f.Client, l.Client)
+}
+
+func matches(want, got string) bool {
+	return want == "" || strings.EqualFold(want, got)
+}
+
+// Store keeps the latest letters in memory. When it is full, the oldest
+// letter makes room for the new one.
+type Store struct {
+	now func() time.Time
+
+	mu       sync.Mutex
+	letters  []Letter // Oldest first
+	lastID   uint64
+	capacity int
+}
+
+// NewStore creates a store keeping the latest capacity letters
+func NewStore(capacity int) *Store {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+	return &Store{now: time.Now, capacity: capacity}
+}
+
+// Add keeps a new letter and returns it with its ID and time. A nil store
+// keeps nothing.
+func (s *Store) Add(letter Letter) Letter {
+	if s == nil {
+		return letter
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	letter.ID = strconv.FormatUint(s.lastID, 10)
+	letter.Time = s.now().UTC()
+	letter.Attempts = 1
+	if len(s.letters) == s.capacity {
+		s.letters = s.letters[1:]
+	}
+	s.letters = append(s.letters, letter)
+	return letter
+}
+
+// Fail records that a resubmission of letter id failed again as failed.
+// The letter keeps its ID and client and counts the attempt. It reports
+// false when the letter is gone.
+func (s *Store) Fail(id string, failed Letter) (Letter, bool) {
+	if s == nil {
+		return Letter{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return Letter{}, false
+	}
+	letter := &s.letters[i]
+	letter.Time = s.now().UTC()
+	letter.Stage = failed.Stage
+	letter.Reason = failed.Reason
+	letter.Channel = failed.Channel
+	letter.MessageNumber = failed.MessageNumber
+	letter.MessageType = failed.MessageType
+	letter.Body = failed.Body
+	letter.Attempts++
+	return *letter, true
+}
+
+// Get returns the letter with the given ID
+func (s *Store) Get(id string) (Letter, bool) {
+	if s == nil {
+		return Letter{}, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if i := s.index(id); i >= 0 {
+		return s.letters[i], true
+	}
+	return Letter{}, false
+}
+
+// Delete removes the letter with the given ID and reports whether it existed
+func (s *Store) Delete(id string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.index(id)
+	if i < 0 {
+		return false
+	}
+	s.letters = append(s.letters[:i], s.letters[i+1:]...)
+	return true
+}
+
+// List returns the letters matching filter, newest first
+func (s *Store) List(filter Filter) []Letter {
+	letters := []Letter{}
+	if s == nil {
+		return letters
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.letters) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(letters) == filter.Limit {
+			break
+		}
+		if filter.Matches(s.letters[i]) {
+			letters = append(letters, s.letters[i])
+		}
+	}
+	return letters
+}
+
+// Len returns the number of letters kept
+func (s *Store) Len() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.letters)
+}
+
+// index returns the position of letter id, or -1. The store must be locked.
+func (s *Store) index(id string) int {
+	for i, letter := range s.letters {
+		if letter.ID == id {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,59 @@
+package deadletter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	store := NewStore(2)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store.now = func() time.Time { now = now.Add(time.Second); return now }
+
+	store.Add(Letter{Client: "ip:10.0.0.1", Stage: StageDecode, Reason: "unexpected EOF", Body: "{"})
+	second := store.Add(Letter{Client: "key:emitter", Stage: StageValidate, Channel: "rocket-1", MessageType: "RocketLaunched", Body: "{}"})
+	store.Add(Letter{Client: "key:emitter", Stage: StageApply, Channel: "rocket-2", MessageType: "RocketSpeedDecreased", Body: "{}"})
+
+	// Only the latest letters are kept, newest first
+	letters := store.List(Filter{})
+	if len(letters) != 2 || letters[0].ID != "3" || letters[1].ID != "2" || store.Len() != 2 {
+		t.Fatalf("Unexpected letters: %+v", letters)
+	}
+	if second.ID != "2" || second.Attempts != 1 || !second.Time.Equal(now.Add(-time.Second)) {
+		t.Errorf("Unexpected letter: %+v", second)
+	}
+	if _, ok := store.Get("1"); ok {
+		t.Error("Expected the oldest letter to be evicted")
+	}
+
+	// Filters ignore case and combine
+	if letters := store.List(Filter{Client: "KEY:EMITTER", Stage: StageApply}); len(letters) != 1 || letters[0].Channel != "rocket-2" {
+		t.Errorf("Expected the apply letter, got %+v", letters)
+	}
+	if letters := store.List(Filter{Limit: 1}); len(letters) != 1 || letters[0].ID != "3" {
+		t.Errorf("Expected the newest letter only, got %+v", letters)
+	}
+
+	// A failed resubmission replaces the body and reason of the letter
+	failed, ok := store.Fail("2", Letter{Client: "key:ops", Stage: StageApply, Reason: "speed below zero", Channel: "rocket-1", Body: `{"fixed":true}`})
+	if !ok || failed.Attempts != 2 || failed.Client != "key:emitter" || failed.Stage != StageApply || failed.Body != `{"fixed":true}` {
+		t.Errorf("Unexpected failed letter: %+v", failed)
+	}
+	if _, ok := store.Fail("1", Letter{}); ok {
+		t.Error("Expected failing an evicted letter to report false")
+	}
+
+	if !store.Delete("2") || store.Delete("2") {
+		t.Error("Expected the letter to be deleted once")
+	}
+	if letters := store.List(Filter{}); len(letters) != 1 || letters[0].ID != "3" {
+		t.Errorf("Unexpected letters after deleting: %+v", letters)
+	}
+
+	// A nil store keeps nothing
+	var disabled *Store
+	disabled.Add(Letter{Body: "{"})
+	if letters := disabled.List(Filter{}); len(letters) != 0 || disabled.Len() != 0 {
+		t.Errorf("Expected no letters, got %+v", letters)
+	}
+}
@@ -0,0 +1,177 @@
+package graph
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/openapi"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// maxBodySize bounds the size of a GraphQL request body
+const maxBodySize = 1 << 20
+
+// Error codes set in the extensions of request errors
+const (
+	codeInvalidRequest  = "invalid_request"
+	codeQueryTooDeep    = "query_too_deep"
+	codeQueryTooComplex = "query_too_complex"
+)
+
+// limitError describes a query exceeding the limits
+type limitError struct {
+	code    string
+	message string
+}
+
+// Request is the body of a GraphQL request
+type Request struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// Handler serves GraphQL queries against the repository
+type Handler struct {
+	Auth *auth.Authenticator // Optional; the endpoint is open when nil or disabled
+
+	schema graphql.Schema
+	limits atomic.Pointer[Limits]
+}
+
+// NewHandler creates a handler resolving against repo
+func NewHandler(repo storage.RocketRepository, limits Limits) (*Handler, error) {
+	schema, err := NewSchema(repo)
+	if err != nil {
+		return nil, err
+	}
+	h := &Handler{schema: schema}
+	h.Update(limits)
+	return h, nil
+}
+
+// Update atomically replaces the query limits
+func (h *Handler) Update(limits Limits) {
+	h.limits.Store(&limits)
+}
+
+// RegisterRoutes registers the GraphQL endpoint with the provided router
+func (h *Handler) RegisterRoutes(mux openapi.Router) {
+	mux.HandleFunc("GET /graphql", h.Auth.Require(auth.ScopeRead, h.HandleGetQuery))
+	mux.HandleFunc("POST /graphql", h.Auth.Require(auth.ScopeRead, h.HandleQuery))
+}
+
+// HandleQuery executes a GraphQL query
+// @Summary GraphQL query
+// @Description Executes a read-only GraphQL query over rocket(id), rockets(filter, sort, page) and stats(filter). Queries exceeding the depth or complexity limits are rejected before execution. The schema can be explored with GraphiQL at /graphiql.
+// @Tags graphql
+// @Accept json
+// @Produce json
+// @Param request body graph.Request true "GraphQL request"
+// @Success 200 {object} map[string]any "data, with errors raised by resolvers"
+// @Failure 400 {object} map[string]any "errors for invalid requests, invalid queries, and queries over the limits (extensions.code is query_too_deep or query_too_complex)"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the read scope"
+// @Security ApiKeyAuth
+// @Router /graphql [post]
+func (h *Handler) HandleQuery(w http.ResponseWriter, r *http.Request) {
+	req, err := readRequest(r)
+	if err != nil {
+		respondWithErrors(w, http.StatusBadRequest, withCode(gqlerrors.NewFormattedError(err.Error()), codeInvalidRequest))
+		return
+	}
+
+	// Parse and validate the query before estimating its cost
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		respondWithErrors(w, http.StatusBadRequest, gqlerrors.FormatErrors(err)...)
+		return
+	}
+	if result := graphql.ValidateDocument(&h.schema, doc, nil); !result.IsValid {
+		respondWithErrors(w, http.StatusBadRequest, result.Errors...)
+		return
+	}
+	if limitErr := h.limits.Load().check(analyze(doc, req.OperationName, req.Variables)); limitErr != nil {
+		respondWithErrors(w, http.StatusBadRequest, withCode(gqlerrors.NewFormattedError(limitErr.message), limitErr.code))
+		return
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        h.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       r.Context(),
+	})
+	respondWithJSON(w, http.StatusOK, result)
+}
+
+// HandleGetQuery executes a GraphQL query passed in the query string
+// @Summary GraphQL query over GET
+// @Description Executes a read-only GraphQL query like POST /graphql, with the request in the query string
+// @Tags graphql
+// @Produce json
+// @Param query query string true "GraphQL query"
+// @Param variables query string false "Variables as a JSON object"
+// @Param operationName query string false "Operation to execute"
+// @Success 200 {object} map[string]any "data, with errors raised by resolvers"
+// @Failure 400 {object} map[string]any "errors for invalid requests, invalid queries, and queries over the limits (extensions.code is query_too_deep or query_too_complex)"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the read scope"
+// @Security ApiKeyAuth
+// @Router /graphql [get]
+func (h *Handler) HandleGetQuery(w http.ResponseWriter, r *http.Request) {
+	h.HandleQuery(w, r)
+}
+
+// readRequest reads a query from the JSON body of a POST or the query
+// string of a GET
+func readRequest(r *http.Request) (Request, error) {
+	var req Request
+	if r.Method == http.MethodGet {
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return req, fmt.Errorf("variables must be a JSON object: %v", err)
+			}
+		}
+	} else {
+		decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodySize))
+		if err := decoder.Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body: %v", err)
+		}
+	}
+	if req.Query == "" {
+		return req, fmt.Errorf("missing query")
+	}
+	return req, nil
+}
+
+// withCode sets the error code extension
+func withCode(err gqlerrors.FormattedError, code string) gqlerrors.FormattedError {
+	err.Extensions = map[string]any{"code": code}
+	return err
+}
+
+// respondWithErrors sends a result made only of errors
+func respondWithErrors(w http.ResponseWriter, code int, errs ...gqlerrors.FormattedError) {
+	respondWithJSON(w, code, &graphql.Result{Errors: errs})
+}
+
+// respondWithJSON sends a JSON response
+func respondWithJSON(w http.ResponseWriter, code int, payload any) {
+	response, _ := json.Marshal(payload)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(response)
+}
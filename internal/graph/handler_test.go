@@ -0,0 +1,180 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// response is the JSON form of a GraphQL result
+type response struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Message    string         `json:"message"`
+		Extensions map[string]any `json:"extensions"`
+	} `json:"errors"`
+}
+
+// launch adds a rocket to the repository
+func launch(t *testing.T, repo storage.RocketRepository, id, rocketType, mission string, speed int) {
+	t.Helper()
+
+	var envelope models.Envelope
+	envelope.Metadata.Channel = id
+	envelope.Metadata.MessageNumber = 1
+	envelope.Metadata.MessageTime = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	envelope.Metadata.MessageType = models.MessageTypeRocketLaunched
+	envelope.Message = models.MessageContent{Type: rocketType, LaunchSpeed: speed, Mission: mission}
+	if outcome := repo.ProcessMessage(context.Background(), envelope); outcome.Status != storage.StatusApplied {
+		t.Fatalf("Failed to launch %s: %+v", id, outcome)
+	}
+}
+
+// explode makes a rocket explode
+func explode(t *testing.T, repo storage.RocketRepository, id string) {
+	t.Helper()
+
+	var envelope models.Envelope
+	envelope.Metadata.Channel = id
+	envelope.Metadata.MessageNumber = 2
+	envelope.Metadata.MessageTime = time.Date(2025, 1, 1, 0, 1, 0, 0, time.UTC)
+	envelope.Metadata.MessageType = models.MessageTypeRocketExploded
+	envelope.Message = models.MessageContent{Reason: "PRESSURE_VESSEL_FAILURE"}
+	if outcome := repo.ProcessMessage(context.Background(), envelope); outcome.Status != storage.StatusApplied {
+		t.Fatalf("Failed to explode %s: %+v", id, outcome)
+	}
+}
+
+// newTestHandler creates a handler over three rockets, one of them exploded
+func newTestHandler(t *testing.T, limits Limits) *Handler {
+	t.Helper()
+
+	repo := storage.NewInMemoryRepository()
+	launch(t, repo, "rocket-a", "Falcon-9", "ARTEMIS", 3000)
+	launch(t, repo, "rocket-b", "Falcon-Heavy", "ARTEMIS", 1000)
+	launch(t, repo, "rocket-c", "Falcon-9", "APOLLO", 2000)
+	explode(t, repo, "rocket-c")
+
+	h, err := NewHandler(repo, limits)
+	if err != nil {
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	return h
+}
+
+// query posts a GraphQL request and decodes the response
+func query(t *testing.T, h *Handler, req Request) (int, response) {
+	t.Helper()
+
+	body, _ := json.Marshal(req)
+	rec := httptest.NewRecorder()
+	h.HandleQuery(rec, httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body)))
+
+	var resp response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return rec.Code, resp
+}
+
+func TestQueryRocket(t *testing.T) {
+	h := newTestHandler(t, Limits{MaxDepth: 5, MaxComplexity: 1000})
+
+	code, resp := query(t, h, Request{
+		Query:     `query($id: ID!) { rocket(id: $id) { id type speed status reason version } missing: rocket(id: "nope") { id } }`,
+		Variables: map[string]any{"id": "rocket-c"},
+	})
+	if code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("Expected success, got %d %+v", code, resp.Errors)
+	}
+	rocket := resp.Data["rocket"].(map[string]any)
+	if rocket["type"] != "Falcon-9" || rocket["status"] != "exploded" || rocket["reason"] != "PRESSURE_VESSEL_FAILURE" || rocket["version"] != 2.0 {
+		t.Errorf("Unexpected rocket: %v", rocket)
+	}
+	if resp.Data["missing"] != nil {
+		t.Errorf("Expected null for an unknown rocket, got %v", resp.Data["missing"])
+	}
+}
+
+func TestQueryRockets(t *testing.T) {
+	h := newTestHandler(t, Limits{MaxDepth: 5, MaxComplexity: 1000})
+
+	code, resp := query(t, h, Request{Query: `{
+		rockets(filter: {type: "falcon-9"}, sort: {field: SPEED, order: DESC}, page: {limit: 1}) {
+			total hasNext items { id speed }
+		}
+		stats { total active exploded maxSpeed averageSpeed byType { key count } }
+	}`})
+	if code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Fatalf("Expected success, got %d %+v", code, resp.Errors)
+	}
+
+	page := resp.Data["rockets"].(map[string]any)
+	items := page["items"].([]any)
+	if page["total"] != 2.0 || page["hasNext"] != true || len(items) != 1 || items[0].(map[string]any)["id"] != "rocket-a" {
+		t.Errorf("Unexpected page: %v", page)
+	}
+
+	stats := resp.Data["stats"].(map[string]any)
+	if stats["total"] != 3.0 || stats["active"] != 2.0 || stats["exploded"] != 1.0 || stats["maxSpeed"] != 3000.0 || stats["averageSpeed"] != 2000.0 {
+		t.Errorf("Unexpected stats: %v", stats)
+	}
+	byType := stats["byType"].([]any)
+	if len(byType) != 2 || byType[0].(map[string]any)["key"] != "Falcon-9" || byType[0].(map[string]any)["count"] != 2.0 {
+		t.Errorf("Unexpected groups: %v", byType)
+	}
+
+	// Queries are accepted over GET as well
+	rec := httptest.NewRecorder()
+	h.HandleQuery(rec, httptest.NewRequest(http.MethodGet, "/graphql?query="+url.QueryEscape("{ stats { total } }"), nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected GET to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestQueryLimits(t *testing.T) {
+	h := newTestHandler(t, Limits{MaxDepth: 2, MaxComplexity: 100})
+
+	// Depth counts nested fields, including those of fragments
+	_, resp := query(t, h, Request{Query: `{ rockets { ...page } } fragment page on RocketPage { items { id } }`})
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != codeQueryTooDeep {
+		t.Errorf("Expected query_too_deep, got %+v", resp.Errors)
+	}
+
+	// Page items cost once per requested element, including from variables
+	code, resp := query(t, h, Request{
+		Query:     `query($page: Page) { rockets(page: $page) { total } a: stats { total } }`,
+		Variables: map[string]any{"page": map[string]any{"limit": 500}},
+	})
+	if code != http.StatusOK {
+		t.Errorf("Expected a cheap query to pass, got %d %+v", code, resp.Errors)
+	}
+	h.Update(Limits{MaxDepth: 3, MaxComplexity: 100})
+	code, resp = query(t, h, Request{
+		Query:     `query($page: Page) { rockets(page: $page) { items { id } } }`,
+		Variables: map[string]any{"page": map[string]any{"limit": 500}},
+	})
+	if code != http.StatusBadRequest || len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != codeQueryTooComplex {
+		t.Errorf("Expected query_too_complex, got %d %+v", code, resp.Errors)
+	}
+
+	// Introspection is not limited, so GraphiQL can load the schema
+	code, resp = query(t, h, Request{Query: `{ __schema { types { name fields { name type { name ofType { name } } } } } }`})
+	if code != http.StatusOK || len(resp.Errors) > 0 {
+		t.Errorf("Expected introspection to pass, got %d %+v", code, resp.Errors)
+	}
+
+	// Invalid queries are rejected before execution
+	code, resp = query(t, h, Request{Query: `{ rockets { color } }`})
+	if code != http.StatusBadRequest || len(resp.Errors) == 0 {
+		t.Errorf("Expected a validation error, got %d %+v", code, resp.Errors)
+	}
+}
@@ -0,0 +1,164 @@
+package graph
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Limits bound the cost of a query before it is executed
+type Limits struct {
+	MaxDepth      int // Deepest field nesting, counting top-level fields as 1
+	MaxComplexity int // Estimated number of resolved fields
+}
+
+// defaultListSize estimates the number of elements of list fields whose size is
+// not set by an argument
+const defaultListSize = 10
+
+// cost is the depth and complexity of a selection set
+type cost struct {
+	depth      int
+	complexity int
+}
+
+// analyzer computes the cost of an operation, expanding fragments
+type analyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	visiting  map[string]bool // Fragments being expanded, to stop on cycles
+}
+
+// analyze computes the depth and complexity of the operation that will be
+// executed. Each field costs 1; the fields below a list cost once per
+// expected element, which is the page limit for rockets.items. Introspection
+// fields are free so that tools such as GraphiQL can load the schema.
+func analyze(doc *ast.Document, operationName string, variables map[string]any) cost {
+	a := &analyzer{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+		visiting:  map[string]bool{},
+	}
+	var operation *ast.OperationDefinition
+	for _, definition := range doc.Definitions {
+		switch d := definition.(type) {
+		case *ast.FragmentDefinition:
+			a.fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operation == nil && (operationName == "" || d.Name != nil && d.Name.Value == operationName) {
+				operation = d
+			}
+		}
+	}
+	if operation == nil {
+		return cost{}
+	}
+	return a.selectionSet(operation.SelectionSet, nil)
+}
+
+// selectionSet computes the cost of a selection set; parent is the field
+// that owns it, if any
+func (a *analyzer) selectionSet(set *ast.SelectionSet, parent *ast.Field) cost {
+	var total cost
+	if set == nil {
+		return total
+	}
+	for _, selection := range set.Selections {
+		var c cost
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name.Value, "__") {
+				continue
+			}
+			child := a.selectionSet(s.SelectionSet, s)
+			c = cost{depth: child.depth + 1, complexity: 1 + a.listSize(s, parent)*child.complexity}
+		case *ast.InlineFragment:
+			c = a.selectionSet(s.SelectionSet, parent)
+		case *ast.FragmentSpread:
+			fragment, ok := a.fragments[s.Name.Value]
+			if !ok || a.visiting[s.Name.Value] {
+				continue // Reported by validation
+			}
+			a.visiting[s.Name.Value] = true
+			c = a.selectionSet(fragment.SelectionSet, parent)
+			delete(a.visiting, s.Name.Value)
+		}
+		total.depth = max(total.depth, c.depth)
+		total.complexity += c.complexity
+	}
+	return total
+}
+
+// listSize is the expected number of elements of field, or 1 when it is
+// not a list
+func (a *analyzer) listSize(field, parent *ast.Field) int {
+	switch field.Name.Value {
+	case "items":
+		if parent == nil || parent.Name.Value != "rockets" {
+			return 1
+		}
+		limit := defaultPageLimit
+		for _, arg := range parent.Arguments {
+			if arg.Name.Value != "page" {
+				continue
+			}
+			if value, ok := a.value(arg.Value).(map[string]any); ok {
+				if n, ok := value["limit"].(int); ok {
+					limit = n
+				}
+			}
+		}
+		return min(max(limit, 0), maxPageLimit)
+	case "byType", "byMission":
+		return defaultListSize
+	default:
+		return 1
+	}
+}
+
+// value converts a literal or variable to the Go value used for limits
+func (a *analyzer) value(v ast.Value) any {
+	switch v := v.(type) {
+	case *ast.Variable:
+		value := a.variables[v.Name.Value]
+		if object, ok := value.(map[string]any); ok {
+			// Variables decoded from JSON carry numbers as float64
+			converted := make(map[string]any, len(object))
+			for key, field := range object {
+				if number, ok := field.(float64); ok {
+					field = int(number)
+				}
+				converted[key] = field
+			}
+			return converted
+		}
+		if number, ok := value.(float64); ok {
+			return int(number)
+		}
+		return value
+	case *ast.IntValue:
+		n, _ := strconv.Atoi(v.Value)
+		return n
+	case *ast.ObjectValue:
+		object := make(map[string]any, len(v.Fields))
+		for _, field := range v.Fields {
+			object[field.Name.Value] = a.value(field.Value)
+		}
+		return object
+	default:
+		return nil
+	}
+}
+
+// check reports the first limit exceeded by c, if any
+func (l Limits) check(c cost) *limitError {
+	if l.MaxDepth > 0 && c.depth > l.MaxDepth {
+		return &limitError{code: codeQueryTooDeep, message: fmt.Sprintf("query depth %d exceeds the maximum of %d", c.depth, l.MaxDepth)}
+	}
+	if l.MaxComplexity > 0 && c.complexity > l.MaxComplexity {
+		return &limitError{code: codeQueryTooComplex, message: fmt.Sprintf("query complexity %d exceeds the maximum of %d", c.complexity, l.MaxComplexity)}
+	}
+	return nil
+}
@@ -0,0 +1,343 @@
+// Package graph serves a read-only GraphQL view of the rockets, so that
+// dashboards can fetch a rocket, a filtered page of rockets and aggregate
+// statistics in a single request.
+package graph
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// Bounds of the rockets page size
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 500
+)
+
+// rocketStatus is the status of a rocket as listed by GET /rockets
+func rocketStatus(exploded bool) string {
+	if exploded {
+		return "exploded"
+	}
+	return "active"
+}
+
+// rocketType is the complete state of a rocket
+var rocketType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "Rocket",
+	Description: "Complete state of a rocket",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"type":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"speed":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"mission":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"exploded": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"reason":   &graphql.Field{Type: graphql.String, Description: "Reason for the explosion, if any"},
+		"paused":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Description: "Whether an operator paused ingestion"},
+		"status": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.String),
+			Description: "active or exploded",
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return rocketStatus(p.Source.(*models.RocketState).Exploded), nil
+			},
+		},
+		"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"version":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Incremented on every applied update"},
+	},
+})
+
+// rocketSummaryType is the shortened form of a rocket used in lists
+var rocketSummaryType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "RocketSummary",
+	Description: "Shortened form of a rocket, as listed by GET /rockets",
+	Fields: graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"type":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"speed":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"mission":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":    &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "active or exploded"},
+		"updatedAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"version":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+	},
+})
+
+// rocketPage is the resolved value of the rockets field
+type rocketPage struct {
+	Items  []models.RocketSummary `json:"items"`
+	Total  int                    `json:"total"`
+	Offset int                    `json:"offset"`
+	Limit  int                    `json:"limit"`
+}
+
+var rocketPageType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "RocketPage",
+	Description: "A page of rocket summaries",
+	Fields: graphql.Fields{
+		"items":  &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(rocketSummaryType)))},
+		"total":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Description: "Number of rockets matching the filter"},
+		"offset": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"limit":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"hasNext": &graphql.Field{
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				page := p.Source.(rocketPage)
+				return page.Offset+len(page.Items) < page.Total, nil
+			},
+		},
+	},
+})
+
+// groupStats aggregates the rockets sharing a type or a mission
+type groupStats struct {
+	Key          string  `json:"key"`
+	Count        int     `json:"count"`
+	Active       int     `json:"active"`
+	Exploded     int     `json:"exploded"`
+	AverageSpeed float64 `json:"averageSpeed"`
+}
+
+// rocketStats aggregates the rockets matching a filter
+type rocketStats struct {
+	Total        int          `json:"total"`
+	Active       int          `json:"active"`
+	Exploded     int          `json:"exploded"`
+	AverageSpeed float64      `json:"averageSpeed"`
+	MaxSpeed     int          `json:"maxSpeed"`
+	ByType       []groupStats `json:"byType"`
+	ByMission    []groupStats `json:"byMission"`
+}
+
+var groupStatsType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "GroupStats",
+	Description: "Statistics of the rockets sharing a type or a mission",
+	Fields: graphql.Fields{
+		"key":          &graphql.Field{Type: graphql.NewNonNull(graphql.String), Description: "The type or mission"},
+		"count":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"active":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"exploded":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"averageSpeed": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var rocketStatsType = graphql.NewObject(graphql.ObjectConfig{
+	Name:        "RocketStats",
+	Description: "Aggregate statistics of the rockets",
+	Fields: graphql.Fields{
+		"total":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"active":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"exploded":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"averageSpeed": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"maxSpeed":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"byType":       &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(groupStatsType)))},
+		"byMission":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(groupStatsType)))},
+	},
+})
+
+var rocketFilterInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name:        "RocketFilter",
+	Description: "Selects rockets; omitted fields match every rocket and comparisons ignore case",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"type":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"mission": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"status":  &graphql.InputObjectFieldConfig{Type: graphql.String, Description: "active or exploded"},
+	},
+})
+
+var sortFieldEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "RocketSortField",
+	Values: graphql.EnumValueConfigMap{
+		"ID":         &graphql.EnumValueConfig{Value: "id"},
+		"TYPE":       &graphql.EnumValueConfig{Value: "type"},
+		"SPEED":      &graphql.EnumValueConfig{Value: "speed"},
+		"MISSION":    &graphql.EnumValueConfig{Value: "mission"},
+		"STATUS":     &graphql.EnumValueConfig{Value: "status"},
+		"UPDATED_AT": &graphql.EnumValueConfig{Value: "updatedat"},
+	},
+})
+
+var sortOrderEnum = graphql.NewEnum(graphql.EnumConfig{
+	Name: "SortOrder",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: "asc"},
+		"DESC": &graphql.EnumValueConfig{Value: "desc"},
+	},
+})
+
+var rocketSortInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "RocketSort",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"field": &graphql.InputObjectFieldConfig{Type: sortFieldEnum, DefaultValue: "id"},
+		"order": &graphql.InputObjectFieldConfig{Type: sortOrderEnum, DefaultValue: "asc"},
+	},
+})
+
+var pageInput = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "Page",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"offset": &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: 0},
+		"limit":  &graphql.InputObjectFieldConfig{Type: graphql.Int, DefaultValue: defaultPageLimit, Description: "At most 500"},
+	},
+})
+
+// NewSchema builds the GraphQL schema resolving against repo
+func NewSchema(repo storage.RocketRepository) (graphql.Schema, error) {
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"rocket": &graphql.Field{
+				Type:        rocketType,
+				Description: "A rocket by ID, or null when it is unknown",
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					rocket, exists := repo.GetRocket(p.Context, p.Args["id"].(string))
+					if !exists {
+						return nil, p.Context.Err()
+					}
+					return rocket, nil
+				},
+			},
+			"rockets": &graphql.Field{
+				Type:        graphql.NewNonNull(rocketPageType),
+				Description: "A page of the rockets matching the filter, in the requested order",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: rocketFilterInput},
+					"sort":   &graphql.ArgumentConfig{Type: rocketSortInput},
+					"page":   &graphql.ArgumentConfig{Type: pageInput},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					return resolveRockets(p, repo)
+				},
+			},
+			"stats": &graphql.Field{
+				Type:        graphql.NewNonNull(rocketStatsType),
+				Description: "Aggregate statistics of the rockets matching the filter",
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: rocketFilterInput},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					rockets, err := repo.ListRockets(p.Context, "", "")
+					if err != nil {
+						return nil, err
+					}
+					return computeStats(storage.FilterRocketSummaries(rockets, filterArg(p.Args))), nil
+				},
+			},
+		},
+	})
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
+
+// resolveRockets lists, filters, sorts and pages the rockets
+func resolveRockets(p graphql.ResolveParams, repo storage.RocketRepository) (any, error) {
+	sortField, order := "id", "asc"
+	if s, ok := p.Args["sort"].(map[string]any); ok {
+		sortField, _ = s["field"].(string)
+		order, _ = s["order"].(string)
+	}
+	offset, limit := pageArgs(p.Args)
+	if offset < 0 {
+		return nil, fmt.Errorf("page.offset must not be negative")
+	}
+	if limit < 0 || limit > maxPageLimit {
+		return nil, fmt.Errorf("page.limit must be between 0 and %d", maxPageLimit)
+	}
+
+	rockets, err := repo.ListRockets(p.Context, sortField, order)
+	if err != nil {
+		return nil, err
+	}
+	rockets = storage.FilterRocketSummaries(rockets, filterArg(p.Args))
+
+	page := rocketPage{Total: len(rockets), Offset: offset, Limit: limit}
+	start, end := min(offset, len(rockets)), min(offset+limit, len(rockets))
+	page.Items = rockets[start:end]
+	return page, nil
+}
+
+// pageArgs returns the offset and limit of the page argument
+func pageArgs(args map[string]any) (offset, limit int) {
+	limit = defaultPageLimit
+	if page, ok := args["page"].(map[string]any); ok {
+		if value, ok := page["offset"].(int); ok {
+			offset = value
+		}
+		if value, ok := page["limit"].(int); ok {
+			limit = value
+		}
+	}
+	return offset, limit
+}
+
+// filterArg converts the filter argument
+func filterArg(args map[string]any) storage.RocketFilter {
+	var filter storage.RocketFilter
+	if f, ok := args["filter"].(map[string]any); ok {
+		filter.Type, _ = f["type"].(string)
+		filter.Mission, _ = f["mission"].(string)
+		filter.Status, _ = f["status"].(string)
+	}
+	return filter
+}
+
+// computeStats aggregates rocket summaries overall, by type and by mission.
+// Groups are ordered by key.
+func computeStats(rockets []models.RocketSummary) rocketStats {
+	stats := rocketStats{Total: len(rockets), ByType: []groupStats{}, ByMission: []groupStats{}}
+	byType := map[string]*groupStats{}
+	byMission := map[string]*groupStats{}
+	speeds := 0
+	for _, rocket := range rockets {
+		exploded := rocket.Status == rocketStatus(true)
+		if exploded {
+			stats.Exploded++
+		} else {
+			stats.Active++
+		}
+		speeds += rocket.Speed
+		stats.MaxSpeed = max(stats.MaxSpeed, rocket.Speed)
+		addToGroup(byType, rocket.Type, rocket.Speed, exploded)
+		addToGroup(byMission, rocket.Mission, rocket.Speed, exploded)
+	}
+	if stats.Total > 0 {
+		stats.AverageSpeed = float64(speeds) / float64(stats.Total)
+	}
+	stats.ByType = sortedGroups(byType)
+	stats.ByMission = sortedGroups(byMission)
+	return stats
+}
+
+// addToGroup counts a rocket in its group, keeping the speed total in
+// AverageSpeed until sortedGroups divides it
+func addToGroup(groups map[string]*groupStats, key string, speed int, exploded bool) {
+	group, ok := groups[key]
+	if !ok {
+		group = &groupStats{Key: key}
+		groups[key] = group
+	}
+	group.Count++
+	group.AverageSpeed += float64(speed)
+	if exploded {
+		group.Exploded++
+	} else {
+		group.Active++
+	}
+}
+
+// sortedGroups finishes the groups and orders them by key
+func sortedGroups(groups map[string]*groupStats) []groupStats {
+	result := make([]groupStats, 0, len(groups))
+	for _, group := range groups {
+		group.AverageSpeed /= float64(group.Count)
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result
+}
@@ -0,0 +1,65 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Log output formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New creates a structured logger writing to w in the given format.
+// The level is read from level on every call, so it can be changed at runtime.
+func New(w io.Writer, format string, level *slog.LevelVar) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	case FormatText, "":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %q or %q)", format, FormatJSON, FormatText)
+	}
+}
+
+// ParseLevel converts a level name such as "debug" or "warn" into a slog.Level
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	requestIDKey
+)
+
+// WithLogger returns a context carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// FromContext returns the request-scoped logger, or slog.Default when there is none
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// RequestIDFromContext returns the request ID assigned by the middleware, if any
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
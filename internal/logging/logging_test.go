@@ -0,0 +1,77 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewRejectsUnknownFormat(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, "xml", new(slog.LevelVar)); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Errorf("Expected an error for an unknown level")
+	}
+	if level, err := ParseLevel("debug"); err != nil || level != slog.LevelDebug {
+		t.Errorf("Expected debug level, got %v (%v)", level, err)
+	}
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	levelVar := new(slog.LevelVar)
+	levelVar.Set(slog.LevelDebug)
+	logger, err := New(&buf, FormatJSON, levelVar)
+	if err != nil {
+		t.Fatalf("Failed to create logger: %v", err)
+	}
+
+	var seenID string
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rockets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		seenID = RequestIDFromContext(r.Context())
+		FromContext(r.Context()).Debug("inside handler")
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := Middleware(logger)(mux)
+
+	// A client-provided ID is propagated
+	req := httptest.NewRequest(http.MethodGet, "/rockets/abc", nil)
+	req.Header.Set(RequestIDHeader, "client-id-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIDHeader); got != "client-id-1" {
+		t.Errorf("Expected propagated request ID, got %q", got)
+	}
+	if seenID != "client-id-1" {
+		t.Errorf("Expected handler to see the request ID, got %q", seenID)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a handler line and an access line, got %d lines", len(lines))
+	}
+	var access map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &access); err != nil {
+		t.Fatalf("Failed to decode access log: %v", err)
+	}
+	if access["request_id"] != "client-id-1" || access["route"] != "GET /rockets/{id}" || access["status"] != float64(http.StatusTeapot) {
+		t.Errorf("Unexpected access log: %v", access)
+	}
+
+	// A missing or invalid ID is replaced with a generated one
+	req = httptest.NewRequest(http.MethodGet, "/rockets/abc", nil)
+	req.Header.Set(RequestIDHeader, "has spaces")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get(RequestIDHeader); len(got) != 32 {
+		t.Errorf("Expected a generated 32-character request ID, got %q", got)
+	}
+}
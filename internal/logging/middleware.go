@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader is the header used to receive and return request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds request IDs accepted from clients
+const maxRequestIDLength = 128
+
+// Middleware assigns or propagates a request ID, stores a request-scoped
+// logger in the context and writes one access log line per request
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(RequestIDHeader)
+			if !validRequestID(requestID) {
+				requestID = newRequestID()
+			}
+			w.Header().Set(RequestIDHeader, requestID)
+
+			reqLogger := logger.With("request_id", requestID)
+			ctx := context.WithValue(r.Context(), requestIDKey, requestID)
+			ctx = WithLogger(ctx, reqLogger)
+			r = r.WithContext(ctx)
+
+			sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(sw, r)
+
+			reqLogger.LogAttrs(ctx, slog.LevelInfo, "request",
+				slog.String("method", r.Method),
+				slog.String("path", r.URL.Path),
+				slog.String("route", r.Pattern),
+				slog.Int("status", sw.status),
+				slog.Int("bytes", sw.bytes),
+				slog.Duration("duration", time.Since(start)),
+				slog.String("remote", r.RemoteAddr),
+				slog.String("user_agent", r.UserAgent()),
+			)
+		})
+	}
+}
+
+// validRequestID accepts short, printable ASCII IDs from clients
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID generates a random 128-bit hex request ID
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// statusWriter captures the status code and body size written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,173 @@
+package messages
+
+import (
+	"errors"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// Builtin returns a registry with the message types emitted by the rockets
+func Builtin() *Registry {
+	r := NewRegistry()
+	r.MustRegister(RocketLaunched)
+	r.MustRegister(RocketSpeedIncreased)
+	r.MustRegister(RocketSpeedDecreased)
+	r.MustRegister(RocketExploded)
+	r.MustRegister(RocketMissionChanged)
+	return r
+}
+
+// RocketLaunched starts, or restarts after an explosion, a rocket
+var RocketLaunched = Type{
+	Name:       models.MessageTypeRocketLaunched,
+	NewPayload: func() Payload { return &LaunchedPayload{} },
+	Apply: func(rocket *models.RocketState, msg models.Envelope) error {
+		if msg.Message.Type == "" || msg.Message.Mission == "" {
+			return errors.New("launch requires type and mission")
+		}
+		rocket.Type = msg.Message.Type
+		rocket.Mission = msg.Message.Mission
+		rocket.Speed = msg.Message.LaunchSpeed
+		rocket.CreatedAt = msg.GetMessageTime()
+		rocket.Exploded = false
+		rocket.Reason = ""
+		return nil
+	},
+	AppliesAfterExplosion: true,
+}
+
+// LaunchedPayload is the body of RocketLaunched
+type LaunchedPayload struct {
+	Type        *string `json:"type"`
+	LaunchSpeed *int    `json:"launchSpeed"`
+	Mission     *string `json:"mission"`
+}
+
+func (p *LaunchedPayload) Validate() error {
+	return errors.Join(
+		requireString("type", p.Type),
+		requireInt("launchSpeed", p.LaunchSpeed, 0),
+		requireString("mission", p.Mission),
+	)
+}
+
+func (p *LaunchedPayload) Content() models.MessageContent {
+	return models.MessageContent{Type: *p.Type, LaunchSpeed: *p.LaunchSpeed, Mission: *p.Mission}
+}
+
+// RocketSpeedIncreased adds to the rocket's speed
+var RocketSpeedIncreased = Type{
+	Name:       models.MessageTypeRocketSpeedIncreased,
+	NewPayload: func() Payload { return &SpeedChangedPayload{} },
+	Apply: func(rocket *models.RocketState, msg models.Envelope) error {
+		if msg.Message.By <= 0 {
+			return errors.New("speed change must be positive")
+		}
+		rocket.Speed += msg.Message.By
+		return nil
+	},
+}
+
+// RocketSpeedDecreased subtracts from the rocket's speed, stopping at zero
+var RocketSpeedDecreased = Type{
+	Name:       models.MessageTypeRocketSpeedDecreased,
+	NewPayload: func() Payload { return &SpeedChangedPayload{} },
+	Apply: func(rocket *models.RocketState, msg models.Envelope) error {
+		if msg.Message.By <= 0 {
+			return errors.New("speed change must be positive")
+		}
+		rocket.Speed -= msg.Message.By
+		if rocket.Speed < 0 {
+			rocket.Speed = 0
+		}
+		return nil
+	},
+}
+
+// SpeedChangedPayload is the body of RocketSpeedIncreased and RocketSpeedDecreased
+type SpeedChangedPayload struct {
+	By *int `json:"by"`
+}
+
+func (p *SpeedChangedPayload) Validate() error {
+	return requireInt("by", p.By, 1)
+}
+
+func (p *SpeedChangedPayload) Content() models.MessageContent {
+	return models.MessageContent{By: *p.By}
+}
+
+// RocketExploded marks the rocket as exploded
+var RocketExploded = Type{
+	Name:       models.MessageTypeRocketExploded,
+	NewPayload: func() Payload { return &ExplodedPayload{} },
+	Apply: func(rocket *models.RocketState, msg models.Envelope) error {
+		if msg.Message.Reason == "" {
+			return errors.New("explosion requires a reason")
+		}
+		rocket.Exploded = true
+		rocket.Reason = msg.Message.Reason
+		return nil
+	},
+}
+
+// ExplodedPayload is the body of RocketExploded
+type ExplodedPayload struct {
+	Reason *string `json:"reason"`
+}
+
+func (p *ExplodedPayload) Validate() error {
+	return requireString("reason", p.Reason)
+}
+
+func (p *ExplodedPayload) Content() models.MessageContent {
+	return models.MessageContent{Reason: *p.Reason}
+}
+
+// RocketMissionChanged assigns the rocket a new mission
+var RocketMissionChanged = Type{
+	Name:       models.MessageTypeRocketMissionChanged,
+	NewPayload: func() Payload { return &MissionChangedPayload{} },
+	Apply: func(rocket *models.RocketState, msg models.Envelope) error {
+		if msg.Message.NewMission == "" {
+			return errors.New("mission change requires a new mission")
+		}
+		rocket.Mission = msg.Message.NewMission
+		return nil
+	},
+}
+
+// MissionChangedPayload is the body of RocketMissionChanged
+type MissionChangedPayload struct {
+	NewMission *string `json:"newMission"`
+}
+
+func (p *MissionChangedPayload) Validate() error {
+	return requireString("newMission", p.NewMission)
+}
+
+func (p *MissionChangedPayload) Content() models.MessageContent {
+	return models.MessageContent{NewMission: *p.NewMission}
+}
+
+// requireString reports a missing or empty string field
+func requireString(field string, value *string) error {
+	if value == nil {
+		return fieldError(field, "required")
+	}
+	if *value == "" {
+		return fieldError(field, "must not be empty")
+	}
+	return nil
+}
+
+// requireInt reports a missing integer field or one below minimum
+func requireInt(field string, value *int, minimum int) error {
+	if value == nil {
+		return fieldError(field, "required")
+	}
+	if *value < minimum {
+		return fieldError(field, "must be at least %d, got %d", minimum, *value)
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// Payload is the typed body of one message type. Optional and required
+// fields are pointers so a missing field can be told apart from a zero value.
+type Payload interface {
+	// Validate checks required fields and ranges, reporting every problem as a FieldError
+	Validate() error
+
+	// Content converts the payload into the form the reducers apply
+	Content() models.MessageContent
+}
+
+// FieldError is a problem with one field, identified by its JSON path
+type FieldError struct {
+	Path    string // For example "message.by"
+	Message string
+	Err     error // Optional cause, such as ErrUnknownType
+}
+
+func (e *FieldError) Error() string {
+	return e.Path + ": " + e.Message
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// FieldErrors returns the FieldErrors in err, which may be joined
+func FieldErrors(err error) []*FieldError {
+	var fieldErrs []*FieldError
+	for _, e := range flatten(err) {
+		var fieldErr *FieldError
+		if errors.As(e, &fieldErr) {
+			fieldErrs = append(fieldErrs, fieldErr)
+		}
+	}
+	return fieldErrs
+}
+
+// Join joins errs like errors.Join, keeping only the first FieldError for
+// each path. A field with a value of the wrong type is then not reported as
+// missing as well.
+func Join(errs ...error) error {
+	var joined []error
+	seen := make(map[string]bool)
+	for _, err := range errs {
+		for _, e := range flatten(err) {
+			var fieldErr *FieldError
+			if errors.As(e, &fieldErr) {
+				if seen[fieldErr.Path] {
+					continue
+				}
+				seen[fieldErr.Path] = true
+			}
+			joined = append(joined, e)
+		}
+	}
+	return errors.Join(joined...)
+}
+
+// flatten returns the errors joined in err
+func flatten(err error) []error {
+	if err == nil {
+		return nil
+	}
+	multi, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		return []error{err}
+	}
+	var errs []error
+	for _, e := range multi.Unwrap() {
+		errs = append(errs, flatten(e)...)
+	}
+	return errs
+}
+
+// payloadPath is the JSON path of the message body within an envelope
+const payloadPath = "message"
+
+// fieldError returns a FieldError for a field of the message body
+func fieldError(field, format string, args ...any) *FieldError {
+	return &FieldError{Path: joinPath(payloadPath, field), Message: fmt.Sprintf(format, args...)}
+}
+
+// joinPath appends a field name to the JSON path of its object
+func joinPath(path, field string) string {
+	if path == "" {
+		return field
+	}
+	return path + "." + field
+}
+
+// Decode unmarshals the raw message body of messageType into its typed
+// payload, validates it and returns the content the reducers apply.
+// Fields that the type does not declare are rejected, naming the type they
+// belong to when another type declares them. Every problem is reported.
+func (r *Registry) Decode(messageType string, raw json.RawMessage) (models.MessageContent, error) {
+	content, _, err := r.decode(messageType, raw, false)
+	return content, err
+}
+
+// decode is Decode with an optional lenient mode, in which fields the type
+// does not declare are dropped and returned as JSON paths, and integers may
+// be sent as strings
+func (r *Registry) decode(messageType string, raw json.RawMessage, lenient bool) (models.MessageContent, []string, error) {
+	t, ok := r.Lookup(messageType)
+	if !ok {
+		return models.MessageContent{}, nil, ErrUnknownType
+	}
+
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		raw = []byte("{}")
+	}
+
+	// Check field names first so foreign fields get a useful message
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return models.MessageContent{}, nil, &FieldError{Path: payloadPath, Message: "must be a JSON object"}
+	}
+
+	payload := t.NewPayload()
+	var unknown []string
+	var errs []error
+	if lenient {
+		ignored, err := decodeLenient(payloadPath, raw, payload)
+		unknown = ignored
+		errs = append(errs, err)
+	} else {
+		declared := t.Fields()
+		for _, name := range sortedKeys(fields) {
+			if contains(declared, name) {
+				continue
+			}
+			if owners := r.owners(name); len(owners) > 0 {
+				errs = append(errs, fieldError(name, "belongs to %s, not %s", strings.Join(owners, " or "), t.Name))
+			} else {
+				errs = append(errs, fieldError(name, "unknown field"))
+			}
+		}
+		errs = append(errs, decodeFields(payloadPath, raw, fields, payload))
+	}
+
+	if err := Join(append(errs, payload.Validate())...); err != nil {
+		return models.MessageContent{}, unknown, err
+	}
+	return payload.Content(), unknown, nil
+}
+
+// decodeFields unmarshals the JSON object raw, whose fields are given, into
+// v. When a value has the wrong type, the fields are decoded one at a time
+// so every such field is reported under path.
+func decodeFields(path string, raw []byte, fields map[string]json.RawMessage, v any) error {
+	if err := json.Unmarshal(raw, v); err == nil {
+		return nil
+	}
+
+	var errs []error
+	for _, name := range sortedKeys(fields) {
+		single, _ := json.Marshal(map[string]json.RawMessage{name: fields[name]})
+		if err := json.Unmarshal(single, v); err != nil {
+			errs = append(errs, decodeError(path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// decodeError turns a JSON type error into a FieldError under path
+func decodeError(path string, err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return &FieldError{Path: joinPath(path, typeErr.Field), Message: fmt.Sprintf("expected %s, got %s", jsonKind(typeErr.Type), typeErr.Value)}
+	}
+	return &FieldError{Path: path, Message: err.Error()}
+}
+
+// jsonKind names the JSON type expected for a Go type
+func jsonKind(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return "integer"
+	case reflect.Float64, reflect.Float32:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return t.Kind().String()
+	}
+}
+
+// Fields returns the JSON field names declared by the type's payload
+func (t *Type) Fields() []string {
+	typ := reflect.TypeOf(t.NewPayload()).Elem()
+	fields := make([]string, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// owners returns the names of the registered types that declare field
+func (r *Registry) owners(field string) []string {
+	var owners []string
+	for _, name := range r.Names() {
+		t, _ := r.Lookup(name)
+		if contains(t.Fields(), field) {
+			owners = append(owners, name)
+		}
+	}
+	return owners
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
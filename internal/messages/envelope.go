@@ -0,0 +1,61 @@
+package messages
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// incomingEnvelope is the wire form of models.Envelope. The message body is
+// kept raw until the message type is known.
+type incomingEnvelope struct {
+	Metadata json.RawMessage `json:"metadata"`
+	Message  json.RawMessage `json:"message"`
+}
+
+// DecodeEnvelope parses an incoming message envelope, ensures it contains
+// all required fields and decodes its payload with the registered message
+// type into envelope.Message. A body that is not a JSON object fails with a
+// plain error; otherwise every problem found is reported as a FieldError.
+// In lenient mode the JSON paths of ignored fields are returned as well.
+func (d *Decoder) DecodeEnvelope(body []byte) (models.Envelope, []string, error) {
+	var envelope models.Envelope
+	var incoming incomingEnvelope
+	unknown, err := d.Decode("", body, &incoming)
+	var fieldErr *FieldError
+	if err != nil && !errors.As(err, &fieldErr) {
+		return envelope, nil, err
+	}
+	errs := []error{err}
+
+	// Decode and validate metadata fields
+	if len(incoming.Metadata) == 0 {
+		errs = append(errs, &FieldError{Path: "metadata", Message: "required"})
+	} else {
+		ignored, err := d.Decode("metadata", incoming.Metadata, &envelope.Metadata)
+		unknown = append(unknown, ignored...)
+		errs = append(errs, err)
+	}
+	if envelope.Metadata.Channel == "" {
+		errs = append(errs, &FieldError{Path: "metadata.channel", Message: "missing or empty"})
+	}
+	if envelope.Metadata.MessageNumber <= 0 {
+		errs = append(errs, &FieldError{Path: "metadata.messageNumber", Message: "must be a positive integer"})
+	}
+	if envelope.Metadata.MessageTime.IsZero() {
+		errs = append(errs, &FieldError{Path: "metadata.messageTime", Message: "missing"})
+	}
+
+	// Decode the payload with the registered message type
+	content, ignored, err := d.DecodePayload(envelope.GetMessageType(), incoming.Message)
+	if errors.Is(err, ErrUnknownType) {
+		err = &FieldError{Path: "metadata.messageType", Message: fmt.Sprintf("unknown message type %q", envelope.GetMessageType()), Err: err}
+	}
+	unknown = append(unknown, ignored...)
+	errs = append(errs, err)
+	envelope.Message = content
+
+	return envelope, unknown, Join(errs...)
+}
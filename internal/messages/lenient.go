@@ -0,0 +1,227 @@
+package messages
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/utils"
+)
+
+// Mode selects how strictly incoming messages are decoded
+type Mode string
+
+// Mode constants
+const (
+	ModeStrict  Mode = "strict"  // Unknown fields and mistyped values are rejected
+	ModeLenient Mode = "lenient" // Unknown fields are ignored and recorded, integers may be strings
+)
+
+// ParseMode validates a decoding mode
+func ParseMode(name string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(name))); m {
+	case ModeStrict, ModeLenient:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown decoding mode %q (expected strict or lenient)", name)
+	}
+}
+
+// maxUnknownFields caps the distinct unknown fields recorded per message type,
+// since field names come from the emitters
+const maxUnknownFields = 100
+
+// Decoder decodes envelopes and payloads in the configured mode. In lenient
+// mode, fields nobody declared are ignored so a new emitter field does not
+// stop ingestion, and are recorded per message type for visibility.
+type Decoder struct {
+	Registry *Registry
+	logger   *slog.Logger
+	mode     atomic.Value // Mode
+
+	mu      sync.Mutex
+	unknown map[string]map[string]int64 // Message type -> JSON path -> count
+}
+
+// NewDecoder creates a decoder for the types in registry
+func NewDecoder(registry *Registry, mode Mode, logger *slog.Logger) *Decoder {
+	d := &Decoder{Registry: registry, logger: logger, unknown: make(map[string]map[string]int64)}
+	d.SetMode(mode)
+	return d
+}
+
+// SetMode switches the decoding mode
+func (d *Decoder) SetMode(mode Mode) {
+	d.mode.Store(mode)
+}
+
+// Mode returns the decoding mode in effect
+func (d *Decoder) Mode() Mode {
+	return d.mode.Load().(Mode)
+}
+
+// Decode unmarshals the JSON object at path into the struct v points to,
+// reporting every problem as a FieldError. Strict mode rejects fields v does
+// not declare; lenient mode drops them and returns their paths, and accepts
+// integers sent as strings. Data that is not a JSON object at the top level,
+// where path is empty, fails with a plain error.
+func (d *Decoder) Decode(path string, data []byte, v any) ([]string, error) {
+	if d.Mode() == ModeLenient {
+		return decodeLenient(path, data, v)
+	}
+
+	fields, err := objectFields(path, data)
+	if err != nil {
+		return nil, err
+	}
+	var errs []error
+	declared := jsonFields(reflect.TypeOf(v).Elem())
+	for _, name := range sortedKeys(fields) {
+		if _, ok := declared[name]; !ok {
+			errs = append(errs, &FieldError{Path: joinPath(path, name), Message: "unknown field"})
+		}
+	}
+	errs = append(errs, decodeFields(path, data, fields, v))
+	return nil, errors.Join(errs...)
+}
+
+// DecodePayload decodes the message body of messageType in the current mode.
+// In lenient mode it returns the fields that were ignored.
+func (d *Decoder) DecodePayload(messageType string, raw json.RawMessage) (models.MessageContent, []string, error) {
+	return d.Registry.decode(messageType, raw, d.Mode() == ModeLenient)
+}
+
+// RecordUnknown counts fields ignored for a message type, identified by
+// their JSON paths. The first sighting of each field is logged.
+func (d *Decoder) RecordUnknown(messageType string, paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	counts, ok := d.unknown[messageType]
+	if !ok {
+		counts = make(map[string]int64)
+		d.unknown[messageType] = counts
+	}
+	for _, path := range paths {
+		if _, seen := counts[path]; !seen {
+			if len(counts) >= maxUnknownFields {
+				path = "other"
+			} else {
+				d.logger.Warn("Ignoring unknown message field", "messageType", messageType, "field", path)
+			}
+		}
+		counts[path]++
+	}
+}
+
+// UnknownFields returns how often each ignored field was seen, per message type
+func (d *Decoder) UnknownFields() map[string]map[string]int64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	snapshot := make(map[string]map[string]int64, len(d.unknown))
+	for messageType, counts := range d.unknown {
+		copied := make(map[string]int64, len(counts))
+		for path, n := range counts {
+			copied[path] = n
+		}
+		snapshot[messageType] = copied
+	}
+	return snapshot
+}
+
+// decodeLenient decodes the JSON object at path into the struct v points
+// to. Fields the struct does not declare are dropped and their paths
+// returned, and strings holding an integer are converted for integer fields.
+func decodeLenient(path string, data []byte, v any) ([]string, error) {
+	if _, err := objectFields(path, data); err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber() // Keep numbers exact when re-encoding
+	var values map[string]any
+	if err := decoder.Decode(&values); err != nil {
+		return nil, err
+	}
+
+	declared := jsonFields(reflect.TypeOf(v).Elem())
+	var unknown []string
+	fields := make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		fieldType, ok := declared[name]
+		if !ok {
+			unknown = append(unknown, joinPath(path, name))
+			continue
+		}
+		if s, isString := value.(string); isString && isInt(fieldType) {
+			if n, ok := utils.CoerceInt(s); ok {
+				value = n
+			}
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return unknown, err
+		}
+		fields[name] = encoded
+	}
+	sort.Strings(unknown)
+
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return unknown, err
+	}
+	return unknown, decodeFields(path, normalized, fields, v)
+}
+
+// objectFields splits a JSON object into its fields. Data that is not an
+// object is a FieldError at path, or a plain error at the top level.
+func objectFields(path string, data []byte) (map[string]json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(data, &fields)
+	if err == nil && fields == nil {
+		err = errors.New("expected a JSON object, got null")
+	}
+	if err != nil {
+		if path == "" {
+			return nil, err
+		}
+		return nil, &FieldError{Path: path, Message: "must be a JSON object"}
+	}
+	return fields, nil
+}
+
+// jsonFields maps the JSON names of a struct's fields to their types
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = typ.Field(i).Type
+		}
+	}
+	return fields
+}
+
+// isInt reports whether t is an integer or a pointer to one
+func isInt(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Int32:
+		return true
+	}
+	return false
+}
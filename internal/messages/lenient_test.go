@@ -0,0 +1,96 @@
+package messages
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+func TestDecodePayloadLenient(t *testing.T) {
+	d := NewDecoder(Builtin(), ModeLenient, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	content, unknown, err := d.DecodePayload(models.MessageTypeRocketLaunched,
+		json.RawMessage(`{"type":"Falcon-9","launchSpeed":"500","mission":"ARTEMIS","stage":2}`))
+	if err != nil {
+		t.Fatalf("Expected lenient decoding to succeed, got %v", err)
+	}
+	expected := models.MessageContent{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}
+	if content != expected {
+		t.Errorf("Expected %+v, got %+v", expected, content)
+	}
+	if strings.Join(unknown, ",") != "message.stage" {
+		t.Errorf("Expected message.stage to be ignored, got %v", unknown)
+	}
+
+	// Values that are not integers are still rejected, as are missing fields
+	_, _, err = d.DecodePayload(models.MessageTypeRocketSpeedIncreased, json.RawMessage(`{"by":"fast"}`))
+	if err == nil || err.Error() != "message.by: expected integer, got string" {
+		t.Errorf("Expected a type error, got %v", err)
+	}
+	_, _, err = d.DecodePayload(models.MessageTypeRocketExploded, json.RawMessage(`{"color":"red"}`))
+	if err == nil || err.Error() != "message.reason: required" {
+		t.Errorf("Expected a required error, got %v", err)
+	}
+
+	// Strict mode rejects the same message
+	d.SetMode(ModeStrict)
+	if _, _, err := d.DecodePayload(models.MessageTypeRocketLaunched,
+		json.RawMessage(`{"type":"Falcon-9","launchSpeed":500,"mission":"ARTEMIS","stage":2}`)); err == nil {
+		t.Errorf("Expected strict mode to reject an unknown field")
+	}
+}
+
+func TestDecodeEnvelopeLenient(t *testing.T) {
+	d := NewDecoder(Builtin(), ModeLenient, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	var env models.Envelope
+	unknown, err := d.Decode("metadata", []byte(`{"channel":"a","messageNumber":" 7","emitter":"eu-1"}`), &env.Metadata)
+	if err != nil {
+		t.Fatalf("Expected lenient decoding to succeed, got %v", err)
+	}
+	if env.Metadata.Channel != "a" || env.Metadata.MessageNumber != 7 {
+		t.Errorf("Unexpected metadata: %+v", env.Metadata)
+	}
+	if strings.Join(unknown, ",") != "metadata.emitter" {
+		t.Errorf("Expected emitter to be ignored, got %v", unknown)
+	}
+}
+
+func TestRecordUnknown(t *testing.T) {
+	d := NewDecoder(Builtin(), ModeLenient, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	d.RecordUnknown(models.MessageTypeRocketLaunched, []string{"message.stage"})
+	d.RecordUnknown(models.MessageTypeRocketLaunched, []string{"message.stage", "metadata.emitter"})
+	d.RecordUnknown(models.MessageTypeRocketExploded, nil)
+
+	counts := d.UnknownFields()
+	if len(counts) != 1 {
+		t.Fatalf("Expected one message type, got %v", counts)
+	}
+	launched := counts[models.MessageTypeRocketLaunched]
+	if launched["message.stage"] != 2 || launched["metadata.emitter"] != 1 {
+		t.Errorf("Unexpected counts: %v", launched)
+	}
+
+	// Distinct fields are capped per message type
+	for i := 0; i < maxUnknownFields*2; i++ {
+		d.RecordUnknown(models.MessageTypeRocketExploded, []string{"message.f" + string(rune('a'+i%26)) + string(rune('a'+i/26))})
+	}
+	exploded := d.UnknownFields()[models.MessageTypeRocketExploded]
+	if len(exploded) != maxUnknownFields+1 || exploded["other"] != maxUnknownFields {
+		t.Errorf("Expected %d fields plus other, got %d (other=%d)", maxUnknownFields, len(exploded), exploded["other"])
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	if mode, err := ParseMode(" Lenient "); err != nil || mode != ModeLenient {
+		t.Errorf("Expected lenient, got %q (%v)", mode, err)
+	}
+	if _, err := ParseMode("loose"); err == nil {
+		t.Errorf("Expected an unknown mode to be rejected")
+	}
+}
@@ -0,0 +1,58 @@
+package messages
+
+import (
+	"errors"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// RocketStateOverridden is the synthetic message that passes an operator's
+// correction of the rocket's type, speed and mission through the reducer. It
+// carries the complete corrected values, so applying it again gives the same
+// state; an empty type or mission keeps the rocket's own, which is empty too
+// until the rocket was launched. It is not part of Builtin, since emitters
+// cannot send it; the repository applies it outside of the emitter's message
+// numbering.
+var RocketStateOverridden = Type{
+	Name:       models.MessageTypeRocketStateOverridden,
+	NewPayload: func() Payload { return &OverriddenPayload{} },
+	Apply: func(rocket *models.RocketState, msg models.Envelope) error {
+		if msg.Message.Speed < 0 {
+			return errors.New("override requires a speed of at least 0")
+		}
+		if msg.Message.Type != "" {
+			rocket.Type = msg.Message.Type
+		}
+		rocket.Speed = msg.Message.Speed
+		if msg.Message.Mission != "" {
+			rocket.Mission = msg.Message.Mission
+		}
+		return nil
+	},
+	AppliesAfterExplosion: true,
+}
+
+// OverriddenPayload is the body of RocketStateOverridden
+type OverriddenPayload struct {
+	Type    *string `json:"type"`
+	Speed   *int    `json:"speed"`
+	Mission *string `json:"mission"`
+}
+
+// Validate requires every field, but accepts an empty type and mission since
+// a rocket has none until it was launched
+func (p *OverriddenPayload) Validate() error {
+	var errs []error
+	if p.Type == nil {
+		errs = append(errs, fieldError("type", "required"))
+	}
+	errs = append(errs, requireInt("speed", p.Speed, 0))
+	if p.Mission == nil {
+		errs = append(errs, fieldError("mission", "required"))
+	}
+	return errors.Join(errs...)
+}
+
+func (p *OverriddenPayload) Content() models.MessageContent {
+	return models.MessageContent{Type: *p.Type, Speed: *p.Speed, Mission: *p.Mission}
+}
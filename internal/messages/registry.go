@@ -0,0 +1,94 @@
+package messages
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// Type describes one message type: the schema of its payload, how the
+// payload is validated and how it changes a rocket's state
+type Type struct {
+	// Name is the value of metadata.messageType
+	Name string
+
+	// NewPayload returns a pointer to an empty payload struct to decode the
+	// message body into. Its JSON fields are the only ones the type accepts.
+	NewPayload func() Payload
+
+	// Apply is the reducer that applies the message to the rocket state.
+	// It is called with the rocket locked and in message number order.
+	Apply func(*models.RocketState, models.Envelope) error
+
+	// AppliesAfterExplosion marks types that are still applied to an exploded
+	// rocket, such as a relaunch; other types are ignored once it exploded
+	AppliesAfterExplosion bool
+}
+
+// Registry maps message type names to their definitions.
+// It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]*Type
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]*Type)}
+}
+
+// Register adds a message type. Names must be unique, and NewPayload and
+// Apply are required.
+func (r *Registry) Register(t Type) error {
+	if t.Name == "" {
+		return errors.New("message type needs a name")
+	}
+	if t.NewPayload == nil {
+		return fmt.Errorf("message type %q needs a payload schema", t.Name)
+	}
+	if t.Apply == nil {
+		return fmt.Errorf("message type %q needs an Apply reducer", t.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.types[t.Name]; exists {
+		return fmt.Errorf("message type %q is already registered", t.Name)
+	}
+	r.types[t.Name] = &t
+	return nil
+}
+
+// MustRegister is like Register but panics on error, for use at startup
+func (r *Registry) MustRegister(t Type) {
+	if err := r.Register(t); err != nil {
+		panic(err)
+	}
+}
+
+// Lookup returns the definition of a message type
+func (r *Registry) Lookup(name string) (*Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.types[name]
+	return t, ok
+}
+
+// Names returns the registered type names in alphabetical order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.types))
+	for name := range r.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ErrUnknownType is returned for message types that are not registered
+var ErrUnknownType = errors.New("unknown message type")
@@ -0,0 +1,185 @@
+package messages
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+func envelope(messageType string, content models.MessageContent) models.Envelope {
+	var env models.Envelope
+	env.Metadata.Channel = "test-rocket"
+	env.Metadata.MessageNumber = 1
+	env.Metadata.MessageTime = time.Unix(1_700_000_000, 0)
+	env.Metadata.MessageType = messageType
+	env.Message = content
+	return env
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	noop := func(*models.RocketState, models.Envelope) error { return nil }
+	payload := func() Payload { return &ExplodedPayload{} }
+
+	if err := r.Register(Type{Name: "A", NewPayload: payload, Apply: noop}); err != nil {
+		t.Fatalf("Expected registration to succeed, got %v", err)
+	}
+	if err := r.Register(Type{Name: "A", NewPayload: payload, Apply: noop}); err == nil {
+		t.Errorf("Expected duplicate names to be rejected")
+	}
+	if err := r.Register(Type{Name: "B", NewPayload: payload}); err == nil {
+		t.Errorf("Expected a type without reducer to be rejected")
+	}
+	if err := r.Register(Type{Name: "C", Apply: noop}); err == nil {
+		t.Errorf("Expected a type without payload schema to be rejected")
+	}
+	if err := r.Register(Type{Apply: noop}); err == nil {
+		t.Errorf("Expected a type without name to be rejected")
+	}
+
+	if _, err := r.Decode("Unknown", json.RawMessage(`{}`)); err != ErrUnknownType {
+		t.Errorf("Expected ErrUnknownType, got %v", err)
+	}
+
+	if names := Builtin().Names(); len(names) != 5 || names[0] != models.MessageTypeRocketExploded {
+		t.Errorf("Unexpected builtin names: %v", names)
+	}
+	if fields := RocketLaunched.Fields(); strings.Join(fields, ",") != "type,launchSpeed,mission" {
+		t.Errorf("Unexpected launch fields: %v", fields)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	r := Builtin()
+	tests := []struct {
+		name        string
+		messageType string
+		payload     string
+		expected    models.MessageContent
+		errors      []string // Expected paths and messages, in order
+	}{
+		{
+			name:        "launch",
+			messageType: models.MessageTypeRocketLaunched,
+			payload:     `{"type":"Falcon-9","launchSpeed":0,"mission":"ARTEMIS"}`,
+			expected:    models.MessageContent{Type: "Falcon-9", Mission: "ARTEMIS"},
+		},
+		{
+			name:        "launch with missing fields",
+			messageType: models.MessageTypeRocketLaunched,
+			payload:     `{"type":""}`,
+			errors:      []string{"message.type: must not be empty", "message.launchSpeed: required", "message.mission: required"},
+		},
+		{
+			name:        "negative launch speed",
+			messageType: models.MessageTypeRocketLaunched,
+			payload:     `{"type":"Falcon-9","launchSpeed":-1,"mission":"ARTEMIS"}`,
+			errors:      []string{"message.launchSpeed: must be at least 0, got -1"},
+		},
+		{
+			name:        "speed change",
+			messageType: models.MessageTypeRocketSpeedIncreased,
+			payload:     `{"by":300}`,
+			expected:    models.MessageContent{By: 300},
+		},
+		{
+			name:        "speed change without by",
+			messageType: models.MessageTypeRocketSpeedDecreased,
+			payload:     `{}`,
+			errors:      []string{"message.by: required"},
+		},
+		{
+			name:        "speed change of zero",
+			messageType: models.MessageTypeRocketSpeedIncreased,
+			payload:     `{"by":0}`,
+			errors:      []string{"message.by: must be at least 1, got 0"},
+		},
+		{
+			name:        "wrong type",
+			messageType: models.MessageTypeRocketSpeedIncreased,
+			payload:     `{"by":"fast"}`,
+			errors:      []string{"message.by: expected integer, got string"},
+		},
+		{
+			name:        "every wrong and missing field",
+			messageType: models.MessageTypeRocketLaunched,
+			payload:     `{"type":1,"launchSpeed":"fast"}`,
+			errors:      []string{"message.launchSpeed: expected integer, got string", "message.type: expected string, got number", "message.mission: required"},
+		},
+		{
+			name:        "foreign and unknown fields",
+			messageType: models.MessageTypeRocketExploded,
+			payload:     `{"reason":"PRESSURE","by":10,"color":"red"}`,
+			errors:      []string{"message.by: belongs to RocketSpeedDecreased or RocketSpeedIncreased, not RocketExploded", "message.color: unknown field"},
+		},
+		{
+			name:        "missing body",
+			messageType: models.MessageTypeRocketMissionChanged,
+			payload:     ``,
+			errors:      []string{"message.newMission: required"},
+		},
+		{
+			name:        "body not an object",
+			messageType: models.MessageTypeRocketMissionChanged,
+			payload:     `"GEMINI"`,
+			errors:      []string{"message: must be a JSON object"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := r.Decode(tt.messageType, json.RawMessage(tt.payload))
+			if len(tt.errors) == 0 {
+				if err != nil || content != tt.expected {
+					t.Errorf("Expected %+v, got %+v (%v)", tt.expected, content, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Expected errors %v, got none", tt.errors)
+			}
+			if got := strings.Split(err.Error(), "\n"); strings.Join(got, "|") != strings.Join(tt.errors, "|") {
+				t.Errorf("Expected errors %v, got %v", tt.errors, got)
+			}
+		})
+	}
+}
+
+func TestBuiltinApply(t *testing.T) {
+	rocket := &models.RocketState{ID: "test-rocket"}
+
+	steps := []struct {
+		t       Type
+		content models.MessageContent
+	}{
+		{RocketLaunched, models.MessageContent{Type: "Falcon-9", LaunchSpeed: 500, Mission: "ARTEMIS"}},
+		{RocketSpeedIncreased, models.MessageContent{By: 300}},
+		{RocketSpeedDecreased, models.MessageContent{By: 1000}},
+		{RocketMissionChanged, models.MessageContent{NewMission: "GEMINI"}},
+		{RocketExploded, models.MessageContent{Reason: "PRESSURE"}},
+	}
+	for _, step := range steps {
+		if err := step.t.Apply(rocket, envelope(step.t.Name, step.content)); err != nil {
+			t.Fatalf("%s: unexpected error %v", step.t.Name, err)
+		}
+	}
+	if rocket.Type != "Falcon-9" || rocket.Speed != 0 || rocket.Mission != "GEMINI" || !rocket.Exploded || rocket.Reason != "PRESSURE" {
+		t.Errorf("Unexpected state: %+v", rocket)
+	}
+
+	// Relaunching resets the explosion
+	if !RocketLaunched.AppliesAfterExplosion || RocketSpeedIncreased.AppliesAfterExplosion {
+		t.Errorf("Expected only launches to apply after an explosion")
+	}
+	RocketLaunched.Apply(rocket, envelope(models.MessageTypeRocketLaunched, models.MessageContent{Type: "Falcon-9", LaunchSpeed: 100, Mission: "APOLLO"}))
+	if rocket.Exploded || rocket.Reason != "" || rocket.Speed != 100 {
+		t.Errorf("Expected relaunch to reset the rocket, got %+v", rocket)
+	}
+
+	// Reducers reject payloads they cannot apply
+	if err := RocketSpeedIncreased.Apply(rocket, envelope(models.MessageTypeRocketSpeedIncreased, models.MessageContent{})); err == nil {
+		t.Errorf("Expected a speed change without amount to be rejected")
+	}
+}
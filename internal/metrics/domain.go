@@ -0,0 +1,180 @@
+package metrics
+
+import (
+	"bufio"
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// overflowLabel replaces label values beyond the cardinality cap. A mission
+// or type that happens to carry it is counted with the overflow, so that the
+// label value is never exported twice.
+const overflowLabel = "__other__"
+
+// DomainOptions configures the per-rocket gauges exporter
+type DomainOptions struct {
+	// MaxRockets caps how many rockets get per-rocket series; the most
+	// recently updated rockets win. Zero means no cap.
+	MaxRockets int
+
+	// MaxLabelValues caps distinct mission and type label values in the
+	// aggregate counts; the rest are folded into "__other__". Zero means no cap.
+	MaxLabelValues int
+
+	// StaleAfter drops per-rocket series for rockets that have not been
+	// updated for this long. Zero keeps every rocket.
+	StaleAfter time.Duration
+
+	// Timeout bounds how long a scrape waits for the repository
+	Timeout time.Duration
+}
+
+// DefaultDomainOptions returns conservative caps for the domain exporter
+func DefaultDomainOptions() DomainOptions {
+	return DomainOptions{
+		MaxRockets:     1000,
+		MaxLabelValues: 100,
+		Timeout:        5 * time.Second,
+	}
+}
+
+// DomainGauges exports rocket state as gauges. Series are rebuilt from a
+// repository snapshot on every scrape, so rockets removed from the repository
+// stop being exported immediately.
+type DomainGauges struct {
+	repo storage.RocketRepository
+	opts atomic.Pointer[DomainOptions]
+	now  func() time.Time
+}
+
+// RegisterDomainGauges adds per-rocket and per-mission/type gauges to the registry
+func (m *Metrics) RegisterDomainGauges(repo storage.RocketRepository, opts DomainOptions) *DomainGauges {
+	g := &DomainGauges{repo: repo, now: time.Now}
+	g.SetOptions(opts)
+	m.Registry.register(g)
+	return g
+}
+
+// SetOptions replaces the caps used from the next scrape on
+func (g *DomainGauges) SetOptions(opts DomainOptions) {
+	g.opts.Store(&opts)
+}
+
+func (g *DomainGauges) write(w *bufio.Writer) {
+	opts := *g.opts.Load()
+
+	ctx := context.Background()
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	rockets, err := g.repo.ListRockets(ctx, "updatedat", "desc")
+	if err != nil {
+		// Skip the domain families rather than exporting a partial snapshot
+		return
+	}
+
+	var speed, exploded []Sample
+	dropped := 0
+	stale := 0
+	for _, rocket := range rockets {
+		if opts.StaleAfter > 0 && g.now().Sub(rocket.UpdatedAt) > opts.StaleAfter {
+			stale++
+			continue
+		}
+		if opts.MaxRockets > 0 && len(speed) >= opts.MaxRockets {
+			dropped++
+			continue
+		}
+
+		labels := []string{rocket.ID, rocket.Type, rocket.Mission}
+		speed = append(speed, Sample{LabelValues: labels, Value: float64(rocket.Speed)})
+		exploded = append(exploded, Sample{LabelValues: labels, Value: boolValue(rocket.Status == "exploded")})
+	}
+
+	families := []*gaugeVecFunc{
+		{
+			name: "lunar_rocket_speed", help: "Current speed of each rocket.",
+			labels: []string{"id", "type", "mission"},
+			fn:     func() []Sample { return speed },
+		},
+		{
+			name: "lunar_rocket_exploded", help: "Whether each rocket has exploded (1) or not (0).",
+			labels: []string{"id", "type", "mission"},
+			fn:     func() []Sample { return exploded },
+		},
+		{
+			name: "lunar_rockets_by_mission", help: "Number of rockets per mission.",
+			labels: []string{"mission"},
+			fn: func() []Sample {
+				return countBy(rockets, opts.MaxLabelValues, func(r models.RocketSummary) string { return r.Mission })
+			},
+		},
+		{
+			name: "lunar_rockets_by_type", help: "Number of rockets per rocket type.",
+			labels: []string{"type"},
+			fn: func() []Sample {
+				return countBy(rockets, opts.MaxLabelValues, func(r models.RocketSummary) string { return r.Type })
+			},
+		},
+		{
+			name: "lunar_rocket_series_dropped", help: "Rockets left out of per-rocket series by the cardinality cap.",
+			fn: func() []Sample { return []Sample{{Value: float64(dropped)}} },
+		},
+		{
+			name: "lunar_rocket_series_stale", help: "Rockets left out of per-rocket series because they are stale.",
+			fn: func() []Sample { return []Sample{{Value: float64(stale)}} },
+		},
+	}
+	for _, family := range families {
+		family.write(w)
+	}
+}
+
+// countBy counts rockets per label value, folding values beyond the cap into overflowLabel
+func countBy(rockets []models.RocketSummary, maxValues int, label func(models.RocketSummary) string) []Sample {
+	counts := make(map[string]int)
+	for _, rocket := range rockets {
+		counts[label(rocket)]++
+	}
+
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	// Keep the largest groups when capping, ties broken by name
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+
+	samples := make([]Sample, 0, len(values))
+	overflow := 0
+	for _, value := range values {
+		if value == overflowLabel || maxValues > 0 && len(samples) >= maxValues {
+			overflow += counts[value]
+			continue
+		}
+		samples = append(samples, Sample{LabelValues: []string{value}, Value: float64(counts[value])})
+	}
+	if overflow > 0 {
+		samples = append(samples, Sample{LabelValues: []string{overflowLabel}, Value: float64(overflow)})
+	}
+	return samples
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,105 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+func launchRocket(t *testing.T, repo storage.RocketRepository, id, rocketType, mission string, speed int, at time.Time) {
+	t.Helper()
+
+	var env models.Envelope
+	env.Metadata.Channel = id
+	env.Metadata.MessageNumber = 1
+	env.Metadata.MessageTime = at
+	env.Metadata.MessageType = models.MessageTypeRocketLaunched
+	env.Message.Type = rocketType
+	env.Message.Mission = mission
+	env.Message.LaunchSpeed = speed
+	if outcome := repo.ProcessMessage(context.Background(), env); outcome.Status != storage.StatusApplied {
+		t.Fatalf("Expected launch to be applied, got %q", outcome.Status)
+	}
+}
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+
+	var sb strings.Builder
+	if err := m.Registry.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	return sb.String()
+}
+
+func TestDomainGauges(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	now := time.Now()
+	launchRocket(t, repo, "r1", "Falcon-9", "ARTEMIS", 500, now)
+	launchRocket(t, repo, "r2", "Falcon-9", "ARTEMIS", 700, now.Add(time.Second))
+	launchRocket(t, repo, "r3", "Saturn-V", "APOLLO", 900, now.Add(2*time.Second))
+
+	m := NewMetrics()
+	m.RegisterDomainGauges(repo, DomainOptions{MaxRockets: 2, MaxLabelValues: 1})
+	out := scrape(t, m)
+
+	expected := []string{
+		`lunar_rocket_speed{id="r3",type="Saturn-V",mission="APOLLO"} 900`,
+		`lunar_rocket_speed{id="r2",type="Falcon-9",mission="ARTEMIS"} 700`,
+		`lunar_rocket_exploded{id="r3",type="Saturn-V",mission="APOLLO"} 0`,
+		`lunar_rockets_by_mission{mission="ARTEMIS"} 2`,
+		`lunar_rockets_by_mission{mission="__other__"} 1`,
+		`lunar_rockets_by_type{type="Falcon-9"} 2`,
+		`lunar_rocket_series_dropped 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, `id="r1"`) {
+		t.Errorf("Expected the oldest rocket to be dropped by the cap")
+	}
+}
+
+func TestDomainGaugesStaleness(t *testing.T) {
+	repo := storage.NewInMemoryRepository()
+	now := time.Now()
+	launchRocket(t, repo, "fresh", "Falcon-9", "ARTEMIS", 500, now)
+	launchRocket(t, repo, "idle", "Falcon-9", "ARTEMIS", 500, now.Add(-time.Hour))
+
+	m := NewMetrics()
+	m.RegisterDomainGauges(repo, DomainOptions{StaleAfter: time.Minute})
+	out := scrape(t, m)
+
+	if !strings.Contains(out, `id="fresh"`) || strings.Contains(out, `id="idle"`) {
+		t.Errorf("Expected only the fresh rocket to be exported, got:\n%s", out)
+	}
+	if !strings.Contains(out, "lunar_rocket_series_stale 1\n") {
+		t.Errorf("Expected one stale rocket to be reported")
+	}
+}
+
+func TestCountByOverflowLabel(t *testing.T) {
+	rockets := []models.RocketSummary{
+		{Mission: "ARTEMIS"}, {Mission: "ARTEMIS"}, {Mission: "other"}, {Mission: "APOLLO"}, {Mission: overflowLabel},
+	}
+	samples := countBy(rockets, 2, func(r models.RocketSummary) string { return r.Mission })
+
+	// A mission named like the overflow bucket is folded into it, so that
+	// every label value appears once
+	seen := make(map[string]float64)
+	for _, sample := range samples {
+		if _, ok := seen[sample.LabelValues[0]]; ok {
+			t.Errorf("Expected %q to be exported once, got %+v", sample.LabelValues[0], samples)
+		}
+		seen[sample.LabelValues[0]] = sample.Value
+	}
+	if seen["ARTEMIS"] != 2 || seen["APOLLO"] != 1 || seen[overflowLabel] != 2 {
+		t.Errorf("Unexpected samples: %+v", samples)
+	}
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+func TestRegistryTextFormat(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.NewCounterVec("test_total", "A test counter.", "kind")
+	histogram := reg.NewHistogramVec("test_seconds", "A test histogram.", []float64{0.1, 1}, "op")
+	reg.NewGaugeFunc("test_gauge", "A test gauge.", func() float64 { return 42 })
+
+	counter.Inc(`a"b`)
+	counter.Add(2, "plain")
+	histogram.Observe(0.05, "read")
+	histogram.Observe(0.5, "read")
+	histogram.Observe(5, "read")
+
+	var sb strings.Builder
+	if err := reg.WriteText(&sb); err != nil {
+		t.Fatalf("WriteText failed: %v", err)
+	}
+	out := sb.String()
+
+	expected := []string{
+		"# TYPE test_total counter",
+		`test_total{kind="a\"b"} 1`,
+		`test_total{kind="plain"} 2`,
+		"# TYPE test_seconds histogram",
+		`test_seconds_bucket{op="read",le="0.1"} 1`,
+		`test_seconds_bucket{op="read",le="1"} 2`,
+		`test_seconds_bucket{op="read",le="+Inf"} 3`,
+		`test_seconds_sum{op="read"} 5.55`,
+		`test_seconds_count{op="read"} 3`,
+		"# TYPE test_gauge gauge",
+		"test_gauge 42",
+	}
+	for _, line := range expected {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("Expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestMiddlewareRecordsRoutePattern(t *testing.T) {
+	m := NewMetrics()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /rockets/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	mux.HandleFunc("GET /metrics", m.Handler())
+
+	server := httptest.NewServer(m.Middleware(mux))
+	defer server.Close()
+
+	for _, id := range []string{"a", "b"} {
+		resp, err := http.Get(server.URL + "/rockets/" + id)
+		if err != nil {
+			t.Fatalf("Error making request: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	if got := m.httpRequests.Value("GET /rockets/{id}", "GET", "404"); got != 2 {
+		t.Errorf("Expected 2 requests recorded for the route pattern, got %v", got)
+	}
+	if got := m.httpDuration.Count("GET /rockets/{id}", "GET"); got != 2 {
+		t.Errorf("Expected 2 latency observations, got %d", got)
+	}
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("Error making request: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("Expected text/plain content type, got %q", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), "go_goroutines ") {
+		t.Errorf("Expected runtime metrics in output")
+	}
+	for name, kind := range map[string]string{
+		"go_memstats_alloc_bytes":      "gauge",
+		"go_memstats_heap_inuse_bytes": "gauge",
+		"go_memstats_sys_bytes":        "gauge",
+		"go_memstats_num_gc":           "counter",
+	} {
+		if !strings.Contains(string(body), "# TYPE "+name+" "+kind+"\n"+name+" ") {
+			t.Errorf("Expected the %s %s in output", name, kind)
+		}
+	}
+}
+
+func TestRepositoryObserver(t *testing.T) {
+	m := NewMetrics()
+	repo := storage.NewInMemoryRepository()
+	repo.SetObserver(m)
+	m.RegisterRocketCount(func() int {
+		count, _ := repo.RocketCount(context.Background())
+		return count
+	})
+	m.RegisterDeadLetters(func() int { return 2 })
+
+	var env models.Envelope
+	env.Metadata.Channel = "metrics-rocket"
+	env.Metadata.MessageNumber = 2
+	env.Metadata.MessageTime = time.Now()
+	env.Metadata.MessageType = models.MessageTypeRocketSpeedIncreased
+	env.Message.By = 10
+	repo.ProcessMessage(context.Background(), env)
+	repo.ProcessMessage(context.Background(), env)
+
+	if got := m.ingestion.Value(models.MessageTypeRocketSpeedIncreased, string(storage.StatusBuffered)); got != 1 {
+		t.Errorf("Expected 1 buffered message, got %v", got)
+	}
+	if got := m.ingestion.Value(models.MessageTypeRocketSpeedIncreased, string(storage.StatusDuplicate)); got != 1 {
+		t.Errorf("Expected 1 duplicate message, got %v", got)
+	}
+	if got := m.bufferDepth.Count(); got != 2 {
+		t.Errorf("Expected 2 buffer depth observations, got %d", got)
+	}
+	if got := m.lockWait.Count(storage.LockRocket); got == 0 {
+		t.Errorf("Expected rocket lock waits to be observed")
+	}
+
+	var sb strings.Builder
+	m.Registry.WriteText(&sb)
+	if !strings.Contains(sb.String(), "lunar_rockets 1\n") {
+		t.Errorf("Expected lunar_rockets gauge to report 1 rocket")
+	}
+	if !strings.Contains(sb.String(), "lunar_dead_letters 2\n") {
+		t.Errorf("Expected lunar_dead_letters gauge to report 2 letters")
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	m := NewMetrics()
+	m.RegisterUnknownFields(func() map[string]map[string]int64 {
+		return map[string]map[string]int64{
+			models.MessageTypeRocketLaunched: {"message.stage": 3, "metadata.region": 1},
+		}
+	})
+
+	var sb strings.Builder
+	m.Registry.WriteText(&sb)
+	for _, expected := range []string{
+		`lunar_unknown_fields{message_type="RocketLaunched",field="message.stage"} 3`,
+		`lunar_unknown_fields{message_type="RocketLaunched",field="metadata.region"} 1`,
+	} {
+		if !strings.Contains(sb.String(), expected) {
+			t.Errorf("Expected %q in output", expected)
+		}
+	}
+}
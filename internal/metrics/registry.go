@@ -0,0 +1,334 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collector is anything that can write itself in Prometheus text format
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+// Registry holds a set of metrics and renders them in the Prometheus
+// text exposition format
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// WriteText writes all registered metrics to w
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler returns an http.HandlerFunc that serves the registry
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.WriteText(w)
+	}
+}
+
+// CounterVec is a set of counters partitioned by label values
+type CounterVec struct {
+	name   string
+	help   string
+	labels []string
+
+	mu     sync.Mutex
+	values map[string]*counterValue
+}
+
+type counterValue struct {
+	labelValues []string
+	value       float64
+}
+
+// NewCounterVec creates and registers a counter with the given label names
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{name: name, help: help, labels: labels, values: make(map[string]*counterValue)}
+	r.register(c)
+	return c
+}
+
+// Inc increments the counter for the given label values by one
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add increments the counter for the given label values by delta
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := labelKey(labelValues)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.values[key]
+	if !ok {
+		v = &counterValue{labelValues: append([]string(nil), labelValues...)}
+		c.values[key] = v
+	}
+	v.value += delta
+}
+
+// Value returns the current value for the given label values
+func (c *CounterVec) Value(labelValues ...string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if v, ok := c.values[labelKey(labelValues)]; ok {
+		return v.value
+	}
+	return 0
+}
+
+func (c *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, c.name, c.help, "counter")
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		writeSample(w, c.name, formatLabels(c.labels, v.labelValues), v.value)
+	}
+}
+
+// HistogramVec is a set of histograms partitioned by label values
+type HistogramVec struct {
+	name    string
+	help    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	values map[string]*histogramValue
+}
+
+type histogramValue struct {
+	labelValues []string
+	counts      []uint64 // Per bucket, not cumulative
+	count       uint64
+	sum         float64
+}
+
+// DefaultDurationBuckets are the bucket bounds, in seconds, used for latencies
+var DefaultDurationBuckets = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// NewHistogramVec creates and registers a histogram with the given bucket
+// upper bounds and label names
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{name: name, help: help, labels: labels, buckets: sorted, values: make(map[string]*histogramValue)}
+	r.register(h)
+	return h
+}
+
+// Observe records a single observation for the given label values
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := labelKey(labelValues)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	v, ok := h.values[key]
+	if !ok {
+		v = &histogramValue{
+			labelValues: append([]string(nil), labelValues...),
+			counts:      make([]uint64, len(h.buckets)),
+		}
+		h.values[key] = v
+	}
+
+	for i, bound := range h.buckets {
+		if value <= bound {
+			v.counts[i]++
+			break
+		}
+	}
+	v.count++
+	v.sum += value
+}
+
+// Count returns the number of observations for the given label values
+func (h *HistogramVec) Count(labelValues ...string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if v, ok := h.values[labelKey(labelValues)]; ok {
+		return v.count
+	}
+	return 0
+}
+
+func (h *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, h.name, h.help, "histogram")
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	bucketLabels := append(append([]string(nil), h.labels...), "le")
+	for _, key := range sortedKeys(h.values) {
+		v := h.values[key]
+
+		var cumulative uint64
+		for i, bound := range h.buckets {
+			cumulative += v.counts[i]
+			values := append(append([]string(nil), v.labelValues...), formatFloat(bound))
+			writeSample(w, h.name+"_bucket", formatLabels(bucketLabels, values), float64(cumulative))
+		}
+		values := append(append([]string(nil), v.labelValues...), "+Inf")
+		writeSample(w, h.name+"_bucket", formatLabels(bucketLabels, values), float64(v.count))
+
+		labels := formatLabels(h.labels, v.labelValues)
+		writeSample(w, h.name+"_sum", labels, v.sum)
+		writeSample(w, h.name+"_count", labels, float64(v.count))
+	}
+}
+
+// gaugeFunc is a gauge whose value is computed at scrape time
+type gaugeFunc struct {
+	name string
+	help string
+	fn   func() float64
+}
+
+// NewGaugeFunc creates and registers a gauge that calls fn on every scrape
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&gaugeFunc{name: name, help: help, fn: fn})
+}
+
+func (g *gaugeFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	writeSample(w, g.name, "", g.fn())
+}
+
+// Sample is a single labelled value returned by a gauge vector function
+type Sample struct {
+	LabelValues []string
+	Value       float64
+}
+
+// gaugeVecFunc is a labelled gauge whose samples are computed at scrape time
+type gaugeVecFunc struct {
+	name   string
+	help   string
+	labels []string
+	fn     func() []Sample
+}
+
+// NewGaugeVecFunc creates and registers a labelled gauge that calls fn on every scrape.
+// Series that fn stops returning disappear from the output.
+func (r *Registry) NewGaugeVecFunc(name, help string, fn func() []Sample, labels ...string) {
+	r.register(&gaugeVecFunc{name: name, help: help, labels: labels, fn: fn})
+}
+
+func (g *gaugeVecFunc) write(w *bufio.Writer) {
+	writeHeader(w, g.name, g.help, "gauge")
+	for _, s := range g.fn() {
+		writeSample(w, g.name, formatLabels(g.labels, s.LabelValues), s.Value)
+	}
+}
+
+// Helper functions for the text format
+
+// labelKey joins label values into a map key
+func labelKey(values []string) string {
+	return strings.Join(values, "\xff")
+}
+
+// sortedKeys returns map keys in a stable order so output is deterministic
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(w *bufio.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w *bufio.Writer, name, labels string, value float64) {
+	w.WriteString(name)
+	w.WriteString(labels)
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+// formatLabels renders {name="value",...} or an empty string without labels
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		sb.WriteString(name)
+		sb.WriteString(`="`)
+		sb.WriteString(escapeLabelValue(value))
+		sb.WriteByte('"')
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabelValue(s string) string { return labelValueEscaper.Replace(s) }
+func escapeHelp(s string) string       { return helpEscaper.Replace(s) }
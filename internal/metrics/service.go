@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"bufio"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/rah-0/lunar/internal/signing"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// Metrics groups the operational metrics exposed by the service.
+// It implements storage.Observer so it can be installed on a repository,
+// and signing.Observer so it can count signature verifications.
+type Metrics struct {
+	Registry *Registry
+
+	httpRequests *CounterVec
+	httpDuration *HistogramVec
+	ingestion    *CounterVec
+	bufferDepth  *HistogramVec
+	lockWait     *HistogramVec
+	signatures   *CounterVec
+}
+
+// bufferDepthBuckets are the bucket bounds for buffered message counts
+var bufferDepthBuckets = []float64{0, 1, 2, 5, 10, 25, 50, 100, 250, 500, 1000}
+
+// NewMetrics creates the service metrics together with Go runtime gauges
+func NewMetrics() *Metrics {
+	reg := NewRegistry()
+	m := &Metrics{
+		Registry: reg,
+		httpRequests: reg.NewCounterVec("lunar_http_requests_total",
+			"Total HTTP requests by route, method and status code.", "route", "method", "code"),
+		httpDuration: reg.NewHistogramVec("lunar_http_request_duration_seconds",
+			"HTTP request latency by route and method.", DefaultDurationBuckets, "route", "method"),
+		ingestion: reg.NewCounterVec("lunar_ingested_messages_total",
+			"Messages processed by message type and outcome.", "message_type", "status"),
+		bufferDepth: reg.NewHistogramVec("lunar_buffer_depth",
+			"Buffered out-of-order messages for a rocket after each processed message.", bufferDepthBuckets),
+		lockWait: reg.NewHistogramVec("lunar_lock_wait_seconds",
+			"Time spent waiting to acquire repository locks.", DefaultDurationBuckets, "lock"),
+		signatures: reg.NewCounterVec("lunar_signature_verifications_total",
+			"Message signature verifications by result and mode.", "result", "mode"),
+	}
+	registerRuntimeGauges(reg)
+	return m
+}
+
+// RegisterRocketCount adds a gauge reporting the number of tracked rockets
+func (m *Metrics) RegisterRocketCount(count func() int) {
+	m.Registry.NewGaugeFunc("lunar_rockets", "Number of rockets tracked by the repository.", func() float64 {
+		return float64(count())
+	})
+}
+
+// RegisterDeadLetters adds a gauge reporting the number of messages kept in
+// the dead-letter store
+func (m *Metrics) RegisterDeadLetters(count func() int) {
+	m.Registry.NewGaugeFunc("lunar_dead_letters", "Messages that could not be processed, kept for inspection and resubmission.", func() float64 {
+		return float64(count())
+	})
+}
+
+// RegisterUnknownFields adds a gauge reporting how many messages carried each
+// field ignored by lenient decoding, per message type
+func (m *Metrics) RegisterUnknownFields(counts func() map[string]map[string]int64) {
+	m.Registry.NewGaugeVecFunc("lunar_unknown_fields",
+		"Messages seen with a field ignored by lenient decoding, by message type and field.", func() []Sample {
+			var samples []Sample
+			byType := counts()
+			for _, messageType := range sortedKeys(byType) {
+				for _, field := range sortedKeys(byType[messageType]) {
+					samples = append(samples, Sample{
+						LabelValues: []string{messageType, field},
+						Value:       float64(byType[messageType][field]),
+					})
+				}
+			}
+			return samples
+		}, "message_type", "field")
+}
+
+// Handler serves the metrics in Prometheus text format
+// @Summary Prometheus metrics
+// @Description Returns the service and domain metrics in the Prometheus text exposition format
+// @Tags system
+// @Produce plain
+// @Success 200 {string} string "Metrics in the Prometheus text format"
+// @Failure 401 {object} problem.Problem "unauthorized"
+// @Failure 403 {object} problem.Problem "forbidden when the API key lacks the read scope"
+// @Security ApiKeyAuth
+// @Router /metrics [get]
+func (m *Metrics) Handler() http.HandlerFunc {
+	return m.Registry.Handler()
+}
+
+// Middleware records request counts and latencies per route pattern
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sw, r)
+
+		// ServeMux sets the matched pattern on the request; unmatched
+		// requests share one label value to keep cardinality bounded
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		m.httpRequests.Inc(route, r.Method, strconv.Itoa(sw.status))
+		m.httpDuration.Observe(time.Since(start).Seconds(), route, r.Method)
+	})
+}
+
+// ObserveLockWait implements storage.Observer
+func (m *Metrics) ObserveLockWait(lock string, wait time.Duration) {
+	m.lockWait.Observe(wait.Seconds(), lock)
+}
+
+// ObserveOutcome implements storage.Observer
+func (m *Metrics) ObserveOutcome(messageType string, outcome storage.ProcessOutcome) {
+	m.ingestion.Inc(messageType, string(outcome.Status))
+}
+
+// ObserveBufferDepth implements storage.Observer
+func (m *Metrics) ObserveBufferDepth(depth int) {
+	m.bufferDepth.Observe(float64(depth))
+}
+
+// ObserveSignature implements signing.Observer
+func (m *Metrics) ObserveSignature(result signing.Result, mode signing.Mode) {
+	m.signatures.Inc(string(result), string(mode))
+}
+
+// registerRuntimeGauges adds goroutine and memory statistics
+func registerRuntimeGauges(reg *Registry) {
+	reg.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+
+	reg.register(memStatsCollector{})
+}
+
+// memStatsCollector writes the memory metrics from a single read of the
+// memory statistics per scrape, since reading them stops the world
+type memStatsCollector struct{}
+
+func (memStatsCollector) write(w *bufio.Writer) {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	for _, metric := range []struct {
+		name, help, kind string
+		value            uint64
+	}{
+		{"go_memstats_alloc_bytes", "Bytes of allocated heap objects.", "gauge", ms.Alloc},
+		{"go_memstats_heap_inuse_bytes", "Bytes in in-use heap spans.", "gauge", ms.HeapInuse},
+		{"go_memstats_sys_bytes", "Bytes of memory obtained from the OS.", "gauge", ms.Sys},
+		{"go_memstats_num_gc", "Number of completed GC cycles.", "counter", uint64(ms.NumGC)},
+	} {
+		writeHeader(w, metric.name, metric.help, metric.kind)
+		writeSample(w, metric.name, "", float64(metric.value))
+	}
+}
+
+// statusWriter captures the status code written by a handler
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -47,6 +47,9 @@ type MessageContent struct {
 
 	// RocketMissionChanged fields
 	NewMission string `json:"newMission,omitempty"`
+
+	// RocketStateOverridden fields, with type and mission
+	Speed int `json:"speed,omitempty"`
 }
 
 // Message types constants
@@ -56,6 +59,9 @@ const (
 	MessageTypeRocketSpeedDecreased = "RocketSpeedDecreased"
 	MessageTypeRocketExploded       = "RocketExploded"
 	MessageTypeRocketMissionChanged = "RocketMissionChanged"
+
+	// Synthetic type recording an operator's correction; emitters cannot send it
+	MessageTypeRocketStateOverridden = "RocketStateOverridden"
 )
 
 // Rocket status constants
@@ -16,11 +16,26 @@ type RocketState struct {
 	Reason    string       `json:"reason,omitempty"` // Reason for explosion, if applicable
 	UpdatedAt time.Time    `json:"updatedAt"`        // Last updated time
 	CreatedAt time.Time    `json:"createdAt"`        // Time when the rocket was first launched
+	Version   uint64       `json:"version"`          // Incremented on every applied update
+	Paused    bool         `json:"paused,omitempty"` // Whether an operator paused ingestion; messages are held until resumed
+
+	// Latest operator correction of each corrected field, keyed by field name
+	Overrides map[string]FieldOverride `json:"overrides,omitempty"`
 
 	// Used for internal message ordering
 	LastProcessedMessageNumber int `json:"-"`
 }
 
+// FieldOverride records an operator's correction of a rocket field
+type FieldOverride struct {
+	Value    any       `json:"value"`            // Value set by the operator
+	Previous any       `json:"previous"`         // Value before the correction
+	Actor    string    `json:"actor"`            // Name of the API key that made the correction
+	Reason   string    `json:"reason,omitempty"` // Why the emitter's value was wrong
+	At       time.Time `json:"at"`               // Time of the correction
+	Version  uint64    `json:"version"`          // Version produced by the correction
+}
+
 // RocketSummary is a simplified version of RocketState for listing purposes
 type RocketSummary struct {
 	ID        string    `json:"id"`
@@ -29,4 +44,12 @@ type RocketSummary struct {
 	Mission   string    `json:"mission"`
 	Status    string    `json:"status"`
 	UpdatedAt time.Time `json:"updatedAt"`
+	Version   uint64    `json:"version"`
+}
+
+// QuarantinedMessage is a message set aside because its rocket exploded
+type QuarantinedMessage struct {
+	Envelope      Envelope  `json:"envelope"`
+	Reason        string    `json:"reason" enums:"arrived_after_explosion,buffered_at_explosion"` // Why the message was set aside
+	QuarantinedAt time.Time `json:"quarantinedAt"`
 }
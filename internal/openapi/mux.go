@@ -0,0 +1,100 @@
+// Package openapi serves an OpenAPI document built from the routes that are
+// actually registered, so the published spec cannot drift from the server.
+package openapi
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// Router is the part of http.ServeMux that route groups register on
+type Router interface {
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+// Route is a registered route in OpenAPI form
+type Route struct {
+	Method string // Lowercase HTTP method, like get
+	Path   string // Path template, like /rockets/{id}
+}
+
+// Mux is an http.ServeMux that records its routes and serves their
+// documentation at /openapi.json
+type Mux struct {
+	*http.ServeMux
+
+	doc    string // Generated document the operations and models come from
+	mu     sync.Mutex
+	routes []Route
+}
+
+// NewMux creates a mux documenting its routes with the operations and
+// definitions of doc, a Swagger 2.0 document generated from annotations
+func NewMux(doc string) *Mux {
+	m := &Mux{ServeMux: http.NewServeMux(), doc: doc}
+	m.HandleFunc("GET /openapi.json", m.HandleSpec)
+	return m
+}
+
+// Handle registers handler for pattern and records the route
+func (m *Mux) Handle(pattern string, handler http.Handler) {
+	m.ServeMux.Handle(pattern, handler)
+	m.record(pattern)
+}
+
+// HandleFunc registers handler for pattern and records the route
+func (m *Mux) HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request)) {
+	m.ServeMux.HandleFunc(pattern, handler)
+	m.record(pattern)
+}
+
+// Routes returns the registered routes in registration order
+func (m *Mux) Routes() []Route {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Route(nil), m.routes...)
+}
+
+// record adds the route of pattern
+func (m *Mux) record(pattern string) {
+	route := ParsePattern(pattern)
+	m.mu.Lock()
+	m.routes = append(m.routes, route)
+	m.mu.Unlock()
+}
+
+// ParsePattern converts a ServeMux pattern to a route. Wildcards lose their
+// "..." suffix, a trailing {$} is dropped, and patterns without a method are
+// recorded as GET.
+func ParsePattern(pattern string) Route {
+	method, path, found := strings.Cut(pattern, " ")
+	if !found {
+		method, path = http.MethodGet, pattern
+	}
+	path = strings.TrimSpace(path)
+	if i := strings.Index(path, "/"); i > 0 {
+		path = path[i:] // Drop the host
+	}
+	path = strings.TrimSuffix(path, "{$}")
+	path = strings.ReplaceAll(path, "...}", "}")
+	return Route{Method: strings.ToLower(method), Path: path}
+}
+
+// HandleSpec serves the OpenAPI document of the registered routes
+// @Summary OpenAPI document
+// @Description Returns the Swagger 2.0 document of every route registered on the server, with the models they use. Routes without annotations are listed with x-undocumented set.
+// @Tags docs
+// @Produce json
+// @Success 200 {object} map[string]any "OpenAPI document"
+// @Failure 500 {string} string "the generated document could not be read"
+// @Router /openapi.json [get]
+func (m *Mux) HandleSpec(w http.ResponseWriter, r *http.Request) {
+	spec, err := Build(m.doc, m.Routes())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
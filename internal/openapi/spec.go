@@ -0,0 +1,68 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// pathParam matches the parameters of a path template
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+// Build returns a copy of doc whose paths are exactly routes. Operations
+// are taken from doc when annotated, and generated otherwise, so that the
+// document never lists a route that is not served nor misses one that is.
+func Build(doc string, routes []Route) ([]byte, error) {
+	var spec map[string]any
+	if err := json.Unmarshal([]byte(doc), &spec); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	documented, _ := spec["paths"].(map[string]any)
+
+	paths := make(map[string]any)
+	for _, route := range routes {
+		operations, ok := paths[route.Path].(map[string]any)
+		if !ok {
+			operations = make(map[string]any)
+			paths[route.Path] = operations
+		}
+		if annotated, ok := documented[route.Path].(map[string]any); ok && annotated[route.Method] != nil {
+			operations[route.Method] = annotated[route.Method]
+		} else {
+			operations[route.Method] = undocumented(route)
+		}
+	}
+	spec["paths"] = paths
+
+	// The template leaves the host and base path empty when they are not
+	// configured, which is not valid
+	for _, key := range []string{"host", "basePath"} {
+		if value, _ := spec[key].(string); value == "" {
+			delete(spec, key)
+		}
+	}
+	return json.MarshalIndent(spec, "", "    ")
+}
+
+// undocumented generates an operation for a route without annotations
+func undocumented(route Route) map[string]any {
+	var parameters []any
+	for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+		parameters = append(parameters, map[string]any{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"type":     "string",
+		})
+	}
+	operation := map[string]any{
+		"summary":        strings.ToUpper(route.Method) + " " + route.Path,
+		"responses":      map[string]any{"default": map[string]any{"description": "Undocumented"}},
+		"x-undocumented": true,
+	}
+	if parameters != nil {
+		operation["parameters"] = parameters
+	}
+	return operation
+}
@@ -0,0 +1,73 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    Route
+	}{
+		{"GET /rockets/{id}", Route{"get", "/rockets/{id}"}},
+		{"POST /messages", Route{"post", "/messages"}},
+		{"GET /swagger/{path...}", Route{"get", "/swagger/{path}"}},
+		{"GET /{$}", Route{"get", "/"}},
+		{"/health", Route{"get", "/health"}},
+		{"GET example.com/health", Route{"get", "/health"}},
+	}
+	for _, tt := range tests {
+		if got := ParsePattern(tt.pattern); got != tt.want {
+			t.Errorf("ParsePattern(%q) = %+v, want %+v", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	doc := `{
+		"swagger": "2.0",
+		"host": "",
+		"basePath": "",
+		"paths": {
+			"/rockets": {"get": {"summary": "List rockets"}},
+			"/removed": {"get": {"summary": "Not served anymore"}}
+		},
+		"definitions": {"models.Rocket": {"type": "object"}}
+	}`
+	out, err := Build(doc, []Route{{"get", "/rockets"}, {"delete", "/rockets/{id}"}})
+	if err != nil {
+		t.Fatalf("Build failed: %v", err)
+	}
+
+	var spec struct {
+		Host        *string                              `json:"host"`
+		BasePath    *string                              `json:"basePath"`
+		Paths       map[string]map[string]map[string]any `json:"paths"`
+		Definitions map[string]any                       `json:"definitions"`
+	}
+	if err := json.Unmarshal(out, &spec); err != nil {
+		t.Fatalf("Invalid document: %v", err)
+	}
+
+	if spec.Paths["/rockets"]["get"]["summary"] != "List rockets" {
+		t.Errorf("Expected the annotated operation, got %v", spec.Paths["/rockets"])
+	}
+	if _, ok := spec.Paths["/removed"]; ok {
+		t.Error("Expected operations without a route to be dropped")
+	}
+	generated := spec.Paths["/rockets/{id}"]["delete"]
+	if generated["x-undocumented"] != true || len(generated["parameters"].([]any)) != 1 {
+		t.Errorf("Expected a generated operation with the id parameter, got %v", generated)
+	}
+	if spec.Definitions["models.Rocket"] == nil {
+		t.Error("Expected the definitions to be kept")
+	}
+	if spec.Host != nil || spec.BasePath != nil {
+		t.Error("Expected the empty host and base path to be removed")
+	}
+
+	if _, err := Build("not json", nil); err == nil {
+		t.Error("Expected an invalid document to fail")
+	}
+}
@@ -0,0 +1,113 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// ContentType is the media type of problem details (RFC 7807)
+const ContentType = "application/problem+json"
+
+// Code identifies a kind of problem. Codes are stable, so clients can
+// switch on them instead of matching messages.
+type Code string
+
+// Code constants
+const (
+	CodeInvalidPayload     Code = "invalid_payload"      // Body is not a JSON object
+	CodeValidationFailed   Code = "validation_failed"    // One or more fields are invalid, see errors
+	CodeUnknownMessageType Code = "unknown_message_type" // metadata.messageType is not registered
+	CodeInvalidRequest     Code = "invalid_request"      // Path or query parameters are invalid
+	CodeInvalidSignature   Code = "invalid_signature"    // Message signature missing or invalid in enforce mode
+	CodeUnauthorized       Code = "unauthorized"         // API key missing or invalid
+	CodeForbidden          Code = "forbidden"            // API key lacks the required scope
+	CodeChannelForbidden   Code = "channel_forbidden"    // Client may not post to the channel under its certificate
+	CodeNotFound           Code = "not_found"            // Resource does not exist
+	CodePayloadTooLarge    Code = "payload_too_large"    // Body exceeds the size limit
+	CodeRateLimited        Code = "rate_limited"         // Client or channel rate limit exceeded
+	CodeConfigRejected     Code = "config_rejected"      // Reloaded configuration is invalid
+	CodeVersionMismatch    Code = "version_mismatch"     // If-Match does not name the current version
+	CodeVersionRequired    Code = "version_required"     // If-Match is required but missing
+	CodeInternal           Code = "internal_error"       // Unexpected server error
+)
+
+// Entry describes a problem code in the catalogue
+type Entry struct {
+	Code   Code   `json:"code"`
+	Status int    `json:"status"`
+	Title  string `json:"title"`
+}
+
+// Catalogue lists every problem code with its HTTP status and title
+var Catalogue = []Entry{
+	{CodeInvalidPayload, http.StatusBadRequest, "Invalid request payload"},
+	{CodeValidationFailed, http.StatusBadRequest, "Validation failed"},
+	{CodeUnknownMessageType, http.StatusBadRequest, "Unknown message type"},
+	{CodeInvalidRequest, http.StatusBadRequest, "Invalid request"},
+	{CodeInvalidSignature, http.StatusUnauthorized, "Invalid message signature"},
+	{CodeUnauthorized, http.StatusUnauthorized, "Unauthorized"},
+	{CodeForbidden, http.StatusForbidden, "Forbidden"},
+	{CodeChannelForbidden, http.StatusForbidden, "Channel not allowed"},
+	{CodeNotFound, http.StatusNotFound, "Not found"},
+	{CodePayloadTooLarge, http.StatusRequestEntityTooLarge, "Payload too large"},
+	{CodeRateLimited, http.StatusTooManyRequests, "Rate limit exceeded"},
+	{CodeConfigRejected, http.StatusUnprocessableEntity, "Configuration rejected"},
+	{CodeVersionMismatch, http.StatusPreconditionFailed, "Version mismatch"},
+	{CodeVersionRequired, http.StatusPreconditionRequired, "Version required"},
+	{CodeInternal, http.StatusInternalServerError, "Internal server error"},
+}
+
+// Lookup returns the catalogue entry of code
+func Lookup(code Code) (Entry, bool) {
+	for _, entry := range Catalogue {
+		if entry.Code == code {
+			return entry, true
+		}
+	}
+	return Entry{}, false
+}
+
+// TypeURI returns the problem type URI of code
+func TypeURI(code Code) string {
+	return "urn:lunar:problem:" + string(code)
+}
+
+// Problem is an RFC 7807 problem details object, extended with a code and
+// the list of invalid fields
+type Problem struct {
+	Type   string       `json:"type" example:"urn:lunar:problem:validation_failed"`
+	Title  string       `json:"title" example:"Validation failed"`
+	Status int          `json:"status" example:"400"`
+	Detail string       `json:"detail,omitempty" example:"Invalid message format"`
+	Code   Code         `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is one invalid field, identified by its JSON path
+type FieldError struct {
+	Path    string `json:"path" example:"message.by"`
+	Message string `json:"message" example:"required"`
+}
+
+// New returns the problem for code with detail about this occurrence
+func New(code Code, detail string) *Problem {
+	entry, ok := Lookup(code)
+	if !ok {
+		entry = Entry{Code: code, Status: http.StatusInternalServerError, Title: http.StatusText(http.StatusInternalServerError)}
+	}
+	return &Problem{
+		Type:   TypeURI(code),
+		Title:  entry.Title,
+		Status: entry.Status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// Write sends p with its status code
+func Write(w http.ResponseWriter, p *Problem) {
+	body, _ := json.Marshal(p)
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
@@ -0,0 +1,51 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCatalogue(t *testing.T) {
+	seen := make(map[Code]bool)
+	for _, entry := range Catalogue {
+		if seen[entry.Code] {
+			t.Errorf("Duplicate code %q", entry.Code)
+		}
+		seen[entry.Code] = true
+		if entry.Title == "" || http.StatusText(entry.Status) == "" {
+			t.Errorf("Incomplete catalogue entry %+v", entry)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	p := New(CodeValidationFailed, "Invalid message format")
+	p.Errors = []FieldError{{Path: "message.by", Message: "required"}}
+
+	rec := httptest.NewRecorder()
+	Write(rec, p)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if contentType := rec.Header().Get("Content-Type"); contentType != ContentType {
+		t.Errorf("Expected content type %q, got %q", ContentType, contentType)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	if body["type"] != "urn:lunar:problem:validation_failed" || body["code"] != "validation_failed" || body["status"] != float64(400) {
+		t.Errorf("Unexpected problem: %v", body)
+	}
+	if errs, ok := body["errors"].([]any); !ok || len(errs) != 1 {
+		t.Errorf("Expected one field error, got %v", body["errors"])
+	}
+
+	// Unknown codes are reported as internal errors
+	if p := New("bogus", ""); p.Status != http.StatusInternalServerError {
+		t.Errorf("Expected status code %d for an unknown code, got %d", http.StatusInternalServerError, p.Status)
+	}
+}
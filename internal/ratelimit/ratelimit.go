@@ -0,0 +1,121 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket rate: Rate tokens are added per second up to Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Unlimited reports whether the limit disables limiting
+func (l Limit) Unlimited() bool {
+	return l.Rate <= 0
+}
+
+// bucket is the token bucket of one key
+type bucket struct {
+	tokens float64
+	last   time.Time // Time tokens was last refilled
+}
+
+// Limiter keeps one token bucket per key, such as a client or a channel.
+// Buckets of idle keys are removed by Sweep so memory stays bounded by the
+// number of recently active keys.
+type Limiter struct {
+	now func() time.Time
+
+	mu      sync.Mutex
+	limit   Limit
+	buckets map[string]*bucket
+}
+
+// NewLimiter creates a limiter; a limit with a non-positive rate allows everything
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{now: time.Now, limit: limit, buckets: make(map[string]*bucket)}
+}
+
+// Update replaces the limit; existing buckets keep their tokens up to the new burst
+func (l *Limiter) Update(limit Limit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+}
+
+// Allow takes a token for key. When none is available it reports how long
+// until one will be. A nil limiter allows everything.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.limit.Unlimited() {
+		return true, 0
+	}
+
+	now := l.now()
+	b := l.refill(key, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+
+	wait := time.Duration((1 - b.tokens) / l.limit.Rate * float64(time.Second))
+	return false, wait
+}
+
+// refill returns the bucket of key topped up to now, creating it full if needed
+func (l *Limiter) refill(key string, now time.Time) *bucket {
+	burst := float64(max(l.limit.Burst, 1))
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+		return b
+	}
+
+	elapsed := now.Sub(b.last).Seconds()
+	b.tokens = math.Min(burst, b.tokens+elapsed*l.limit.Rate)
+	b.last = now
+	return b
+}
+
+// Sweep removes buckets that have been idle for at least idle and have
+// refilled completely, returning how many were removed. A full bucket behaves
+// exactly like a new one, so removing it loses nothing.
+func (l *Limiter) Sweep(idle time.Duration) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	burst := float64(max(l.limit.Burst, 1))
+	removed := 0
+	for key, b := range l.buckets {
+		elapsed := now.Sub(b.last)
+		if elapsed >= idle && (l.limit.Unlimited() || b.tokens+elapsed.Seconds()*l.limit.Rate >= burst) {
+			delete(l.buckets, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Len returns the number of keys being tracked
+func (l *Limiter) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.buckets)
+}
+
+// RetryAfter formats a wait as whole seconds for the Retry-After header, at least 1
+func RetryAfter(wait time.Duration) int {
+	return max(1, int(math.Ceil(wait.Seconds())))
+}
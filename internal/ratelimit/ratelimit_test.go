@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter whose clock is advanced by hand
+func newTestLimiter(limit Limit) (*Limiter, *time.Time) {
+	now := time.Unix(1_700_000_000, 0)
+	l := NewLimiter(limit)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestAllow(t *testing.T) {
+	l, now := newTestLimiter(Limit{Rate: 2, Burst: 3})
+
+	// The burst is available immediately
+	for i := 0; i < 3; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+	ok, wait := l.Allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Expected to wait 500ms, got ok=%v wait=%v", ok, wait)
+	}
+
+	// Keys are limited independently
+	if ok, _ := l.Allow("b"); !ok {
+		t.Errorf("Expected another key to be allowed")
+	}
+
+	// Tokens refill at the configured rate
+	*now = now.Add(500 * time.Millisecond)
+	if ok, _ := l.Allow("a"); !ok {
+		t.Errorf("Expected a token after 500ms")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Errorf("Expected only one token after 500ms")
+	}
+
+	// Tokens never exceed the burst
+	*now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		l.Allow("a")
+	}
+	if ok, _ := l.Allow("a"); ok {
+		t.Errorf("Expected the bucket to hold at most the burst")
+	}
+}
+
+func TestUnlimited(t *testing.T) {
+	l, _ := newTestLimiter(Limit{})
+	for i := 0; i < 100; i++ {
+		if ok, _ := l.Allow("a"); !ok {
+			t.Fatalf("Expected an unlimited limiter to allow everything")
+		}
+	}
+	if l.Len() != 0 {
+		t.Errorf("Expected no state for an unlimited limiter, got %d keys", l.Len())
+	}
+
+	var disabled *Limiter
+	if ok, _ := disabled.Allow("a"); !ok {
+		t.Errorf("Expected a nil limiter to allow everything")
+	}
+}
+
+func TestSweep(t *testing.T) {
+	l, now := newTestLimiter(Limit{Rate: 1, Burst: 10})
+	for i := 0; i < 10; i++ {
+		l.Allow("drained")
+	}
+	l.Allow("idle")
+
+	// Both are idle long enough, but "drained" has not refilled yet
+	*now = now.Add(5 * time.Second)
+	if removed := l.Sweep(time.Second); removed != 1 || l.Len() != 1 {
+		t.Errorf("Expected only the refilled key to be removed, got %d removed and %d left", removed, l.Len())
+	}
+
+	*now = now.Add(10 * time.Second)
+	if removed := l.Sweep(time.Second); removed != 1 || l.Len() != 0 {
+		t.Errorf("Expected the refilled key to be removed, got %d removed and %d left", removed, l.Len())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	for wait, expected := range map[time.Duration]int{
+		0:                       1,
+		100 * time.Millisecond:  1,
+		1500 * time.Millisecond: 2,
+		3 * time.Second:         3,
+	} {
+		if got := RetryAfter(wait); got != expected {
+			t.Errorf("Expected %d for %v, got %d", expected, wait, got)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package rpc
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/rpc/lunarv1"
+)
+
+// methodScopes maps each RPC to the API key scope it requires
+var methodScopes = map[string]auth.Scope{
+	lunarv1.RocketService_GetRocket_FullMethodName:      auth.ScopeRead,
+	lunarv1.RocketService_ListRockets_FullMethodName:    auth.ScopeRead,
+	lunarv1.RocketService_IngestMessages_FullMethodName: auth.ScopeIngest,
+	lunarv1.RocketService_WatchRockets_FullMethodName:   auth.ScopeRead,
+}
+
+// authorize checks the API key in the "authorization" or "x-api-key"
+// metadata like the REST API checks headers. It returns a context carrying
+// the key name, Unauthenticated for missing or unknown keys and
+// PermissionDenied for keys without the scope of the method.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	if !s.Auth.Enabled() {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	key, ok := s.Auth.Lookup(auth.Credentials(first(md, "authorization"), first(md, "x-api-key")))
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing or invalid API key")
+	}
+	scope, known := methodScopes[method]
+	if !known {
+		scope = auth.ScopeAdmin // Methods added without a scope are reserved to operators
+	}
+	if !key.Allows(scope) {
+		return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("API key %q lacks the %q scope", key.Name, scope))
+	}
+	return auth.WithPrincipal(ctx, key.Name), nil
+}
+
+// unaryAuth authorizes unary calls
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// streamAuth authorizes streaming calls
+func (s *Server) streamAuth(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authorizedStream carries the context of an authorized stream
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context { return s.ctx }
+
+// first returns the first value of a metadata key
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,895 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        (unknown)
+// source: lunar/v1/rockets.proto
+
+package lunarv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Rocket is the complete state of a rocket
+type Rocket struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Speed         int64                  `protobuf:"varint,3,opt,name=speed,proto3" json:"speed,omitempty"`
+	Mission       string                 `protobuf:"bytes,4,opt,name=mission,proto3" json:"mission,omitempty"`
+	Exploded      bool                   `protobuf:"varint,5,opt,name=exploded,proto3" json:"exploded,omitempty"`
+	Reason        string                 `protobuf:"bytes,6,opt,name=reason,proto3" json:"reason,omitempty"` // Reason for the explosion, if any
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Version       uint64                 `protobuf:"varint,9,opt,name=version,proto3" json:"version,omitempty"` // Incremented on every applied update
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Rocket) Reset() {
+	*x = Rocket{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Rocket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Rocket) ProtoMessage() {}
+
+func (x *Rocket) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Rocket.ProtoReflect.Descriptor instead.
+func (*Rocket) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Rocket) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Rocket) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Rocket) GetSpeed() int64 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+func (x *Rocket) GetMission() string {
+	if x != nil {
+		return x.Mission
+	}
+	return ""
+}
+
+func (x *Rocket) GetExploded() bool {
+	if x != nil {
+		return x.Exploded
+	}
+	return false
+}
+
+func (x *Rocket) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *Rocket) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Rocket) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Rocket) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// RocketSummary is the shortened form of a rocket used in lists
+type RocketSummary struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Speed         int64                  `protobuf:"varint,3,opt,name=speed,proto3" json:"speed,omitempty"`
+	Mission       string                 `protobuf:"bytes,4,opt,name=mission,proto3" json:"mission,omitempty"`
+	Status        string                 `protobuf:"bytes,5,opt,name=status,proto3" json:"status,omitempty"` // "active" or "exploded"
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	Version       uint64                 `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RocketSummary) Reset() {
+	*x = RocketSummary{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RocketSummary) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RocketSummary) ProtoMessage() {}
+
+func (x *RocketSummary) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RocketSummary.ProtoReflect.Descriptor instead.
+func (*RocketSummary) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RocketSummary) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RocketSummary) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *RocketSummary) GetSpeed() int64 {
+	if x != nil {
+		return x.Speed
+	}
+	return 0
+}
+
+func (x *RocketSummary) GetMission() string {
+	if x != nil {
+		return x.Mission
+	}
+	return ""
+}
+
+func (x *RocketSummary) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *RocketSummary) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *RocketSummary) GetVersion() uint64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type GetRocketRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRocketRequest) Reset() {
+	*x = GetRocketRequest{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRocketRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRocketRequest) ProtoMessage() {}
+
+func (x *GetRocketRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRocketRequest.ProtoReflect.Descriptor instead.
+func (*GetRocketRequest) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetRocketRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// RocketFilter selects rockets; empty fields match every rocket and
+// comparisons ignore case
+type RocketFilter struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	Mission       string                 `protobuf:"bytes,2,opt,name=mission,proto3" json:"mission,omitempty"`
+	Status        string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"` // "active" or "exploded"
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RocketFilter) Reset() {
+	*x = RocketFilter{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RocketFilter) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RocketFilter) ProtoMessage() {}
+
+func (x *RocketFilter) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RocketFilter.ProtoReflect.Descriptor instead.
+func (*RocketFilter) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RocketFilter) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *RocketFilter) GetMission() string {
+	if x != nil {
+		return x.Mission
+	}
+	return ""
+}
+
+func (x *RocketFilter) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type ListRocketsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sort          string                 `protobuf:"bytes,1,opt,name=sort,proto3" json:"sort,omitempty"`   // id, type, speed, mission, status or updatedAt; id by default
+	Order         string                 `protobuf:"bytes,2,opt,name=order,proto3" json:"order,omitempty"` // asc or desc; asc by default
+	Filter        *RocketFilter          `protobuf:"bytes,3,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRocketsRequest) Reset() {
+	*x = ListRocketsRequest{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRocketsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRocketsRequest) ProtoMessage() {}
+
+func (x *ListRocketsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRocketsRequest.ProtoReflect.Descriptor instead.
+func (*ListRocketsRequest) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListRocketsRequest) GetSort() string {
+	if x != nil {
+		return x.Sort
+	}
+	return ""
+}
+
+func (x *ListRocketsRequest) GetOrder() string {
+	if x != nil {
+		return x.Order
+	}
+	return ""
+}
+
+func (x *ListRocketsRequest) GetFilter() *RocketFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+type WatchRocketsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ids           []string               `protobuf:"bytes,1,rep,name=ids,proto3" json:"ids,omitempty"` // Rockets to watch; every rocket when empty
+	Filter        *RocketFilter          `protobuf:"bytes,2,opt,name=filter,proto3" json:"filter,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRocketsRequest) Reset() {
+	*x = WatchRocketsRequest{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRocketsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRocketsRequest) ProtoMessage() {}
+
+func (x *WatchRocketsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRocketsRequest.ProtoReflect.Descriptor instead.
+func (*WatchRocketsRequest) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *WatchRocketsRequest) GetIds() []string {
+	if x != nil {
+		return x.Ids
+	}
+	return nil
+}
+
+func (x *WatchRocketsRequest) GetFilter() *RocketFilter {
+	if x != nil {
+		return x.Filter
+	}
+	return nil
+}
+
+// Metadata mirrors the metadata of a REST message envelope
+type Metadata struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Channel       string                 `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	MessageNumber int64                  `protobuf:"varint,2,opt,name=message_number,json=messageNumber,proto3" json:"message_number,omitempty"`
+	MessageTime   *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=message_time,json=messageTime,proto3" json:"message_time,omitempty"`
+	MessageType   string                 `protobuf:"bytes,4,opt,name=message_type,json=messageType,proto3" json:"message_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metadata) Reset() {
+	*x = Metadata{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metadata) ProtoMessage() {}
+
+func (x *Metadata) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metadata.ProtoReflect.Descriptor instead.
+func (*Metadata) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Metadata) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *Metadata) GetMessageNumber() int64 {
+	if x != nil {
+		return x.MessageNumber
+	}
+	return 0
+}
+
+func (x *Metadata) GetMessageTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.MessageTime
+	}
+	return nil
+}
+
+func (x *Metadata) GetMessageType() string {
+	if x != nil {
+		return x.MessageType
+	}
+	return ""
+}
+
+// Envelope is a rocket message. The message is validated like the JSON body
+// of POST /messages, with the fields of its message type.
+type Envelope struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Metadata      *Metadata              `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Message       *structpb.Struct       `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Envelope) GetMetadata() *Metadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Envelope) GetMessage() *structpb.Struct {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+// FieldError describes an invalid field by its JSON path
+type FieldError struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldError) Reset() {
+	*x = FieldError{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldError) ProtoMessage() {}
+
+func (x *FieldError) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldError.ProtoReflect.Descriptor instead.
+func (*FieldError) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *FieldError) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FieldError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// IngestResult is the outcome of one envelope
+type IngestResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Channel       string                 `protobuf:"bytes,1,opt,name=channel,proto3" json:"channel,omitempty"`
+	MessageNumber int64                  `protobuf:"varint,2,opt,name=message_number,json=messageNumber,proto3" json:"message_number,omitempty"`
+	// Processing status as returned by POST /messages, or one of invalid,
+	// channel_forbidden and rate_limited when the envelope was dropped
+	Status        string        `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	Reason        string        `protobuf:"bytes,4,opt,name=reason,proto3" json:"reason,omitempty"`
+	Errors        []*FieldError `protobuf:"bytes,5,rep,name=errors,proto3" json:"errors,omitempty"`    // Invalid fields when the status is invalid
+	Drained       int32         `protobuf:"varint,6,opt,name=drained,proto3" json:"drained,omitempty"` // Buffered messages applied as a consequence
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestResult) Reset() {
+	*x = IngestResult{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestResult) ProtoMessage() {}
+
+func (x *IngestResult) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestResult.ProtoReflect.Descriptor instead.
+func (*IngestResult) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *IngestResult) GetChannel() string {
+	if x != nil {
+		return x.Channel
+	}
+	return ""
+}
+
+func (x *IngestResult) GetMessageNumber() int64 {
+	if x != nil {
+		return x.MessageNumber
+	}
+	return 0
+}
+
+func (x *IngestResult) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *IngestResult) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *IngestResult) GetErrors() []*FieldError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *IngestResult) GetDrained() int32 {
+	if x != nil {
+		return x.Drained
+	}
+	return 0
+}
+
+type IngestMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*IngestResult        `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"` // In the order the envelopes were sent
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IngestMessagesResponse) Reset() {
+	*x = IngestMessagesResponse{}
+	mi := &file_lunar_v1_rockets_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IngestMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IngestMessagesResponse) ProtoMessage() {}
+
+func (x *IngestMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lunar_v1_rockets_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IngestMessagesResponse.ProtoReflect.Descriptor instead.
+func (*IngestMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_lunar_v1_rockets_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *IngestMessagesResponse) GetResults() []*IngestResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_lunar_v1_rockets_proto protoreflect.FileDescriptor
+
+const file_lunar_v1_rockets_proto_rawDesc = "" +
+	"\n" +
+	"\x16lunar/v1/rockets.proto\x12\blunar.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa0\x02\n" +
+	"\x06Rocket\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
+	"\x05speed\x18\x03 \x01(\x03R\x05speed\x12\x18\n" +
+	"\amission\x18\x04 \x01(\tR\amission\x12\x1a\n" +
+	"\bexploded\x18\x05 \x01(\bR\bexploded\x12\x16\n" +
+	"\x06reason\x18\x06 \x01(\tR\x06reason\x129\n" +
+	"\n" +
+	"updated_at\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x129\n" +
+	"\n" +
+	"created_at\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12\x18\n" +
+	"\aversion\x18\t \x01(\x04R\aversion\"\xd0\x01\n" +
+	"\rRocketSummary\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x14\n" +
+	"\x05speed\x18\x03 \x01(\x03R\x05speed\x12\x18\n" +
+	"\amission\x18\x04 \x01(\tR\amission\x12\x16\n" +
+	"\x06status\x18\x05 \x01(\tR\x06status\x129\n" +
+	"\n" +
+	"updated_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12\x18\n" +
+	"\aversion\x18\a \x01(\x04R\aversion\"\"\n" +
+	"\x10GetRocketRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"T\n" +
+	"\fRocketFilter\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x18\n" +
+	"\amission\x18\x02 \x01(\tR\amission\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\"n\n" +
+	"\x12ListRocketsRequest\x12\x12\n" +
+	"\x04sort\x18\x01 \x01(\tR\x04sort\x12\x14\n" +
+	"\x05order\x18\x02 \x01(\tR\x05order\x12.\n" +
+	"\x06filter\x18\x03 \x01(\v2\x16.lunar.v1.RocketFilterR\x06filter\"W\n" +
+	"\x13WatchRocketsRequest\x12\x10\n" +
+	"\x03ids\x18\x01 \x03(\tR\x03ids\x12.\n" +
+	"\x06filter\x18\x02 \x01(\v2\x16.lunar.v1.RocketFilterR\x06filter\"\xad\x01\n" +
+	"\bMetadata\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\x12%\n" +
+	"\x0emessage_number\x18\x02 \x01(\x03R\rmessageNumber\x12=\n" +
+	"\fmessage_time\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\vmessageTime\x12!\n" +
+	"\fmessage_type\x18\x04 \x01(\tR\vmessageType\"m\n" +
+	"\bEnvelope\x12.\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x12.lunar.v1.MetadataR\bmetadata\x121\n" +
+	"\amessage\x18\x02 \x01(\v2\x17.google.protobuf.StructR\amessage\":\n" +
+	"\n" +
+	"FieldError\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xc7\x01\n" +
+	"\fIngestResult\x12\x18\n" +
+	"\achannel\x18\x01 \x01(\tR\achannel\x12%\n" +
+	"\x0emessage_number\x18\x02 \x01(\x03R\rmessageNumber\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x12\x16\n" +
+	"\x06reason\x18\x04 \x01(\tR\x06reason\x12,\n" +
+	"\x06errors\x18\x05 \x03(\v2\x14.lunar.v1.FieldErrorR\x06errors\x12\x18\n" +
+	"\adrained\x18\x06 \x01(\x05R\adrained\"J\n" +
+	"\x16IngestMessagesResponse\x120\n" +
+	"\aresults\x18\x01 \x03(\v2\x16.lunar.v1.IngestResultR\aresults2\x9f\x02\n" +
+	"\rRocketService\x129\n" +
+	"\tGetRocket\x12\x1a.lunar.v1.GetRocketRequest\x1a\x10.lunar.v1.Rocket\x12F\n" +
+	"\vListRockets\x12\x1c.lunar.v1.ListRocketsRequest\x1a\x17.lunar.v1.RocketSummary0\x01\x12H\n" +
+	"\x0eIngestMessages\x12\x12.lunar.v1.Envelope\x1a .lunar.v1.IngestMessagesResponse(\x01\x12A\n" +
+	"\fWatchRockets\x12\x1d.lunar.v1.WatchRocketsRequest\x1a\x10.lunar.v1.Rocket0\x01B5Z3github.com/rah-0/lunar/internal/rpc/lunarv1;lunarv1b\x06proto3"
+
+var (
+	file_lunar_v1_rockets_proto_rawDescOnce sync.Once
+	file_lunar_v1_rockets_proto_rawDescData []byte
+)
+
+func file_lunar_v1_rockets_proto_rawDescGZIP() []byte {
+	file_lunar_v1_rockets_proto_rawDescOnce.Do(func() {
+		file_lunar_v1_rockets_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_lunar_v1_rockets_proto_rawDesc), len(file_lunar_v1_rockets_proto_rawDesc)))
+	})
+	return file_lunar_v1_rockets_proto_rawDescData
+}
+
+var file_lunar_v1_rockets_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_lunar_v1_rockets_proto_goTypes = []any{
+	(*Rocket)(nil),                 // 0: lunar.v1.Rocket
+	(*RocketSummary)(nil),          // 1: lunar.v1.RocketSummary
+	(*GetRocketRequest)(nil),       // 2: lunar.v1.GetRocketRequest
+	(*RocketFilter)(nil),           // 3: lunar.v1.RocketFilter
+	(*ListRocketsRequest)(nil),     // 4: lunar.v1.ListRocketsRequest
+	(*WatchRocketsRequest)(nil),    // 5: lunar.v1.WatchRocketsRequest
+	(*Metadata)(nil),               // 6: lunar.v1.Metadata
+	(*Envelope)(nil),               // 7: lunar.v1.Envelope
+	(*FieldError)(nil),             // 8: lunar.v1.FieldError
+	(*IngestResult)(nil),           // 9: lunar.v1.IngestResult
+	(*IngestMessagesResponse)(nil), // 10: lunar.v1.IngestMessagesResponse
+	(*timestamppb.Timestamp)(nil),  // 11: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),        // 12: google.protobuf.Struct
+}
+var file_lunar_v1_rockets_proto_depIdxs = []int32{
+	11, // 0: lunar.v1.Rocket.updated_at:type_name -> google.protobuf.Timestamp
+	11, // 1: lunar.v1.Rocket.created_at:type_name -> google.protobuf.Timestamp
+	11, // 2: lunar.v1.RocketSummary.updated_at:type_name -> google.protobuf.Timestamp
+	3,  // 3: lunar.v1.ListRocketsRequest.filter:type_name -> lunar.v1.RocketFilter
+	3,  // 4: lunar.v1.WatchRocketsRequest.filter:type_name -> lunar.v1.RocketFilter
+	11, // 5: lunar.v1.Metadata.message_time:type_name -> google.protobuf.Timestamp
+	6,  // 6: lunar.v1.Envelope.metadata:type_name -> lunar.v1.Metadata
+	12, // 7: lunar.v1.Envelope.message:type_name -> google.protobuf.Struct
+	8,  // 8: lunar.v1.IngestResult.errors:type_name -> lunar.v1.FieldError
+	9,  // 9: lunar.v1.IngestMessagesResponse.results:type_name -> lunar.v1.IngestResult
+	2,  // 10: lunar.v1.RocketService.GetRocket:input_type -> lunar.v1.GetRocketRequest
+	4,  // 11: lunar.v1.RocketService.ListRockets:input_type -> lunar.v1.ListRocketsRequest
+	7,  // 12: lunar.v1.RocketService.IngestMessages:input_type -> lunar.v1.Envelope
+	5,  // 13: lunar.v1.RocketService.WatchRockets:input_type -> lunar.v1.WatchRocketsRequest
+	0,  // 14: lunar.v1.RocketService.GetRocket:output_type -> lunar.v1.Rocket
+	1,  // 15: lunar.v1.RocketService.ListRockets:output_type -> lunar.v1.RocketSummary
+	10, // 16: lunar.v1.RocketService.IngestMessages:output_type -> lunar.v1.IngestMessagesResponse
+	0,  // 17: lunar.v1.RocketService.WatchRockets:output_type -> lunar.v1.Rocket
+	14, // [14:18] is the sub-list for method output_type
+	10, // [10:14] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_lunar_v1_rockets_proto_init() }
+func file_lunar_v1_rockets_proto_init() {
+	if File_lunar_v1_rockets_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_lunar_v1_rockets_proto_rawDesc), len(file_lunar_v1_rockets_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_lunar_v1_rockets_proto_goTypes,
+		DependencyIndexes: file_lunar_v1_rockets_proto_depIdxs,
+		MessageInfos:      file_lunar_v1_rockets_proto_msgTypes,
+	}.Build()
+	File_lunar_v1_rockets_proto = out.File
+	file_lunar_v1_rockets_proto_goTypes = nil
+	file_lunar_v1_rockets_proto_depIdxs = nil
+}
@@ -0,0 +1,255 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: lunar/v1/rockets.proto
+
+package lunarv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RocketService_GetRocket_FullMethodName      = "/lunar.v1.RocketService/GetRocket"
+	RocketService_ListRockets_FullMethodName    = "/lunar.v1.RocketService/ListRockets"
+	RocketService_IngestMessages_FullMethodName = "/lunar.v1.RocketService/IngestMessages"
+	RocketService_WatchRockets_FullMethodName   = "/lunar.v1.RocketService/WatchRockets"
+)
+
+// RocketServiceClient is the client API for RocketService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// RocketService exposes the rockets tracked by the server. It shares its
+// storage with the REST API, so both always see the same state.
+type RocketServiceClient interface {
+	// GetRocket returns the complete state of one rocket
+	GetRocket(ctx context.Context, in *GetRocketRequest, opts ...grpc.CallOption) (*Rocket, error)
+	// ListRockets streams the summaries of the rockets matching the filter,
+	// in the requested order
+	ListRockets(ctx context.Context, in *ListRocketsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RocketSummary], error)
+	// IngestMessages accepts a stream of message envelopes and answers with
+	// the outcome of each once the client closes the stream
+	IngestMessages(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Envelope, IngestMessagesResponse], error)
+	// WatchRockets streams the current state of the matching rockets, then
+	// every change until the client cancels
+	WatchRockets(ctx context.Context, in *WatchRocketsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Rocket], error)
+}
+
+type rocketServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRocketServiceClient(cc grpc.ClientConnInterface) RocketServiceClient {
+	return &rocketServiceClient{cc}
+}
+
+func (c *rocketServiceClient) GetRocket(ctx context.Context, in *GetRocketRequest, opts ...grpc.CallOption) (*Rocket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Rocket)
+	err := c.cc.Invoke(ctx, RocketService_GetRocket_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *rocketServiceClient) ListRockets(ctx context.Context, in *ListRocketsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RocketSummary], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RocketService_ServiceDesc.Streams[0], RocketService_ListRockets_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ListRocketsRequest, RocketSummary]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RocketService_ListRocketsClient = grpc.ServerStreamingClient[RocketSummary]
+
+func (c *rocketServiceClient) IngestMessages(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[Envelope, IngestMessagesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RocketService_ServiceDesc.Streams[1], RocketService_IngestMessages_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Envelope, IngestMessagesResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RocketService_IngestMessagesClient = grpc.ClientStreamingClient[Envelope, IngestMessagesResponse]
+
+func (c *rocketServiceClient) WatchRockets(ctx context.Context, in *WatchRocketsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Rocket], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RocketService_ServiceDesc.Streams[2], RocketService_WatchRockets_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchRocketsRequest, Rocket]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RocketService_WatchRocketsClient = grpc.ServerStreamingClient[Rocket]
+
+// RocketServiceServer is the server API for RocketService service.
+// All implementations must embed UnimplementedRocketServiceServer
+// for forward compatibility.
+//
+// RocketService exposes the rockets tracked by the server. It shares its
+// storage with the REST API, so both always see the same state.
+type RocketServiceServer interface {
+	// GetRocket returns the complete state of one rocket
+	GetRocket(context.Context, *GetRocketRequest) (*Rocket, error)
+	// ListRockets streams the summaries of the rockets matching the filter,
+	// in the requested order
+	ListRockets(*ListRocketsRequest, grpc.ServerStreamingServer[RocketSummary]) error
+	// IngestMessages accepts a stream of message envelopes and answers with
+	// the outcome of each once the client closes the stream
+	IngestMessages(grpc.ClientStreamingServer[Envelope, IngestMessagesResponse]) error
+	// WatchRockets streams the current state of the matching rockets, then
+	// every change until the client cancels
+	WatchRockets(*WatchRocketsRequest, grpc.ServerStreamingServer[Rocket]) error
+	mustEmbedUnimplementedRocketServiceServer()
+}
+
+// UnimplementedRocketServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRocketServiceServer struct{}
+
+func (UnimplementedRocketServiceServer) GetRocket(context.Context, *GetRocketRequest) (*Rocket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRocket not implemented")
+}
+func (UnimplementedRocketServiceServer) ListRockets(*ListRocketsRequest, grpc.ServerStreamingServer[RocketSummary]) error {
+	return status.Errorf(codes.Unimplemented, "method ListRockets not implemented")
+}
+func (UnimplementedRocketServiceServer) IngestMessages(grpc.ClientStreamingServer[Envelope, IngestMessagesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method IngestMessages not implemented")
+}
+func (UnimplementedRocketServiceServer) WatchRockets(*WatchRocketsRequest, grpc.ServerStreamingServer[Rocket]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchRockets not implemented")
+}
+func (UnimplementedRocketServiceServer) mustEmbedUnimplementedRocketServiceServer() {}
+func (UnimplementedRocketServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeRocketServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RocketServiceServer will
+// result in compilation errors.
+type UnsafeRocketServiceServer interface {
+	mustEmbedUnimplementedRocketServiceServer()
+}
+
+func RegisterRocketServiceServer(s grpc.ServiceRegistrar, srv RocketServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRocketServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RocketService_ServiceDesc, srv)
+}
+
+func _RocketService_GetRocket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRocketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RocketServiceServer).GetRocket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RocketService_GetRocket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RocketServiceServer).GetRocket(ctx, req.(*GetRocketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _RocketService_ListRockets_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListRocketsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RocketServiceServer).ListRockets(m, &grpc.GenericServerStream[ListRocketsRequest, RocketSummary]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RocketService_ListRocketsServer = grpc.ServerStreamingServer[RocketSummary]
+
+func _RocketService_IngestMessages_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(RocketServiceServer).IngestMessages(&grpc.GenericServerStream[Envelope, IngestMessagesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RocketService_IngestMessagesServer = grpc.ClientStreamingServer[Envelope, IngestMessagesResponse]
+
+func _RocketService_WatchRockets_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchRocketsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RocketServiceServer).WatchRockets(m, &grpc.GenericServerStream[WatchRocketsRequest, Rocket]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RocketService_WatchRocketsServer = grpc.ServerStreamingServer[Rocket]
+
+// RocketService_ServiceDesc is the grpc.ServiceDesc for RocketService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RocketService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "lunar.v1.RocketService",
+	HandlerType: (*RocketServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRocket",
+			Handler:    _RocketService_GetRocket_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ListRockets",
+			Handler:       _RocketService_ListRockets_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "IngestMessages",
+			Handler:       _RocketService_IngestMessages_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "WatchRockets",
+			Handler:       _RocketService_WatchRockets_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "lunar/v1/rockets.proto",
+}
@@ -0,0 +1,353 @@
+// Package rpc serves the rocket API over gRPC. It shares the repository and
+// message decoder with the REST handlers, so both APIs see the same state
+// and validate messages the same way.
+package rpc
+
+//go:generate protoc -I ../../proto --go_out=../.. --go_opt=module=github.com/rah-0/lunar --go-grpc_out=../.. --go-grpc_opt=module=github.com/rah-0/lunar lunar/v1/rockets.proto
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/logging"
+	"github.com/rah-0/lunar/internal/messages"
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/ratelimit"
+	"github.com/rah-0/lunar/internal/rpc/lunarv1"
+	"github.com/rah-0/lunar/internal/signing"
+	"github.com/rah-0/lunar/internal/storage"
+	"github.com/rah-0/lunar/internal/transport"
+)
+
+// Statuses of envelopes dropped before they reach the repository
+const (
+	StatusInvalid          = "invalid"
+	StatusChannelForbidden = "channel_forbidden"
+	StatusRateLimited      = "rate_limited"
+)
+
+// Server implements lunarv1.RocketServiceServer
+type Server struct {
+	lunarv1.UnimplementedRocketServiceServer
+
+	Repository  storage.RocketRepository
+	Decoder     *messages.Decoder        // Decodes the message types accepted by IngestMessages
+	Auth        *auth.Authenticator      // Optional; RPCs are open when nil or disabled
+	Channels    *transport.ChannelPolicy // Optional; restricts client certificates to channels
+	Signatures  *signing.Verifier        // Optional; IngestMessages is refused while signatures are enforced
+	DeadLetters *deadletter.Store        // Optional; keeps the messages that could not be processed
+
+	// Optional ingestion rate limits; unlimited when nil
+	ClientLimiter  *ratelimit.Limiter // Keyed by API key name, or client IP without one
+	ChannelLimiter *ratelimit.Limiter // Keyed by message channel
+}
+
+// NewServer creates a gRPC service backed by repo
+func NewServer(repo storage.RocketRepository) *Server {
+	return &Server{Repository: repo, Decoder: messages.NewDecoder(messages.Builtin(), messages.ModeStrict, slog.Default())}
+}
+
+// Register creates a grpc.Server with the authentication interceptors and
+// registers the service with it
+func (s *Server) Register(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(s.unaryAuth),
+		grpc.ChainStreamInterceptor(s.streamAuth),
+	)
+	server := grpc.NewServer(opts...)
+	lunarv1.RegisterRocketServiceServer(server, s)
+	return server
+}
+
+// GetRocket returns the complete state of one rocket
+func (s *Server) GetRocket(ctx context.Context, req *lunarv1.GetRocketRequest) (*lunarv1.Rocket, error) {
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing rocket ID")
+	}
+	rocket, exists := s.Repository.GetRocket(ctx, req.GetId())
+	if !exists {
+		if err := ctx.Err(); err != nil {
+			return nil, status.FromContextError(err).Err()
+		}
+		return nil, status.Errorf(codes.NotFound, "rocket with ID %s not found", req.GetId())
+	}
+	return toRocket(rocket), nil
+}
+
+// ListRockets streams the matching rocket summaries in the requested order
+func (s *Server) ListRockets(req *lunarv1.ListRocketsRequest, stream grpc.ServerStreamingServer[lunarv1.RocketSummary]) error {
+	sortField, order := strings.ToLower(req.GetSort()), strings.ToLower(req.GetOrder())
+	if sortField != "" && !storage.ValidSortFields[sortField] {
+		return status.Errorf(codes.InvalidArgument, "unknown sort field %q", req.GetSort())
+	}
+	if order != "" && !storage.ValidOrders[order] {
+		return status.Errorf(codes.InvalidArgument, "unknown sort order %q (expected asc or desc)", req.GetOrder())
+	}
+	if sortField == "" {
+		sortField = "id" // Streams have a stable order even without a sort field
+	}
+
+	rockets, err := s.Repository.ListRockets(stream.Context(), sortField, order)
+	if err != nil {
+		return status.FromContextError(err).Err()
+	}
+	for _, rocket := range storage.FilterRocketSummaries(rockets, toFilter(req.GetFilter())) {
+		if err := stream.Send(toSummary(rocket)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IngestMessages processes every envelope of the stream like POST /messages
+// does and reports the outcome of each once the client closes the stream.
+// Invalid envelopes are reported rather than failing the stream.
+func (s *Server) IngestMessages(stream grpc.ClientStreamingServer[lunarv1.Envelope, lunarv1.IngestMessagesResponse]) error {
+	ctx := stream.Context()
+	if s.Signatures.Mode() == signing.ModeEnforce {
+		return status.Error(codes.FailedPrecondition, "message signatures are enforced; post messages to /messages instead")
+	}
+
+	response := &lunarv1.IngestMessagesResponse{}
+	for {
+		envelope, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return stream.SendAndClose(response)
+		}
+		if err != nil {
+			return err
+		}
+		response.Results = append(response.Results, s.ingest(ctx, envelope))
+	}
+}
+
+// ingest validates and processes one envelope
+func (s *Server) ingest(ctx context.Context, in *lunarv1.Envelope) *lunarv1.IngestResult {
+	result := &lunarv1.IngestResult{
+		Channel:       in.GetMetadata().GetChannel(),
+		MessageNumber: in.GetMetadata().GetMessageNumber(),
+	}
+	logger := logging.FromContext(ctx)
+
+	// Validate the envelope with the same decoder as the REST API
+	body, err := envelopeJSON(in)
+	if err != nil {
+		result.Status, result.Reason = StatusInvalid, err.Error()
+		s.DeadLetters.Add(newLetter(ctx, deadletter.StageDecode, result.Reason, body, models.Envelope{}))
+		return result
+	}
+	envelope, unknown, err := s.Decoder.DecodeEnvelope(body)
+	if err != nil {
+		result.Status, result.Reason = StatusInvalid, strings.ReplaceAll(err.Error(), "\n", "; ")
+		stage := deadletter.StageValidate
+		for _, fieldErr := range messages.FieldErrors(err) {
+			result.Errors = append(result.Errors, &lunarv1.FieldError{Path: fieldErr.Path, Message: fieldErr.Message})
+		}
+		if len(result.Errors) == 0 {
+			stage = deadletter.StageDecode
+		}
+		logger.Debug("ingestion decision", "channel", result.Channel, "messageNumber", result.MessageNumber, "status", "dropped", "reason", result.Reason)
+		s.DeadLetters.Add(newLetter(ctx, stage, result.Reason, body, envelope))
+		return result
+	}
+	s.Decoder.RecordUnknown(envelope.GetMessageType(), unknown)
+
+	// Apply the channel policy and rate limits of the REST API
+	var tlsState *tls.ConnectionState
+	if p, ok := peer.FromContext(ctx); ok {
+		if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+			tlsState = &info.State
+		}
+	}
+	if subject, ok := s.Channels.AllowsConn(tlsState, envelope.GetChannel()); !ok {
+		result.Status = StatusChannelForbidden
+		result.Reason = fmt.Sprintf("client certificate %q may not post to channel %q", subject, envelope.GetChannel())
+		return result
+	}
+	if ok, wait := s.ClientLimiter.Allow(clientKey(ctx)); !ok {
+		result.Status = StatusRateLimited
+		result.Reason = fmt.Sprintf("rate limit exceeded for this client; retry in %ds", ratelimit.RetryAfter(wait))
+		return result
+	}
+	if ok, wait := s.ChannelLimiter.Allow(envelope.GetChannel()); !ok {
+		result.Status = StatusRateLimited
+		result.Reason = fmt.Sprintf("rate limit exceeded for channel %q; retry in %ds", envelope.GetChannel(), ratelimit.RetryAfter(wait))
+		return result
+	}
+
+	outcome := s.Repository.ProcessMessage(ctx, envelope)
+	logger.Debug("ingestion decision",
+		"channel", envelope.GetChannel(),
+		"messageNumber", envelope.GetMessageNumber(),
+		"messageType", envelope.GetMessageType(),
+		"status", outcome.Status,
+		"reason", outcome.Reason,
+		"drained", outcome.Drained,
+	)
+	if outcome.Status == storage.StatusRejected {
+		s.DeadLetters.Add(newLetter(ctx, deadletter.StageApply, outcome.Reason, body, envelope))
+	}
+	result.Status = string(outcome.Status)
+	result.Reason = outcome.Reason
+	result.Drained = int32(outcome.Drained)
+	return result
+}
+
+// newLetter describes an envelope of the client of ctx that failed at stage
+func newLetter(ctx context.Context, stage, reason string, body []byte, envelope models.Envelope) deadletter.Letter {
+	return deadletter.Letter{
+		Client:        clientKey(ctx),
+		Stage:         stage,
+		Reason:        reason,
+		Channel:       envelope.GetChannel(),
+		MessageNumber: envelope.GetMessageNumber(),
+		MessageType:   envelope.GetMessageType(),
+		Body:          string(body),
+	}
+}
+
+// WatchRockets sends the current state of the matching rockets, then each
+// rocket again whenever its version changes, until the client cancels
+func (s *Server) WatchRockets(req *lunarv1.WatchRocketsRequest, stream grpc.ServerStreamingServer[lunarv1.Rocket]) error {
+	ctx := stream.Context()
+	filter := toFilter(req.GetFilter())
+	var ids map[string]bool
+	if len(req.GetIds()) > 0 {
+		ids = make(map[string]bool, len(req.GetIds()))
+		for _, id := range req.GetIds() {
+			ids[id] = true
+		}
+	}
+
+	sent := make(map[string]uint64) // Version last sent per rocket
+	for {
+		// Take the notification channel first so no change is missed
+		changed := s.Repository.Changed()
+
+		rockets, err := s.Repository.ListRockets(ctx, "id", "asc")
+		if err != nil {
+			return status.FromContextError(err).Err()
+		}
+
+		// Forget deleted rockets, so that one created again under the same
+		// ID is sent whatever its version
+		listed := make(map[string]bool, len(rockets))
+		for _, summary := range rockets {
+			listed[summary.ID] = true
+		}
+		for id := range sent {
+			if !listed[id] {
+				delete(sent, id)
+			}
+		}
+
+		for _, summary := range rockets {
+			if ids != nil && !ids[summary.ID] || !filter.Matches(summary) {
+				continue
+			}
+			if version, ok := sent[summary.ID]; ok && version >= summary.Version {
+				continue
+			}
+			rocket, exists := s.Repository.GetRocket(ctx, summary.ID)
+			if !exists {
+				continue
+			}
+			if err := stream.Send(toRocket(rocket)); err != nil {
+				return err
+			}
+			sent[summary.ID] = rocket.Version
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// envelopeJSON renders an envelope as the JSON body of POST /messages.
+// Unset fields are left out so the decoder reports them as missing.
+func envelopeJSON(in *lunarv1.Envelope) ([]byte, error) {
+	body := map[string]any{}
+	if md := in.GetMetadata(); md != nil {
+		metadata := map[string]any{
+			"channel":       md.GetChannel(),
+			"messageNumber": md.GetMessageNumber(),
+			"messageType":   md.GetMessageType(),
+		}
+		if md.GetMessageTime() != nil {
+			metadata["messageTime"] = md.GetMessageTime().AsTime().Format(time.RFC3339Nano)
+		}
+		body["metadata"] = metadata
+	}
+	if in.GetMessage() != nil {
+		body["message"] = in.GetMessage().AsMap()
+	}
+	return json.Marshal(body)
+}
+
+// clientKey identifies the client for rate limiting like the REST API does
+func clientKey(ctx context.Context) string {
+	if principal := auth.PrincipalFromContext(ctx); principal != "" {
+		return "key:" + principal
+	}
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr = p.Addr.String()
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return "ip:" + host
+	}
+	return "ip:" + addr
+}
+
+// toFilter converts a request filter; nil matches every rocket
+func toFilter(f *lunarv1.RocketFilter) storage.RocketFilter {
+	return storage.RocketFilter{Type: f.GetType(), Mission: f.GetMission(), Status: f.GetStatus()}
+}
+
+// toRocket converts a rocket state to its protobuf form
+func toRocket(r *models.RocketState) *lunarv1.Rocket {
+	return &lunarv1.Rocket{
+		Id:        r.ID,
+		Type:      r.Type,
+		Speed:     int64(r.Speed),
+		Mission:   r.Mission,
+		Exploded:  r.Exploded,
+		Reason:    r.Reason,
+		UpdatedAt: timestamppb.New(r.UpdatedAt),
+		CreatedAt: timestamppb.New(r.CreatedAt),
+		Version:   r.Version,
+	}
+}
+
+// toSummary converts a rocket summary to its protobuf form
+func toSummary(r models.RocketSummary) *lunarv1.RocketSummary {
+	return &lunarv1.RocketSummary{
+		Id:        r.ID,
+		Type:      r.Type,
+		Speed:     int64(r.Speed),
+		Mission:   r.Mission,
+		Status:    r.Status,
+		UpdatedAt: timestamppb.New(r.UpdatedAt),
+		Version:   r.Version,
+	}
+}
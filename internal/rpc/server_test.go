@@ -0,0 +1,276 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/rah-0/lunar/internal/auth"
+	"github.com/rah-0/lunar/internal/deadletter"
+	"github.com/rah-0/lunar/internal/models"
+	"github.com/rah-0/lunar/internal/rpc/lunarv1"
+	"github.com/rah-0/lunar/internal/storage"
+)
+
+// startServer serves s in-process and returns a client connected to it
+func startServer(t *testing.T, s *Server) lunarv1.RocketServiceClient {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	server := s.Register()
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return lunarv1.NewRocketServiceClient(conn)
+}
+
+// envelope builds a message envelope for the given rocket
+func envelope(t *testing.T, channel string, number int64, messageType string, message map[string]any) *lunarv1.Envelope {
+	t.Helper()
+
+	content, err := structpb.NewStruct(message)
+	if err != nil {
+		t.Fatalf("Failed to build message: %v", err)
+	}
+	return &lunarv1.Envelope{
+		Metadata: &lunarv1.Metadata{
+			Channel:       channel,
+			MessageNumber: number,
+			MessageTime:   timestamppb.New(time.Date(2025, 1, 1, 0, 0, int(number), 0, time.UTC)),
+			MessageType:   messageType,
+		},
+		Message: content,
+	}
+}
+
+// launched builds a RocketLaunched envelope
+func launched(t *testing.T, channel, rocketType, mission string, speed int) *lunarv1.Envelope {
+	return envelope(t, channel, 1, models.MessageTypeRocketLaunched, map[string]any{
+		"type": rocketType, "launchSpeed": speed, "mission": mission,
+	})
+}
+
+// ingest sends envelopes over one IngestMessages stream
+func ingest(t *testing.T, ctx context.Context, client lunarv1.RocketServiceClient, envelopes ...*lunarv1.Envelope) *lunarv1.IngestMessagesResponse {
+	t.Helper()
+
+	stream, err := client.IngestMessages(ctx)
+	if err != nil {
+		t.Fatalf("Failed to open ingest stream: %v", err)
+	}
+	for _, e := range envelopes {
+		if err := stream.Send(e); err != nil {
+			t.Fatalf("Failed to send envelope: %v", err)
+		}
+	}
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+	return response
+}
+
+func TestIngestAndGetRocket(t *testing.T) {
+	ctx := context.Background()
+	repo := storage.NewInMemoryRepository()
+	server := NewServer(repo)
+	server.DeadLetters = deadletter.NewStore(10)
+	client := startServer(t, server)
+
+	response := ingest(t, ctx, client,
+		envelope(t, "rocket-1", 2, models.MessageTypeRocketSpeedIncreased, map[string]any{"by": 500}),
+		launched(t, "rocket-1", "Falcon-9", "ARTEMIS", 1000),
+		envelope(t, "rocket-1", 3, models.MessageTypeRocketSpeedIncreased, map[string]any{"by": "fast", "extra": true}),
+	)
+	if len(response.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(response.Results))
+	}
+	if got := response.Results[0].Status; got != string(storage.StatusBuffered) {
+		t.Errorf("Expected the early message to be buffered, got %q", got)
+	}
+	if got := response.Results[1]; got.Status != string(storage.StatusApplied) || got.Drained != 1 {
+		t.Errorf("Expected the launch to drain the buffer, got %+v", got)
+	}
+	invalid := response.Results[2]
+	if invalid.Status != StatusInvalid || len(invalid.Errors) != 2 {
+		t.Fatalf("Expected two field errors, got %+v", invalid)
+	}
+	if invalid.Errors[0].Path != "message.extra" || invalid.Errors[1].Path != "message.by" {
+		t.Errorf("Unexpected error paths: %v", invalid.Errors)
+	}
+	letters := server.DeadLetters.List(deadletter.Filter{})
+	if len(letters) != 1 || letters[0].Stage != deadletter.StageValidate || letters[0].Channel != "rocket-1" || letters[0].MessageNumber != 3 {
+		t.Errorf("Expected the invalid envelope to be dead-lettered, got %+v", letters)
+	}
+
+	// The REST API sees the same repository
+	state, exists := repo.GetRocket(ctx, "rocket-1")
+	if !exists || state.Speed != 1500 {
+		t.Fatalf("Expected the shared repository to be updated, got %+v", state)
+	}
+
+	rocket, err := client.GetRocket(ctx, &lunarv1.GetRocketRequest{Id: "rocket-1"})
+	if err != nil {
+		t.Fatalf("GetRocket failed: %v", err)
+	}
+	if rocket.Type != "Falcon-9" || rocket.Speed != 1500 || rocket.Version != 2 {
+		t.Errorf("Unexpected rocket: %+v", rocket)
+	}
+
+	_, err = client.GetRocket(ctx, &lunarv1.GetRocketRequest{Id: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Expected NotFound, got %v", err)
+	}
+}
+
+func TestListRockets(t *testing.T) {
+	ctx := context.Background()
+	client := startServer(t, NewServer(storage.NewInMemoryRepository()))
+	ingest(t, ctx, client,
+		launched(t, "rocket-a", "Falcon-9", "ARTEMIS", 3000),
+		launched(t, "rocket-b", "Falcon-Heavy", "ARTEMIS", 1000),
+		launched(t, "rocket-c", "Falcon-9", "APOLLO", 2000),
+	)
+
+	list := func(req *lunarv1.ListRocketsRequest) []string {
+		t.Helper()
+		stream, err := client.ListRockets(ctx, req)
+		if err != nil {
+			t.Fatalf("ListRockets failed: %v", err)
+		}
+		var ids []string
+		for {
+			summary, err := stream.Recv()
+			if err == io.EOF {
+				return ids
+			}
+			if err != nil {
+				t.Fatalf("ListRockets failed: %v", err)
+			}
+			ids = append(ids, summary.Id)
+		}
+	}
+
+	if ids := list(&lunarv1.ListRocketsRequest{}); len(ids) != 3 || ids[0] != "rocket-a" {
+		t.Errorf("Expected all rockets by ID, got %v", ids)
+	}
+	ids := list(&lunarv1.ListRocketsRequest{Sort: "speed", Order: "desc", Filter: &lunarv1.RocketFilter{Type: "falcon-9"}})
+	if len(ids) != 2 || ids[0] != "rocket-a" || ids[1] != "rocket-c" {
+		t.Errorf("Expected Falcon-9 rockets by speed descending, got %v", ids)
+	}
+
+	stream, _ := client.ListRockets(ctx, &lunarv1.ListRocketsRequest{Sort: "color"})
+	if _, err := stream.Recv(); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown sort field, got %v", err)
+	}
+}
+
+func TestWatchRockets(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	repo := storage.NewInMemoryRepository()
+	client := startServer(t, NewServer(repo))
+	ingest(t, ctx, client, launched(t, "rocket-1", "Falcon-9", "ARTEMIS", 1000))
+
+	stream, err := client.WatchRockets(ctx, &lunarv1.WatchRocketsRequest{Ids: []string{"rocket-1"}})
+	if err != nil {
+		t.Fatalf("WatchRockets failed: %v", err)
+	}
+
+	// The current state comes first
+	rocket, err := stream.Recv()
+	if err != nil || rocket.Version != 1 {
+		t.Fatalf("Expected the current state, got %+v (%v)", rocket, err)
+	}
+
+	// Other rockets are not reported, changes to the watched one are
+	ingest(t, ctx, client,
+		launched(t, "rocket-2", "Falcon-9", "ARTEMIS", 1000),
+		envelope(t, "rocket-1", 2, models.MessageTypeRocketSpeedIncreased, map[string]any{"by": 250}),
+	)
+	rocket, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected a change, got %v", err)
+	}
+	if rocket.Id != "rocket-1" || rocket.Version != 2 || rocket.Speed != 1250 {
+		t.Errorf("Unexpected change: %+v", rocket)
+	}
+
+	// A rocket deleted and created again is reported as new
+	if err := repo.DeleteRocket(ctx, "rocket-1"); err != nil {
+		t.Fatalf("DeleteRocket failed: %v", err)
+	}
+	ingest(t, ctx, client, launched(t, "rocket-1", "Saturn-V", "APOLLO", 500))
+	rocket, err = stream.Recv()
+	if err != nil {
+		t.Fatalf("Expected the new rocket, got %v", err)
+	}
+	if rocket.Type != "Saturn-V" || rocket.Version != 3 {
+		t.Errorf("Expected the new rocket at version 3, got %+v", rocket)
+	}
+}
+
+func TestAuthInterceptors(t *testing.T) {
+	authenticator := auth.NewAuthenticator(true, []auth.Key{
+		{Name: "reader", Hash: hash("read-key"), Scopes: []auth.Scope{auth.ScopeRead}},
+		{Name: "emitter", Hash: hash("ingest-key"), Scopes: []auth.Scope{auth.ScopeIngest}},
+	})
+	s := NewServer(storage.NewInMemoryRepository())
+	s.Auth = authenticator
+	client := startServer(t, s)
+
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+
+	// Missing and unknown keys are unauthenticated
+	if _, err := client.GetRocket(context.Background(), &lunarv1.GetRocketRequest{Id: "x"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a key, got %v", err)
+	}
+	if _, err := client.GetRocket(withKey("wrong"), &lunarv1.GetRocketRequest{Id: "x"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated for an unknown key, got %v", err)
+	}
+
+	// Keys need the scope of the method
+	if _, err := client.GetRocket(withKey("read-key"), &lunarv1.GetRocketRequest{Id: "x"}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected the reader to pass, got %v", err)
+	}
+	stream, err := client.IngestMessages(withKey("read-key"))
+	if err == nil {
+		_, err = stream.CloseAndRecv()
+	}
+	if status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for the reader, got %v", err)
+	}
+
+	// Bearer tokens work as well
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer ingest-key")
+	if response := ingest(t, ctx, client, launched(t, "rocket-1", "Falcon-9", "ARTEMIS", 1000)); response.Results[0].Status != string(storage.StatusApplied) {
+		t.Errorf("Expected the emitter to ingest, got %+v", response.Results[0])
+	}
+}
+
+// hash returns the stored hash of a plaintext key
+func hash(key string) []byte {
+	raw, _ := auth.ParseHash(auth.HashKey(key))
+	return raw
+}
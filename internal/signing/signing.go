@@ -0,0 +1,278 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Header carries the signature of a message body
+const Header = "X-Lunar-Signature"
+
+// Mode controls what happens to messages that fail verification
+type Mode string
+
+// Mode constants
+const (
+	ModeOff     Mode = "off"     // Signatures are not checked
+	ModeLog     Mode = "log"     // Failures are logged and counted, but the message is accepted
+	ModeEnforce Mode = "enforce" // Failures are counted and the message is rejected
+)
+
+// ParseMode validates a verification mode
+func ParseMode(name string) (Mode, error) {
+	switch m := Mode(strings.ToLower(strings.TrimSpace(name))); m {
+	case ModeOff, ModeLog, ModeEnforce:
+		return m, nil
+	case "":
+		return ModeOff, nil
+	default:
+		return "", fmt.Errorf("unknown signature mode %q (expected off, log or enforce)", name)
+	}
+}
+
+// Result is the outcome of verifying a signature, used as a metric label
+type Result string
+
+// Result constants
+const (
+	ResultValid          Result = "valid"
+	ResultMissing        Result = "missing"
+	ResultMalformed      Result = "malformed"
+	ResultUnknownEmitter Result = "unknown_emitter"
+	ResultExpired        Result = "expired"
+	ResultMismatch       Result = "mismatch"
+	ResultReplayed       Result = "replayed"
+)
+
+// Error describes a failed verification
+type Error struct {
+	Result  Result
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func fail(result Result, format string, args ...any) *Error {
+	return &Error{Result: result, Message: fmt.Sprintf(format, args...)}
+}
+
+// Observer receives the result of every verification.
+// Implementations must be safe for concurrent use.
+type Observer interface {
+	ObserveSignature(result Result, mode Mode)
+}
+
+// Settings configures a Verifier
+type Settings struct {
+	Mode      Mode
+	Tolerance time.Duration     // Maximum age and clock skew of a signature timestamp
+	Secrets   map[string][]byte // Shared secret per emitter
+}
+
+// Sign returns the header value for body, signed by emitter at time t.
+// The signature is a hex HMAC-SHA256 over "<unix seconds>.<body>":
+//
+//	X-Lunar-Signature: t=1700000000,emitter=eu-1,v1=5257a869...
+func Sign(emitter string, secret, body []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return fmt.Sprintf("t=%s,emitter=%s,v1=%s", timestamp, emitter, hex.EncodeToString(mac(secret, timestamp, body)))
+}
+
+func mac(secret []byte, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+// Verifier checks message signatures. Its settings can be swapped at any
+// time, which is how secrets are rotated without a restart. Signatures that
+// were already accepted are remembered until they expire, so a captured
+// message cannot be replayed within the tolerance window either.
+type Verifier struct {
+	settings atomic.Pointer[Settings]
+	observer Observer
+	now      func() time.Time
+
+	mu        sync.Mutex
+	seen      map[string]time.Time // Accepted signature -> expiry
+	lastPrune time.Time
+}
+
+// pruneInterval bounds how often expired signatures are swept
+const pruneInterval = 10 * time.Second
+
+// NewVerifier creates a verifier; observer may be nil
+func NewVerifier(settings Settings, observer Observer) *Verifier {
+	v := &Verifier{observer: observer, now: time.Now, seen: make(map[string]time.Time)}
+	v.Update(settings)
+	return v
+}
+
+// Update atomically replaces the settings
+func (v *Verifier) Update(settings Settings) {
+	v.settings.Store(&settings)
+}
+
+// Mode returns the verification mode in effect
+func (v *Verifier) Mode() Mode {
+	if v == nil {
+		return ModeOff
+	}
+	return v.settings.Load().Mode
+}
+
+// Verify checks header against body and returns the signing emitter.
+// A signature already accepted by Commit is reported as replayed, but a valid
+// one is not remembered until it is committed, so that a message refused for
+// another reason, such as a rate limit, can be sent again unchanged.
+// The result is reported to the observer unless the mode is off.
+func (v *Verifier) Verify(header string, body []byte) (string, error) {
+	settings := v.settings.Load()
+	if settings.Mode == ModeOff {
+		return "", nil
+	}
+
+	emitter, err := v.verify(settings, header, body)
+	result := ResultValid
+	if err != nil {
+		result = err.Result
+	}
+	if v.observer != nil {
+		v.observer.ObserveSignature(result, settings.Mode)
+	}
+	if err != nil {
+		return emitter, err
+	}
+	return emitter, nil
+}
+
+// Commit remembers the signature of header, which Verify accepted, once its
+// message was accepted for processing. It fails with ResultReplayed when the
+// same signature was committed in the meantime.
+func (v *Verifier) Commit(header string) error {
+	settings := v.settings.Load()
+	if settings.Mode == ModeOff {
+		return nil
+	}
+
+	signed, err := parseHeader(header)
+	if err != nil {
+		return err
+	}
+	if !v.remember(signed.key(), signed.signedAt.Add(settings.Tolerance), v.now()) {
+		return fail(ResultReplayed, "signature was already used")
+	}
+	return nil
+}
+
+// signedHeader is the parsed content of a signature header
+type signedHeader struct {
+	timestamp string
+	emitter   string
+	signedAt  time.Time
+	mac       []byte
+}
+
+// key identifies the signature among those remembered. It is built from the
+// decoded MAC so that changing the case of the hex digits does not make a
+// replay look new.
+func (h signedHeader) key() string {
+	return h.emitter + ":" + hex.EncodeToString(h.mac)
+}
+
+func parseHeader(header string) (signedHeader, *Error) {
+	if header == "" {
+		return signedHeader{}, fail(ResultMissing, "missing %s header", Header)
+	}
+
+	var h signedHeader
+	var signature string
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch name {
+		case "t":
+			h.timestamp = value
+		case "emitter":
+			h.emitter = value
+		case "v1":
+			signature = value
+		}
+	}
+	unix, err := strconv.ParseInt(h.timestamp, 10, 64)
+	if err != nil || h.emitter == "" || signature == "" {
+		return h, fail(ResultMalformed, "malformed %s header (expected t=<unix>,emitter=<name>,v1=<hex>)", Header)
+	}
+	if h.mac, err = hex.DecodeString(signature); err != nil {
+		return h, fail(ResultMalformed, "signature is not hex encoded")
+	}
+	h.signedAt = time.Unix(unix, 0)
+	return h, nil
+}
+
+func (v *Verifier) verify(settings *Settings, header string, body []byte) (string, *Error) {
+	signed, err := parseHeader(header)
+	if err != nil {
+		return signed.emitter, err
+	}
+	emitter := signed.emitter
+
+	secret, ok := settings.Secrets[emitter]
+	if !ok {
+		return emitter, fail(ResultUnknownEmitter, "unknown emitter %q", emitter)
+	}
+
+	now := v.now()
+	if skew := now.Sub(signed.signedAt); skew > settings.Tolerance || skew < -settings.Tolerance {
+		return emitter, fail(ResultExpired, "signature timestamp is outside the %s tolerance", settings.Tolerance)
+	}
+
+	if !hmac.Equal(signed.mac, mac(secret, signed.timestamp, body)) {
+		return emitter, fail(ResultMismatch, "signature does not match the body")
+	}
+
+	if v.used(signed.key(), now) {
+		return emitter, fail(ResultReplayed, "signature was already used")
+	}
+	return emitter, nil
+}
+
+// used reports whether a signature was committed and has not expired yet
+func (v *Verifier) used(key string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	expiry, ok := v.seen[key]
+	return ok && !now.After(expiry)
+}
+
+// remember records an accepted signature and reports false if it was seen before.
+// Expired entries are swept periodically on the way.
+func (v *Verifier) remember(key string, expiry, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if now.Sub(v.lastPrune) >= pruneInterval {
+		for k, exp := range v.seen {
+			if now.After(exp) {
+				delete(v.seen, k)
+			}
+		}
+		v.lastPrune = now
+	}
+	if _, ok := v.seen[key]; ok {
+		return false
+	}
+	v.seen[key] = expiry
+	return true
+}
@@ -0,0 +1,118 @@
+package signing
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingObserver counts results by name
+type recordingObserver struct {
+	mu      sync.Mutex
+	results map[Result]int
+}
+
+func (o *recordingObserver) ObserveSignature(result Result, mode Mode) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.results[result]++
+}
+
+func TestVerify(t *testing.T) {
+	secret := []byte("emitter-secret")
+	body := []byte(`{"metadata":{"channel":"abc"}}`)
+	now := time.Unix(1_700_000_000, 0)
+
+	observer := &recordingObserver{results: make(map[Result]int)}
+	verifier := NewVerifier(Settings{
+		Mode:      ModeEnforce,
+		Tolerance: 5 * time.Minute,
+		Secrets:   map[string][]byte{"eu-1": secret},
+	}, observer)
+	verifier.now = func() time.Time { return now }
+
+	valid := Sign("eu-1", secret, body, now.Add(-time.Minute))
+	if emitter, err := verifier.Verify(valid, body); err != nil || emitter != "eu-1" {
+		t.Fatalf("Expected a valid signature from eu-1, got %q, %v", emitter, err)
+	}
+
+	// A signature is only used up once its message is committed
+	if _, err := verifier.Verify(valid, body); err != nil {
+		t.Fatalf("Expected an uncommitted signature to verify again, got %v", err)
+	}
+	if err := verifier.Commit(valid); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if err, ok := verifier.Commit(valid).(*Error); !ok || err.Result != ResultReplayed {
+		t.Errorf("Expected a second commit to be a replay, got %v", err)
+	}
+
+	// The same signature in upper case must not pass for a new one
+	prefix, signature, _ := strings.Cut(valid, "v1=")
+	upperCased := prefix + "v1=" + strings.ToUpper(signature)
+
+	tests := []struct {
+		name     string
+		header   string
+		body     []byte
+		expected Result
+	}{
+		{"missing", "", body, ResultMissing},
+		{"malformed", "v1=abc", body, ResultMalformed},
+		{"not hex", "t=1700000000,emitter=eu-1,v1=xyz", body, ResultMalformed},
+		{"unknown emitter", Sign("us-1", secret, body, now), body, ResultUnknownEmitter},
+		{"too old", Sign("eu-1", secret, body, now.Add(-10*time.Minute)), body, ResultExpired},
+		{"in the future", Sign("eu-1", secret, body, now.Add(10*time.Minute)), body, ResultExpired},
+		{"wrong secret", Sign("eu-1", []byte("other"), body, now), body, ResultMismatch},
+		{"tampered body", Sign("eu-1", secret, body, now), []byte(`{"metadata":{"channel":"xyz"}}`), ResultMismatch},
+		{"replayed", valid, body, ResultReplayed},
+		{"replayed in upper case", upperCased, body, ResultReplayed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifier.Verify(tt.header, tt.body)
+			verr, ok := err.(*Error)
+			if !ok || verr.Result != tt.expected {
+				t.Errorf("Expected %s, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	if observer.results[ResultValid] != 2 || observer.results[ResultMismatch] != 2 || observer.results[ResultReplayed] != 2 {
+		t.Errorf("Unexpected observed results: %v", observer.results)
+	}
+
+	// Replays are remembered only within the tolerance window
+	now = now.Add(10 * time.Minute)
+	if _, err := verifier.Verify(valid, body); err == nil {
+		t.Errorf("Expected an expired signature to be rejected")
+	}
+	fresh := Sign("eu-1", secret, body, now)
+	if _, err := verifier.Verify(fresh, body); err != nil {
+		t.Fatalf("Expected a fresh signature to be accepted, got %v", err)
+	}
+	if err := verifier.Commit(fresh); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if len(verifier.seen) != 1 {
+		t.Errorf("Expected expired signatures to be pruned, got %d remembered", len(verifier.seen))
+	}
+}
+
+func TestVerifyOff(t *testing.T) {
+	observer := &recordingObserver{results: make(map[Result]int)}
+	verifier := NewVerifier(Settings{Mode: ModeOff}, observer)
+
+	if _, err := verifier.Verify("", []byte("{}")); err != nil {
+		t.Errorf("Expected no verification when off, got %v", err)
+	}
+	if len(observer.results) != 0 {
+		t.Errorf("Expected nothing observed when off, got %v", observer.results)
+	}
+
+	var disabled *Verifier
+	if disabled.Mode() != ModeOff {
+		t.Errorf("Expected a nil verifier to be off")
+	}
+}
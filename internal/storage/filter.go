@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"strings"
+
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// RocketFilter selects rocket summaries. Empty fields match every rocket;
+// comparisons ignore case.
+type RocketFilter struct {
+	Type    string
+	Mission string
+	Status  string // "active" or "exploded"
+}
+
+// Matches reports whether the summary satisfies every set field
+func (f RocketFilter) Matches(summary models.RocketSummary) bool {
+	return matchField(f.Type, summary.Type) &&
+		matchField(f.Mission, summary.Mission) &&
+		matchField(f.Status, summary.Status)
+}
+
+// FilterRocketSummaries returns the summaries matching the filter, keeping their order
+func FilterRocketSummaries(summaries []models.RocketSummary, filter RocketFilter) []models.RocketSummary {
+	if filter == (RocketFilter{}) {
+		return summaries
+	}
+	matched := make([]models.RocketSummary, 0, len(summaries))
+	for _, summary := range summaries {
+		if filter.Matches(summary) {
+			matched = append(matched, summary)
+		}
+	}
+	return matched
+}
+
+// matchField compares a filter value with a field, treating empty as any
+func matchField(want, value string) bool {
+	return want == "" || strings.EqualFold(want, value)
+}
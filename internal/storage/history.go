@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/rah-0/lunar/internal/messages"
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// HistoryCapacity is the number of applied messages a rocket's history
+// keeps. When it is full, the oldest message is folded into the state the
+// history starts from.
+const HistoryCapacity = 1000
+
+// appliedMessage is a message in a rocket's history, with the type that
+// applied it
+type appliedMessage struct {
+	Envelope models.Envelope
+	Type     *messages.Type
+}
+
+// Replay describes a rocket's state rebuilt from its history
+type Replay struct {
+	Rocket   *models.RocketState `json:"rocket"`   // The rebuilt state; version, pause and overrides are not part of the history
+	Messages int                 `json:"messages"` // Messages reapplied, operator corrections included
+	Folded   int                 `json:"folded"`   // Oldest messages folded into the starting state to respect HistoryCapacity
+	Matches  bool                `json:"matches"`  // Whether the rebuilt type, speed, mission and explosion match the rocket's
+}
+
+// remember appends a message that was just applied to the history. The
+// entry must be locked.
+func (e *rocketEntry) remember(messageType *messages.Type, envelope models.Envelope) {
+	if len(e.history) == HistoryCapacity {
+		oldest := e.history[0]
+		e.history = e.history[1:]
+		e.historyFolded++
+		// The message applied to the rocket before, so it applies to the
+		// same state again
+		_ = replayMessage(e.historyBase, oldest)
+	}
+	e.history = append(e.history, appliedMessage{Envelope: envelope, Type: messageType})
+}
+
+// replayMessage applies a message of the history to state with the
+// bookkeeping the repository did when it was applied
+func replayMessage(state *models.RocketState, applied appliedMessage) error {
+	if err := applied.Type.Apply(state, applied.Envelope); err != nil {
+		return err
+	}
+	// Operator corrections are not numbered and leave the cursor alone
+	if n := applied.Envelope.GetMessageNumber(); n > 0 {
+		state.LastProcessedMessageNumber = n
+	}
+	state.UpdatedAt = applied.Envelope.GetMessageTime()
+	return nil
+}
+
+// ReplayRocket rebuilds a rocket's state by applying its history again, in
+// the order the messages were applied, to the state the rocket was created
+// with. The live rocket is left alone. It fails with ErrRocketNotFound for
+// unknown rockets.
+func (r *InMemoryRepository) ReplayRocket(ctx context.Context, id string) (Replay, error) {
+	entry, err := r.lockedEntry(ctx, id)
+	if err != nil {
+		return Replay{}, err
+	}
+	defer entry.Mu.Unlock()
+
+	base := entry.historyBase
+	state := &models.RocketState{
+		ID:                         base.ID,
+		Type:                       base.Type,
+		Speed:                      base.Speed,
+		Mission:                    base.Mission,
+		Exploded:                   base.Exploded,
+		Reason:                     base.Reason,
+		UpdatedAt:                  base.UpdatedAt,
+		CreatedAt:                  base.CreatedAt,
+		LastProcessedMessageNumber: base.LastProcessedMessageNumber,
+	}
+	replay := Replay{Rocket: state, Messages: len(entry.history), Folded: entry.historyFolded}
+	for _, applied := range entry.history {
+		if err := replayMessage(state, applied); err != nil {
+			return Replay{}, err
+		}
+	}
+
+	live := entry.State
+	replay.Matches = state.Type == live.Type && state.Speed == live.Speed &&
+		state.Mission == live.Mission && state.Exploded == live.Exploded && state.Reason == live.Reason
+	return replay, nil
+}
@@ -0,0 +1,355 @@
+package storage
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rah-0/lunar/internal/messages"
+	"github.com/rah-0/lunar/internal/models"
+)
+
+// Maintainer repairs rocket data on behalf of operators
+type Maintainer interface {
+	// DeleteRocket forgets a rocket with its buffer and message cursor, so
+	// that its next message creates it again. It fails with
+	// ErrRocketNotFound for unknown rockets.
+	DeleteRocket(ctx context.Context, id string) error
+
+	// ClearBuffer drops the out-of-order messages buffered for a rocket
+	// and returns how many were dropped
+	ClearBuffer(ctx context.Context, id string) (int, error)
+
+	// ResetCursor sets the number of the last message processed for a
+	// rocket, so that numbering can restart after an emitter reset
+	ResetCursor(ctx context.Context, id string, messageNumber int) (CursorReset, error)
+
+	// PurgeRockets deletes every rocket matching filter and returns their
+	// IDs in order
+	PurgeRockets(ctx context.Context, filter RocketFilter) ([]string, error)
+
+	// OverrideRocket corrects fields of a rocket when match accepts its
+	// current version, and returns the new state. It fails with
+	// ErrRocketNotFound for unknown rockets and with ErrVersionMismatch,
+	// along with the current state, when the rocket changed since the
+	// operator read it, and with ErrInvalidOverride for invalid values.
+	OverrideRocket(ctx context.Context, id string, match func(version uint64) bool, override Override) (*models.RocketState, error)
+
+	// PauseRocket stops applying the messages of a rocket: they are held in
+	// its buffer until ResumeRocket applies them in message number order.
+	// Pausing a paused rocket does nothing.
+	PauseRocket(ctx context.Context, id string) (PauseState, error)
+
+	// ResumeRocket applies the messages held while a rocket was paused and
+	// accepts new ones again. Resuming a running rocket does nothing.
+	ResumeRocket(ctx context.Context, id string) (PauseState, error)
+
+	// ReplayRocket rebuilds a rocket's state from the messages applied to
+	// it, operator corrections included, without changing the rocket. It
+	// fails with ErrRocketNotFound for unknown rockets.
+	ReplayRocket(ctx context.Context, id string) (Replay, error)
+}
+
+// ErrVersionMismatch is returned when a rocket changed since the version an
+// operator based a correction on
+var ErrVersionMismatch = errors.New("rocket version does not match")
+
+// ErrInvalidOverride is returned when a correction sets an empty type or
+// mission, or a negative speed
+var ErrInvalidOverride = errors.New("invalid override")
+
+// Override is an operator's correction of a rocket. Nil fields are kept.
+type Override struct {
+	Type    *string
+	Speed   *int
+	Mission *string
+	Actor   string // Who made the correction
+	Reason  string // Why the emitter's value was wrong; optional
+}
+
+// CursorReset describes the effect of resetting a message cursor
+type CursorReset struct {
+	Previous int `json:"previous"` // Last processed message number before the reset
+	Cursor   int `json:"cursor"`   // Last processed message number after the reset and draining
+	Dropped  int `json:"dropped"`  // Buffered messages at or below the new cursor, which are discarded
+	Drained  int `json:"drained"`  // Buffered messages applied since they became next in sequence
+}
+
+// PauseState describes whether ingestion of a rocket is paused
+type PauseState struct {
+	Paused  bool `json:"paused"`
+	Held    int  `json:"held"`    // Buffered messages not applied yet
+	Drained int  `json:"drained"` // Held messages applied when the rocket was resumed
+}
+
+// lockedEntry returns the entry of a rocket with its mutex held
+func (r *InMemoryRepository) lockedEntry(ctx context.Context, id string) (*rocketEntry, error) {
+	if err := r.lock(ctx, r.mu, LockRepository); err != nil {
+		return nil, err
+	}
+	entry, exists := r.rockets[id]
+	r.mu.Unlock()
+	if !exists {
+		return nil, ErrRocketNotFound
+	}
+
+	if err := r.lock(ctx, entry.Mu, LockRocket); err != nil {
+		return nil, err
+	}
+	if entry.deleted {
+		entry.Mu.Unlock()
+		return nil, ErrRocketNotFound
+	}
+	return entry, nil
+}
+
+// DeleteRocket removes a rocket and wakes up everyone waiting for it
+func (r *InMemoryRepository) DeleteRocket(ctx context.Context, id string) error {
+	if err := r.lock(ctx, r.mu, LockRepository); err != nil {
+		return err
+	}
+
+	entry, exists := r.rockets[id]
+	if !exists {
+		r.mu.Unlock()
+		return ErrRocketNotFound
+	}
+	if err := r.lock(ctx, entry.Mu, LockRocket); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+	r.deleteEntry(id, entry)
+	entry.Mu.Unlock()
+	r.mu.Unlock()
+
+	r.notifyChanged()
+	return nil
+}
+
+// deleteEntry removes a locked entry from the map, which must be locked too,
+// and keeps its version in the tombstones
+func (r *InMemoryRepository) deleteEntry(id string, entry *rocketEntry) {
+	delete(r.rockets, id)
+	r.tombstones[id] = entry.State.Version
+	entry.deleted = true
+	close(entry.changed)
+	entry.changed = make(chan struct{})
+}
+
+// ClearBuffer drops the buffered messages of a rocket
+func (r *InMemoryRepository) ClearBuffer(ctx context.Context, id string) (int, error) {
+	entry, err := r.lockedEntry(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	defer entry.Mu.Unlock()
+
+	dropped := entry.Buffer.Len()
+	entry.Buffer = &MessageBuffer{}
+	heap.Init(entry.Buffer)
+	r.observer.ObserveBufferDepth(0)
+	return dropped, nil
+}
+
+// ResetCursor moves the message cursor of a rocket. Buffered messages the
+// cursor moved past are dropped, and those that became next in sequence are
+// applied, unless the rocket exploded.
+func (r *InMemoryRepository) ResetCursor(ctx context.Context, id string, messageNumber int) (CursorReset, error) {
+	entry, err := r.lockedEntry(ctx, id)
+	if err != nil {
+		return CursorReset{}, err
+	}
+
+	reset := CursorReset{Previous: entry.State.LastProcessedMessageNumber}
+	entry.State.LastProcessedMessageNumber = messageNumber
+
+	kept := MessageBuffer{}
+	for _, buffered := range *entry.Buffer {
+		if buffered.GetMessageNumber() <= messageNumber {
+			reset.Dropped++
+			continue
+		}
+		kept = append(kept, buffered)
+	}
+	heap.Init(&kept)
+	entry.Buffer = &kept
+
+	// The cursor is part of the state, so moving it or dropping buffered
+	// messages makes cached copies stale even when nothing is drained
+	changed := reset.Previous != messageNumber || reset.Dropped > 0
+	if changed {
+		entry.bumpVersion()
+	}
+
+	if !entry.State.Exploded && !entry.State.Paused {
+		reset.Drained = r.processBufferedMessages(entry)
+	}
+	reset.Cursor = entry.State.LastProcessedMessageNumber
+	r.observer.ObserveBufferDepth(entry.Buffer.Len())
+	entry.Mu.Unlock()
+
+	if changed || reset.Drained > 0 {
+		r.notifyChanged()
+	}
+	return reset, nil
+}
+
+// PurgeRockets deletes the rockets matching filter
+func (r *InMemoryRepository) PurgeRockets(ctx context.Context, filter RocketFilter) ([]string, error) {
+	if err := r.lock(ctx, r.mu, LockRepository); err != nil {
+		return nil, err
+	}
+
+	// Watchers are told about the rockets deleted so far, even when a
+	// rocket lock cannot be taken, once the repository is unlocked
+	purged := []string{}
+	defer func() {
+		if len(purged) > 0 {
+			r.notifyChanged()
+		}
+	}()
+	defer r.mu.Unlock()
+
+	for id, entry := range r.rockets {
+		if err := r.lock(ctx, entry.Mu, LockRocket); err != nil {
+			return nil, err
+		}
+		if filter.Matches(entry.summary()) {
+			r.deleteEntry(id, entry)
+			purged = append(purged, id)
+		}
+		entry.Mu.Unlock()
+	}
+	sort.Strings(purged)
+	return purged, nil
+}
+
+// OverrideRocket applies the correction as a synthetic RocketStateOverridden
+// message carrying the complete corrected values. It goes through the same
+// reducer and version bookkeeping as emitter messages, but leaves the
+// message cursor alone, and records each corrected field in the state's
+// overrides. The synthetic message joins the rocket's history between the
+// emitter messages it was applied after and before, so that ReplayRocket
+// applies the correction again.
+func (r *InMemoryRepository) OverrideRocket(ctx context.Context, id string, match func(version uint64) bool, override Override) (*models.RocketState, error) {
+	// Only the fields the operator sent are checked; the others keep the
+	// rocket's values, whatever they are
+	switch {
+	case override.Type != nil && *override.Type == "":
+		return nil, fmt.Errorf("%w: type must not be empty", ErrInvalidOverride)
+	case override.Mission != nil && *override.Mission == "":
+		return nil, fmt.Errorf("%w: mission must not be empty", ErrInvalidOverride)
+	case override.Speed != nil && *override.Speed < 0:
+		return nil, fmt.Errorf("%w: speed must not be negative", ErrInvalidOverride)
+	}
+
+	entry, err := r.lockedEntry(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer entry.Mu.Unlock()
+
+	rocket := entry.State
+	if !match(rocket.Version) {
+		return entry.snapshot(), ErrVersionMismatch
+	}
+
+	var envelope models.Envelope
+	envelope.Metadata.Channel = id
+	envelope.Metadata.MessageTime = time.Now().UTC()
+	envelope.Metadata.MessageType = messages.RocketStateOverridden.Name
+	envelope.Message = models.MessageContent{Type: rocket.Type, Speed: rocket.Speed, Mission: rocket.Mission}
+
+	// Remember the value each corrected field had before
+	previous := map[string]any{}
+	if override.Type != nil {
+		previous["type"] = rocket.Type
+		envelope.Message.Type = *override.Type
+	}
+	if override.Speed != nil {
+		previous["speed"] = rocket.Speed
+		envelope.Message.Speed = *override.Speed
+	}
+	if override.Mission != nil {
+		previous["mission"] = rocket.Mission
+		envelope.Message.Mission = *override.Mission
+	}
+
+	if err := messages.RocketStateOverridden.Apply(rocket, envelope); err != nil {
+		outcome := rejectedOutcome(err)
+		r.observer.ObserveOutcome(envelope.GetMessageType(), outcome)
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOverride, err)
+	}
+	entry.remember(&messages.RocketStateOverridden, envelope)
+	entry.recordChange(envelope.GetMessageTime())
+
+	if rocket.Overrides == nil {
+		rocket.Overrides = make(map[string]models.FieldOverride)
+	}
+	current := map[string]any{"type": rocket.Type, "speed": rocket.Speed, "mission": rocket.Mission}
+	for field, value := range previous {
+		rocket.Overrides[field] = models.FieldOverride{
+			Value:    current[field],
+			Previous: value,
+			Actor:    override.Actor,
+			Reason:   override.Reason,
+			At:       envelope.GetMessageTime(),
+			Version:  rocket.Version,
+		}
+	}
+
+	r.observer.ObserveOutcome(envelope.GetMessageType(), ProcessOutcome{Status: StatusApplied})
+	r.notifyChanged()
+	return entry.snapshot(), nil
+}
+
+// PauseRocket marks the rocket paused. The version is incremented so that
+// cached copies of the rocket, which show whether it is paused, go stale.
+func (r *InMemoryRepository) PauseRocket(ctx context.Context, id string) (PauseState, error) {
+	entry, err := r.lockedEntry(ctx, id)
+	if err != nil {
+		return PauseState{}, err
+	}
+
+	paused := !entry.State.Paused
+	if paused {
+		entry.State.Paused = true
+		entry.bumpVersion()
+	}
+	state := PauseState{Paused: true, Held: entry.Buffer.Len()}
+	entry.Mu.Unlock()
+
+	if paused {
+		r.notifyChanged()
+	}
+	return state, nil
+}
+
+// ResumeRocket clears the paused mark and drains the buffer. Messages that
+// are still not next in sequence stay buffered, as they would have been
+// without the pause.
+func (r *InMemoryRepository) ResumeRocket(ctx context.Context, id string) (PauseState, error) {
+	entry, err := r.lockedEntry(ctx, id)
+	if err != nil {
+		return PauseState{}, err
+	}
+
+	resumed := entry.State.Paused
+	state := PauseState{}
+	if resumed {
+		entry.State.Paused = false
+		entry.bumpVersion()
+		state.Drained = r.processBufferedMessages(entry)
+		r.observer.ObserveBufferDepth(entry.Buffer.Len())
+	}
+	state.Held = entry.Buffer.Len()
+	entry.Mu.Unlock()
+
+	if resumed {
+		r.notifyChanged()
+	}
+	return state, nil
+}
@@ -0,0 +1,332 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeleteRocket(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+
+	// Long polls waiting for the rocket end when it is deleted
+	waitErr := make(chan error, 1)
+	go func() {
+		_, err := repo.WaitForVersion(ctx, "rocket-1", 1)
+		waitErr <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	changed := repo.Changed()
+	if err := repo.DeleteRocket(ctx, "rocket-1"); err != nil {
+		t.Fatalf("DeleteRocket failed: %v", err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected Changed to fire after the delete")
+	}
+	select {
+	case err := <-waitErr:
+		if !errors.Is(err, ErrRocketNotFound) {
+			t.Errorf("Expected the waiter to get ErrRocketNotFound, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiter to wake up")
+	}
+
+	if _, exists := repo.GetRocket(ctx, "rocket-1"); exists {
+		t.Error("Expected the rocket to be gone")
+	}
+	if err := repo.DeleteRocket(ctx, "rocket-1"); !errors.Is(err, ErrRocketNotFound) {
+		t.Errorf("Expected ErrRocketNotFound, got %v", err)
+	}
+
+	// The next message creates the rocket again, from message number 1,
+	// with a version above the deleted one's so that its ETags never match
+	if outcome := repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-Heavy", 100, "APOLLO")); outcome.Status != StatusApplied {
+		t.Errorf("Expected the launch to apply again, got %+v", outcome)
+	}
+	if rocket, _ := repo.GetRocket(ctx, "rocket-1"); rocket.Type != "Falcon-Heavy" || rocket.Version != 2 {
+		t.Errorf("Expected a new rocket at version 2, got %+v", rocket)
+	}
+
+	// So does a rocket deleted by a purge
+	changed = repo.Changed()
+	if purged, _ := repo.PurgeRockets(ctx, RocketFilter{}); len(purged) != 1 {
+		t.Fatalf("Expected rocket-1 to be purged, got %v", purged)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected Changed to fire after the purge")
+	}
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 100, "APOLLO"))
+	if rocket, _ := repo.GetRocket(ctx, "rocket-1"); rocket.Version != 3 {
+		t.Errorf("Expected the rocket to continue at version 3, got %d", rocket.Version)
+	}
+}
+
+func TestClearBufferAndResetCursor(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 3, launchTime, 100))
+
+	dropped, err := repo.ClearBuffer(ctx, "rocket-1")
+	if err != nil || dropped != 1 {
+		t.Fatalf("Expected 1 dropped message, got %d, %v", dropped, err)
+	}
+	if _, err := repo.ClearBuffer(ctx, "missing"); !errors.Is(err, ErrRocketNotFound) {
+		t.Errorf("Expected ErrRocketNotFound, got %v", err)
+	}
+
+	// Messages 4 and 5 wait for 3, which the emitter will never send
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 4, launchTime, 100))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 5, launchTime, 100))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 7, launchTime, 100))
+
+	// Moving the cursor past 3 applies 4 and 5, and 7 still waits for 6
+	changed := repo.Changed()
+	reset, err := repo.ResetCursor(ctx, "rocket-1", 3)
+	if err != nil {
+		t.Fatalf("ResetCursor failed: %v", err)
+	}
+	if reset != (CursorReset{Previous: 1, Cursor: 5, Dropped: 0, Drained: 2}) {
+		t.Errorf("Unexpected reset: %+v", reset)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected Changed to fire after draining")
+	}
+	if rocket, _ := repo.GetRocket(ctx, "rocket-1"); rocket.Speed != 700 {
+		t.Errorf("Expected speed 700, got %d", rocket.Speed)
+	}
+
+	// Resetting to 0 drops what was buffered and accepts numbering from 1.
+	// Nothing is drained, but the cursor is part of the state, so the
+	// version moves on and watchers are told.
+	before, _ := repo.GetRocket(ctx, "rocket-1")
+	changed = repo.Changed()
+	reset, _ = repo.ResetCursor(ctx, "rocket-1", 0)
+	if reset != (CursorReset{Previous: 5, Cursor: 0, Dropped: 0, Drained: 0}) {
+		t.Errorf("Unexpected reset: %+v", reset)
+	}
+	if rocket, _ := repo.GetRocket(ctx, "rocket-1"); rocket.Version <= before.Version {
+		t.Errorf("Expected the version to move past %d, got %d", before.Version, rocket.Version)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected Changed to fire after moving the cursor")
+	}
+	reset, _ = repo.ResetCursor(ctx, "rocket-1", 7)
+	if reset.Dropped != 1 || reset.Cursor != 7 {
+		t.Errorf("Expected the buffered message 7 to be dropped, got %+v", reset)
+	}
+}
+
+func TestPurgeRockets(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-b", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-a", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-c", 1, launchTime, "Falcon-9", 500, "APOLLO"))
+	repo.ProcessMessage(ctx, createExplodeMessage("rocket-c", 2, launchTime, "PRESSURE_VESSEL_FAILURE"))
+
+	purged, err := repo.PurgeRockets(ctx, RocketFilter{Mission: "artemis"})
+	if err != nil || len(purged) != 2 || purged[0] != "rocket-a" || purged[1] != "rocket-b" {
+		t.Fatalf("Expected rocket-a and rocket-b to be purged, got %v, %v", purged, err)
+	}
+	if count, _ := repo.RocketCount(ctx); count != 1 {
+		t.Errorf("Expected 1 rocket left, got %d", count)
+	}
+
+	purged, _ = repo.PurgeRockets(ctx, RocketFilter{Status: "active"})
+	if len(purged) != 0 {
+		t.Errorf("Expected nothing to be purged, got %v", purged)
+	}
+}
+
+func TestOverrideRocket(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 3, launchTime, 100))
+
+	at := func(version uint64) func(uint64) bool {
+		return func(current uint64) bool { return current == version }
+	}
+	mission, speed := "APOLLO", 0
+
+	// A correction based on an old version is refused
+	rocket, err := repo.OverrideRocket(ctx, "rocket-1", at(0), Override{Mission: &mission})
+	if !errors.Is(err, ErrVersionMismatch) || rocket.Version != 1 {
+		t.Fatalf("Expected a version mismatch at version 1, got %+v, %v", rocket, err)
+	}
+	if _, err := repo.OverrideRocket(ctx, "missing", at(1), Override{Mission: &mission}); !errors.Is(err, ErrRocketNotFound) {
+		t.Errorf("Expected ErrRocketNotFound, got %v", err)
+	}
+
+	changed := repo.Changed()
+	rocket, err = repo.OverrideRocket(ctx, "rocket-1", at(1), Override{Mission: &mission, Speed: &speed, Actor: "ops", Reason: "missed"})
+	if err != nil {
+		t.Fatalf("OverrideRocket failed: %v", err)
+	}
+	if rocket.Mission != "APOLLO" || rocket.Speed != 0 || rocket.Type != "Falcon-9" || rocket.Version != 2 {
+		t.Errorf("Unexpected rocket: %+v", rocket)
+	}
+	override := rocket.Overrides["mission"]
+	if override.Value != "APOLLO" || override.Previous != "ARTEMIS" || override.Actor != "ops" || override.Reason != "missed" || override.Version != 2 {
+		t.Errorf("Unexpected mission override: %+v", override)
+	}
+	if _, ok := rocket.Overrides["type"]; ok || rocket.Overrides["speed"].Previous != 500 {
+		t.Errorf("Expected overrides of mission and speed only, got %+v", rocket.Overrides)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected Changed to fire")
+	}
+
+	// The emitter's numbering is untouched: message 2 still fills the gap,
+	// and applies on top of the corrected speed
+	if outcome := repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 2, launchTime, 100)); outcome.Status != StatusApplied || outcome.Drained != 1 {
+		t.Errorf("Expected message 2 to apply and drain message 3, got %+v", outcome)
+	}
+	// The speed they changed is no longer reported as corrected
+	rocket, _ = repo.GetRocket(ctx, "rocket-1")
+	if rocket.Speed != 200 || rocket.Version != 4 || rocket.Overrides["mission"].Version != 2 {
+		t.Errorf("Unexpected rocket after the emitter caught up: %+v", rocket)
+	}
+	if _, ok := rocket.Overrides["speed"]; ok || len(rocket.Overrides) != 1 {
+		t.Errorf("Expected only the mission override to be left, got %+v", rocket.Overrides)
+	}
+
+	// A rocket not launched yet has no type or mission, and can still have
+	// its speed corrected
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-2", 2, launchTime, 100))
+	speed = 300
+	if rocket, err := repo.OverrideRocket(ctx, "rocket-2", at(0), Override{Speed: &speed}); err != nil || rocket.Speed != 300 || rocket.Type != "" {
+		t.Errorf("Expected the speed of an unlaunched rocket to be corrected, got %+v, %v", rocket, err)
+	}
+	empty := ""
+	if _, err := repo.OverrideRocket(ctx, "rocket-2", at(1), Override{Mission: &empty}); !errors.Is(err, ErrInvalidOverride) {
+		t.Errorf("Expected ErrInvalidOverride for an empty mission, got %v", err)
+	}
+}
+
+func TestReplayRocket(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 3, launchTime.Add(2*time.Second), 100))
+
+	mission, speed := "APOLLO", 0
+	if _, err := repo.OverrideRocket(ctx, "rocket-1", func(uint64) bool { return true }, Override{Mission: &mission, Speed: &speed}); err != nil {
+		t.Fatalf("OverrideRocket failed: %v", err)
+	}
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 2, launchTime.Add(time.Second), 100))
+
+	// The correction is reapplied between the messages it came between, so
+	// the replay ends with the corrected mission and the speed built on it
+	replay, err := repo.ReplayRocket(ctx, "rocket-1")
+	if err != nil {
+		t.Fatalf("ReplayRocket failed: %v", err)
+	}
+	if replay.Rocket.Mission != "APOLLO" || replay.Rocket.Speed != 200 || replay.Rocket.Type != "Falcon-9" || replay.Rocket.LastProcessedMessageNumber != 3 {
+		t.Errorf("Expected the corrected values back, got %+v", replay.Rocket)
+	}
+	if replay.Messages != 4 || replay.Folded != 0 || !replay.Matches {
+		t.Errorf("Expected four messages replayed to the live state, got %+v", replay)
+	}
+
+	if _, err := repo.ReplayRocket(ctx, "missing"); !errors.Is(err, ErrRocketNotFound) {
+		t.Errorf("Expected ErrRocketNotFound, got %v", err)
+	}
+}
+
+func TestReplayRocketFoldsHistory(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 0, "ARTEMIS"))
+	for n := 2; n <= HistoryCapacity+10; n++ {
+		repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", n, launchTime, 1))
+	}
+
+	// The oldest messages are folded into the starting state, which keeps
+	// the replay right
+	replay, err := repo.ReplayRocket(ctx, "rocket-1")
+	if err != nil {
+		t.Fatalf("ReplayRocket failed: %v", err)
+	}
+	if replay.Messages != HistoryCapacity || replay.Folded != 10 || !replay.Matches || replay.Rocket.Speed != HistoryCapacity+9 {
+		t.Errorf("Unexpected replay: messages %d, folded %d, matches %v, speed %d", replay.Messages, replay.Folded, replay.Matches, replay.Rocket.Speed)
+	}
+}
+
+func TestPauseAndResumeRocket(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+
+	if _, err := repo.PauseRocket(ctx, "missing"); !errors.Is(err, ErrRocketNotFound) {
+		t.Errorf("Expected ErrRocketNotFound, got %v", err)
+	}
+
+	changed := repo.Changed()
+	if state, err := repo.PauseRocket(ctx, "rocket-1"); err != nil || !state.Paused {
+		t.Fatalf("Expected the rocket to be paused, got %+v, %v", state, err)
+	}
+	select {
+	case <-changed:
+	default:
+		t.Error("Expected Changed to fire when pausing")
+	}
+	if state, _ := repo.PauseRocket(ctx, "rocket-1"); !state.Paused {
+		t.Errorf("Expected pausing twice to keep the rocket paused, got %+v", state)
+	}
+
+	// Messages are held, even the next one in sequence, and duplicates are
+	// still recognized
+	outcome := repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 2, launchTime, 100))
+	if outcome.Status != StatusBuffered || outcome.Reason == "" {
+		t.Errorf("Expected message 2 to be held, got %+v", outcome)
+	}
+	repo.ProcessMessage(ctx, createMissionChangeMessage("rocket-1", 3, launchTime, "APOLLO"))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 5, launchTime, 100))
+	if outcome := repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 2, launchTime, 100)); outcome.Status != StatusDuplicate {
+		t.Errorf("Expected a duplicate, got %+v", outcome)
+	}
+	rocket, _ := repo.GetRocket(ctx, "rocket-1")
+	if !rocket.Paused || rocket.Speed != 500 || rocket.Mission != "ARTEMIS" || rocket.Version != 2 {
+		t.Errorf("Expected the state to be frozen at version 2, got %+v", rocket)
+	}
+
+	// Resuming applies the held messages in order, up to the gap
+	state, err := repo.ResumeRocket(ctx, "rocket-1")
+	if err != nil || state.Paused || state.Drained != 2 || state.Held != 1 {
+		t.Fatalf("Unexpected resume: %+v, %v", state, err)
+	}
+	rocket, _ = repo.GetRocket(ctx, "rocket-1")
+	if rocket.Paused || rocket.Speed != 600 || rocket.Mission != "APOLLO" || rocket.Version != 5 {
+		t.Errorf("Unexpected rocket after resuming: %+v", rocket)
+	}
+	if state, _ := repo.ResumeRocket(ctx, "rocket-1"); state.Drained != 0 || state.Held != 1 {
+		t.Errorf("Expected resuming twice to do nothing, got %+v", state)
+	}
+	if outcome := repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 4, launchTime, 100)); outcome.Status != StatusApplied || outcome.Drained != 1 {
+		t.Errorf("Expected message 4 to apply and drain message 5, got %+v", outcome)
+	}
+}
@@ -0,0 +1,29 @@
+package storage
+
+import "time"
+
+// Lock names reported to an Observer
+const (
+	LockRepository = "repository" // The mutex protecting the rockets map
+	LockRocket     = "rocket"     // The per-rocket mutex protecting state and buffer
+)
+
+// Observer receives instrumentation events from the repository.
+// Implementations must be safe for concurrent use.
+type Observer interface {
+	// ObserveLockWait reports how long it took to acquire a ContextMutex
+	ObserveLockWait(lock string, wait time.Duration)
+
+	// ObserveOutcome reports the outcome of processing a message
+	ObserveOutcome(messageType string, outcome ProcessOutcome)
+
+	// ObserveBufferDepth reports a rocket's buffer length after a message was processed
+	ObserveBufferDepth(depth int)
+}
+
+// noopObserver discards all events
+type noopObserver struct{}
+
+func (noopObserver) ObserveLockWait(string, time.Duration) {}
+func (noopObserver) ObserveOutcome(string, ProcessOutcome) {}
+func (noopObserver) ObserveBufferDepth(int)                {}
@@ -0,0 +1,38 @@
+package storage
+
+// ProcessStatus describes what the repository did with an incoming message
+type ProcessStatus string
+
+// Processing status constants
+const (
+	StatusApplied               ProcessStatus = "applied"                 // Applied to the rocket state
+	StatusBuffered              ProcessStatus = "buffered"                // Held until the missing messages arrive
+	StatusDuplicate             ProcessStatus = "duplicate"               // Already processed or already buffered
+	StatusConflict              ProcessStatus = "conflict"                // Same message number buffered with different content
+	StatusIgnoredAfterExplosion ProcessStatus = "ignored_after_explosion" // Rocket exploded and the message is not a relaunch; newer messages are quarantined
+	StatusRejected              ProcessStatus = "rejected"                // Unknown type or the update could not be applied
+	StatusCancelled             ProcessStatus = "cancelled"               // Request context ended before processing finished
+)
+
+// ProcessOutcome is the result of processing a single message
+type ProcessOutcome struct {
+	Status ProcessStatus `json:"status"`
+	Reason string        `json:"reason,omitempty"` // Explanation for rejected, conflicting, cancelled or held messages
+	// Number of previously buffered messages applied as a consequence of this message
+	Drained int `json:"drained"`
+}
+
+// Processed reports whether the message was accepted, either applied or buffered
+func (o ProcessOutcome) Processed() bool {
+	return o.Status == StatusApplied || o.Status == StatusBuffered
+}
+
+// cancelledOutcome builds the outcome for a context that ended early
+func cancelledOutcome(err error) ProcessOutcome {
+	return ProcessOutcome{Status: StatusCancelled, Reason: err.Error()}
+}
+
+// rejectedOutcome builds the outcome for a message that could not be applied
+func rejectedOutcome(err error) ProcessOutcome {
+	return ProcessOutcome{Status: StatusRejected, Reason: err.Error()}
+}
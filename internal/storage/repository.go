@@ -3,7 +3,13 @@ package storage
 import (
 	"container/heap"
 	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
 
+	"github.com/rah-0/lunar/internal/messages"
 	"github.com/rah-0/lunar/internal/models"
 	"golang.org/x/sync/semaphore"
 )
@@ -17,7 +23,42 @@ type RocketRepository interface {
 	ListRockets(ctx context.Context, sortField, order string) ([]models.RocketSummary, error)
 
 	// ProcessMessage processes a rocket message using the Envelope format
-	ProcessMessage(ctx context.Context, envelope models.Envelope) bool
+	// and reports what was done with it
+	ProcessMessage(ctx context.Context, envelope models.Envelope) ProcessOutcome
+
+	// WaitForVersion blocks until the rocket's version exceeds version and
+	// returns its state. It fails with ErrRocketNotFound for unknown rockets
+	// and with the context's error when ctx is done first.
+	WaitForVersion(ctx context.Context, id string, version uint64) (*models.RocketState, error)
+
+	// Changed returns a channel that is closed the next time any rocket's
+	// version changes. Callers take the channel before reading the state
+	// so that no change is missed.
+	Changed() <-chan struct{}
+
+	// Quarantine returns the messages set aside because the rocket exploded,
+	// in message number order, with how many were dropped to respect
+	// QuarantineCapacity. It fails with ErrRocketNotFound for unknown rockets.
+	Quarantine(ctx context.Context, id string) (QuarantineState, error)
+}
+
+// ErrRocketNotFound is returned for rockets the repository does not track
+var ErrRocketNotFound = errors.New("rocket not found")
+
+// Reasons a message is quarantined
+const (
+	QuarantineArrivedAfterExplosion = "arrived_after_explosion" // The rocket had exploded and the message does not relaunch it
+	QuarantineBufferedAtExplosion   = "buffered_at_explosion"   // The message was waiting in the buffer when the rocket exploded
+)
+
+// QuarantineCapacity is the number of messages a rocket's quarantine keeps.
+// When it is full, the oldest message makes room for the new one.
+const QuarantineCapacity = 1000
+
+// QuarantineState describes a rocket's quarantine
+type QuarantineState struct {
+	Messages []models.QuarantinedMessage `json:"messages"` // In message number order
+	Dropped  int                         `json:"dropped"`  // Messages evicted because the quarantine was full
 }
 
 // MessageBuffer is a priority queue for out-of-order messages
@@ -48,21 +89,195 @@ func (mb *MessageBuffer) Pop() any {
 type rocketEntry struct {
 	State  *models.RocketState
 	Buffer *MessageBuffer
-	Mu     *ContextMutex // Protects both State and Buffer
+	Mu     *ContextMutex // Protects State, Buffer and changed
+
+	// changed is closed and replaced whenever the version is incremented,
+	// waking up everyone waiting for the rocket to change
+	changed chan struct{}
+
+	// deleted is set when an operator removed the rocket, so that waiters
+	// holding the entry stop waiting
+	deleted bool
+
+	// quarantine holds the messages set aside because the rocket exploded,
+	// in the order they were set aside, and quarantineDropped counts those
+	// evicted to respect QuarantineCapacity
+	quarantine        []models.QuarantinedMessage
+	quarantineDropped int
+
+	// history holds the messages applied to the rocket, operator
+	// corrections included, in the order they were applied, so that
+	// ReplayRocket can rebuild the state. historyBase is the state the
+	// history starts from, into which historyFolded messages were folded to
+	// respect HistoryCapacity.
+	history       []appliedMessage
+	historyBase   *models.RocketState
+	historyFolded int
+}
+
+// newRocketEntry creates the entry of a rocket with an empty buffer
+func newRocketEntry(state *models.RocketState) *rocketEntry {
+	buffer := &MessageBuffer{}
+	heap.Init(buffer)
+	return &rocketEntry{
+		State:       state,
+		Buffer:      buffer,
+		Mu:          NewContextMutex(),
+		changed:     make(chan struct{}),
+		historyBase: &models.RocketState{ID: state.ID, Type: state.Type, Speed: state.Speed, Mission: state.Mission},
+	}
+}
+
+// recordApplied updates the rocket's bookkeeping and history after a
+// message of messageType was applied and notifies waiters of the new version
+func (e *rocketEntry) recordApplied(messageType *messages.Type, envelope models.Envelope) {
+	e.State.LastProcessedMessageNumber = envelope.GetMessageNumber()
+	e.dropOverwrittenOverrides()
+	e.remember(messageType, envelope)
+	e.recordChange(envelope.GetMessageTime())
+}
+
+// dropOverwrittenOverrides forgets the operator corrections that a message
+// from the emitter replaced, so that the rocket only reports the fields that
+// still hold a corrected value
+func (e *rocketEntry) dropOverwrittenOverrides() {
+	current := map[string]any{"type": e.State.Type, "speed": e.State.Speed, "mission": e.State.Mission}
+	for field, override := range e.State.Overrides {
+		if override.Value != current[field] {
+			delete(e.State.Overrides, field)
+		}
+	}
+	if len(e.State.Overrides) == 0 {
+		e.State.Overrides = nil
+	}
+}
+
+// recordChange increments the version after the state changed at updatedAt
+// and notifies waiters
+func (e *rocketEntry) recordChange(updatedAt time.Time) {
+	e.State.UpdatedAt = updatedAt
+	e.bumpVersion()
+}
+
+// bumpVersion increments the version and notifies waiters, for changes that
+// do not come from the rocket itself such as pausing it
+func (e *rocketEntry) bumpVersion() {
+	e.State.Version++
+	close(e.changed)
+	e.changed = make(chan struct{})
+}
+
+// snapshot returns a deep copy of the state without the mutex.
+// The entry must be locked.
+func (e *rocketEntry) snapshot() *models.RocketState {
+	return &models.RocketState{
+		ID:                         e.State.ID,
+		Type:                       e.State.Type,
+		Speed:                      e.State.Speed,
+		Mission:                    e.State.Mission,
+		Exploded:                   e.State.Exploded,
+		Reason:                     e.State.Reason,
+		UpdatedAt:                  e.State.UpdatedAt,
+		CreatedAt:                  e.State.CreatedAt,
+		Version:                    e.State.Version,
+		Paused:                     e.State.Paused,
+		Overrides:                  maps.Clone(e.State.Overrides),
+		LastProcessedMessageNumber: e.State.LastProcessedMessageNumber,
+	}
+}
+
+// summary returns the listing form of the state. The entry must be locked.
+func (e *rocketEntry) summary() models.RocketSummary {
+	status := "active"
+	if e.State.Exploded {
+		status = "exploded"
+	}
+	return models.RocketSummary{
+		ID:        e.State.ID,
+		Type:      e.State.Type,
+		Speed:     e.State.Speed,
+		Mission:   e.State.Mission,
+		Status:    status,
+		UpdatedAt: e.State.UpdatedAt,
+		Version:   e.State.Version,
+	}
 }
 
 // InMemoryRepository is an in-memory implementation of RocketRepository
 type InMemoryRepository struct {
-	mu      *ContextMutex // Protects the rockets map only
-	rockets map[string]*rocketEntry
+	mu       *ContextMutex // Protects the rockets and tombstones maps only
+	rockets  map[string]*rocketEntry
+	observer Observer
+	rejected RejectionHandler
+
+	// tombstones holds the last version of deleted rockets, so that a
+	// rocket created again under the same ID continues above it and cached
+	// ETags of the deleted rocket never match the new one
+	tombstones map[string]uint64
+	registry   *messages.Registry
+
+	changedMu sync.Mutex    // Protects changed
+	changed   chan struct{} // Closed and replaced whenever any rocket changes
 }
 
 // NewInMemoryRepository creates a new in-memory repository
 func NewInMemoryRepository() *InMemoryRepository {
 	return &InMemoryRepository{
-		mu:      NewContextMutex(),
-		rockets: make(map[string]*rocketEntry),
+		mu:         NewContextMutex(),
+		rockets:    make(map[string]*rocketEntry),
+		tombstones: make(map[string]uint64),
+		observer:   noopObserver{},
+		rejected:   func(models.Envelope, error) {},
+		registry:   messages.Builtin(),
+		changed:    make(chan struct{}),
+	}
+}
+
+// SetRegistry replaces the message types the repository can apply.
+// It must be called before the repository is used concurrently.
+func (r *InMemoryRepository) SetRegistry(registry *messages.Registry) {
+	r.registry = registry
+}
+
+// SetObserver installs an Observer for instrumentation events.
+// It must be called before the repository is used concurrently.
+func (r *InMemoryRepository) SetObserver(o Observer) {
+	if o == nil {
+		o = noopObserver{}
+	}
+	r.observer = o
+}
+
+// RejectionHandler receives the buffered messages that are dropped because
+// they could not be applied once their turn came. It is called with the
+// rocket locked and must not call back into the repository.
+type RejectionHandler func(envelope models.Envelope, err error)
+
+// SetRejectionHandler installs the handler for rejected buffered messages.
+// It must be called before the repository is used concurrently.
+func (r *InMemoryRepository) SetRejectionHandler(h RejectionHandler) {
+	if h == nil {
+		h = func(models.Envelope, error) {}
 	}
+	r.rejected = h
+}
+
+// RocketCount returns the number of rockets currently tracked
+func (r *InMemoryRepository) RocketCount(ctx context.Context) (int, error) {
+	if err := r.lock(ctx, r.mu, LockRepository); err != nil {
+		return 0, err
+	}
+	defer r.mu.Unlock()
+
+	return len(r.rockets), nil
+}
+
+// lock acquires m and reports the time spent waiting to the observer
+func (r *InMemoryRepository) lock(ctx context.Context, m *ContextMutex, name string) error {
+	start := time.Now()
+	err := m.Lock(ctx)
+	r.observer.ObserveLockWait(name, time.Since(start))
+	return err
 }
 
 func (r *InMemoryRepository) GetRocket(ctx context.Context, id string) (*models.RocketState, bool) {
@@ -72,7 +287,7 @@ func (r *InMemoryRepository) GetRocket(ctx context.Context, id string) (*models.
 	}
 
 	// Get a read lock on the repository
-	if err := r.mu.Lock(ctx); err != nil {
+	if err := r.lock(ctx, r.mu, LockRepository); err != nil {
 		return nil, false
 	}
 
@@ -83,23 +298,13 @@ func (r *InMemoryRepository) GetRocket(ctx context.Context, id string) (*models.
 	}
 
 	// Get a read lock on the entry
-	if err := entry.Mu.Lock(ctx); err != nil {
+	if err := r.lock(ctx, entry.Mu, LockRocket); err != nil {
 		r.mu.Unlock()
 		return nil, false
 	}
 
 	// Create a deep copy of the state without the mutex
-	rocketCopy := &models.RocketState{
-		ID:                         entry.State.ID,
-		Type:                       entry.State.Type,
-		Speed:                      entry.State.Speed,
-		Mission:                    entry.State.Mission,
-		Exploded:                   entry.State.Exploded,
-		Reason:                     entry.State.Reason,
-		UpdatedAt:                  entry.State.UpdatedAt,
-		CreatedAt:                  entry.State.CreatedAt,
-		LastProcessedMessageNumber: entry.State.LastProcessedMessageNumber,
-	}
+	rocketCopy := entry.snapshot()
 
 	// Unlock in reverse order of locking
 	entry.Mu.Unlock()
@@ -108,6 +313,57 @@ func (r *InMemoryRepository) GetRocket(ctx context.Context, id string) (*models.
 	return rocketCopy, true
 }
 
+// WaitForVersion blocks until the rocket's version exceeds version. It waits
+// on the rocket's change notification rather than polling.
+func (r *InMemoryRepository) WaitForVersion(ctx context.Context, id string, version uint64) (*models.RocketState, error) {
+	if err := r.lock(ctx, r.mu, LockRepository); err != nil {
+		return nil, err
+	}
+	entry, exists := r.rockets[id]
+	r.mu.Unlock()
+	if !exists {
+		return nil, ErrRocketNotFound
+	}
+
+	for {
+		if err := r.lock(ctx, entry.Mu, LockRocket); err != nil {
+			return nil, err
+		}
+		if entry.deleted {
+			entry.Mu.Unlock()
+			return nil, ErrRocketNotFound
+		}
+		if entry.State.Version > version {
+			rocketCopy := entry.snapshot()
+			entry.Mu.Unlock()
+			return rocketCopy, nil
+		}
+		changed := entry.changed
+		entry.Mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Changed returns a channel that is closed the next time any rocket changes
+func (r *InMemoryRepository) Changed() <-chan struct{} {
+	r.changedMu.Lock()
+	defer r.changedMu.Unlock()
+	return r.changed
+}
+
+// notifyChanged wakes up everyone waiting on Changed
+func (r *InMemoryRepository) notifyChanged() {
+	r.changedMu.Lock()
+	close(r.changed)
+	r.changed = make(chan struct{})
+	r.changedMu.Unlock()
+}
+
 // ContextMutex is a context-aware mutex that can be cancelled
 // It uses semaphore.Weighted under the hood to support context cancellation
 type ContextMutex struct {
@@ -143,7 +399,7 @@ func (r *InMemoryRepository) ListRockets(ctx context.Context, sortField, order s
 	}
 
 	// Get a read lock on the repository
-	if err := r.mu.Lock(ctx); err != nil {
+	if err := r.lock(ctx, r.mu, LockRepository); err != nil {
 		return nil, ctx.Err()
 	}
 	defer r.mu.Unlock()
@@ -159,25 +415,12 @@ func (r *InMemoryRepository) ListRockets(ctx context.Context, sortField, order s
 		}
 
 		// Try to acquire the lock with context
-		if err := entry.Mu.Lock(ctx); err != nil {
+		if err := r.lock(ctx, entry.Mu, LockRocket); err != nil {
 			return nil, ctx.Err()
 		}
 
 		// Process the entry while holding the lock
-		state := entry.State
-		status := "active"
-		if state.Exploded {
-			status = "exploded"
-		}
-
-		summaries = append(summaries, models.RocketSummary{
-			ID:        state.ID,
-			Type:      state.Type,
-			Speed:     state.Speed,
-			Mission:   state.Mission,
-			Status:    status,
-			UpdatedAt: state.UpdatedAt,
-		})
+		summaries = append(summaries, entry.summary())
 
 		// Unlock immediately after processing the entry
 		entry.Mu.Unlock()
@@ -199,93 +442,128 @@ func sortRocketSummaries(summaries []models.RocketSummary, field, order string)
 }
 
 // ProcessMessage processes a rocket message using the Envelope
-func (r *InMemoryRepository) ProcessMessage(ctx context.Context, envelope models.Envelope) bool {
+func (r *InMemoryRepository) ProcessMessage(ctx context.Context, envelope models.Envelope) ProcessOutcome {
+	outcome := r.processMessage(ctx, envelope)
+	r.observer.ObserveOutcome(envelope.GetMessageType(), outcome)
+	if outcome.Status == StatusApplied {
+		r.notifyChanged()
+	}
+	return outcome
+}
+
+// processMessage finds or creates the rocket entry and applies the message to it
+func (r *InMemoryRepository) processMessage(ctx context.Context, envelope models.Envelope) ProcessOutcome {
 	// Check if context is done before processing the message
 	if err := ctx.Err(); err != nil {
-		return false
+		return cancelledOutcome(err)
+	}
+
+	// Get the definition of this message type
+	messageType, ok := r.registry.Lookup(envelope.GetMessageType())
+	if !ok {
+		return rejectedOutcome(messages.ErrUnknownType)
 	}
 
 	// Get the rocket ID from the envelope
 	rocketID := envelope.Metadata.Channel
 
-	// Get a write lock on the repository
-	if err := r.mu.Lock(ctx); err != nil {
-		return false
+	// Process the message with proper ordering
+	msgCtx := MessageContext{
+		ID:       rocketID,
+		Envelope: envelope,
+		Type:     messageType,
+		Ctx:      ctx, // Pass through the original context
 	}
 
-	// Get or create the rocket entry
-	entry, exists := r.rockets[rocketID]
-	if !exists {
-		// Create a new rocket state
-		state := &models.RocketState{
-			ID:       rocketID,
-			Type:     envelope.Message.Type,
-			Mission:  "",
-			Speed:    0,
-			Exploded: false,
+	for {
+		// Get a write lock on the repository
+		if err := r.lock(ctx, r.mu, LockRepository); err != nil {
+			return cancelledOutcome(err)
 		}
 
-		// Create a new message buffer
-		buffer := &MessageBuffer{}
-		heap.Init(buffer)
+		// Get or create the rocket entry
+		entry, exists := r.rockets[rocketID]
+		if !exists {
+			// Create a new rocket state
+			state := &models.RocketState{
+				ID:       rocketID,
+				Type:     envelope.Message.Type,
+				Mission:  "",
+				Speed:    0,
+				Exploded: false,
+				Version:  r.tombstones[rocketID],
+			}
+			delete(r.tombstones, rocketID)
 
-		// Create a new entry with a new context mutex
-		entry = &rocketEntry{
-			State:  state,
-			Buffer: buffer,
-			Mu:     NewContextMutex(),
+			// Create a new entry with an empty buffer and a new context mutex
+			entry = newRocketEntry(state)
+			r.rockets[rocketID] = entry
 		}
-		r.rockets[rocketID] = entry
-	}
-
-	// We can unlock the repository mutex now that we have the entry
-	r.mu.Unlock()
 
-	// Get the update function for this message type
-	updateFunc := r.getUpdateFuncForMessage(envelope)
-	if updateFunc == nil {
-		return false
-	}
+		// We can unlock the repository mutex now that we have the entry
+		r.mu.Unlock()
 
-	// Process the message with proper ordering
-	msgCtx := MessageContext{
-		ID:         rocketID,
-		Envelope:   envelope,
-		UpdateFunc: updateFunc,
-		Ctx:        ctx, // Pass through the original context
+		// Process the message with ordering. The entry may have been deleted
+		// in between, in which case the message goes to a new one.
+		if outcome, ok := r.processMessageWithOrdering(entry, msgCtx); ok {
+			return outcome
+		}
 	}
-
-	// Process the message with ordering
-	return r.processMessageWithOrdering(entry, msgCtx)
 }
 
 // MessageContext groups related message processing parameters
 type MessageContext struct {
-	ID         string
-	Envelope   models.Envelope
-	UpdateFunc func(*models.RocketState) bool
-	Ctx        context.Context // Original context from the request
+	ID       string
+	Envelope models.Envelope
+	Type     *messages.Type  // Registered definition of the message type
+	Ctx      context.Context // Original context from the request
 }
 
-// processMessageWithOrdering processes messages in correct sequence using buffering
-func (r *InMemoryRepository) processMessageWithOrdering(entry *rocketEntry, ctx MessageContext) bool {
+// processMessageWithOrdering processes messages in correct sequence using
+// buffering. It reports false, without processing the message, when the
+// entry was deleted after it was looked up.
+func (r *InMemoryRepository) processMessageWithOrdering(entry *rocketEntry, ctx MessageContext) (ProcessOutcome, bool) {
 	// Lock the entry for the duration of processing
-	if err := entry.Mu.Lock(ctx.Ctx); err != nil {
-		return false
+	if err := r.lock(ctx.Ctx, entry.Mu, LockRocket); err != nil {
+		return cancelledOutcome(err), true
 	}
 	defer entry.Mu.Unlock()
+	if entry.deleted {
+		return ProcessOutcome{}, false
+	}
+	return r.applyInOrder(entry, ctx), true
+}
+
+// applyInOrder applies the message when it is the next expected one, and
+// buffers it otherwise. The entry must be locked.
+func (r *InMemoryRepository) applyInOrder(entry *rocketEntry, ctx MessageContext) ProcessOutcome {
+	defer func() { r.observer.ObserveBufferDepth(entry.Buffer.Len()) }()
 
 	rocket := entry.State
 	msgNum := ctx.Envelope.GetMessageNumber()
 
-	// If rocket has exploded, only allow relaunch messages
-	if rocket.Exploded && ctx.Envelope.GetMessageType() != models.MessageTypeRocketLaunched {
-		return false
+	// If rocket has exploded, only allow types such as relaunches. Newer
+	// messages are quarantined in case the rocket is relaunched.
+	if rocket.Exploded && !ctx.Type.AppliesAfterExplosion {
+		if msgNum <= rocket.LastProcessedMessageNumber {
+			return ProcessOutcome{Status: StatusIgnoredAfterExplosion}
+		}
+		entry.quarantineMessage(ctx.Envelope, QuarantineArrivedAfterExplosion)
+		return ProcessOutcome{Status: StatusIgnoredAfterExplosion, Reason: "quarantined until the rocket is relaunched"}
 	}
 
 	// Check if this is a duplicate or old message
 	if msgNum <= rocket.LastProcessedMessageNumber {
-		return false
+		return ProcessOutcome{Status: StatusDuplicate}
+	}
+
+	// Hold every message of a paused channel until an operator resumes it
+	if rocket.Paused {
+		outcome := r.bufferMessage(entry, ctx.Envelope)
+		if outcome.Status == StatusBuffered {
+			outcome.Reason = "channel is paused"
+		}
+		return outcome
 	}
 
 	// Check if this is the next expected message
@@ -294,42 +572,61 @@ func (r *InMemoryRepository) processMessageWithOrdering(entry *rocketEntry, ctx
 	// If this is the next expected message, process it immediately
 	if msgNum == expectedMsgNum {
 		// Apply the update
-		if !ctx.UpdateFunc(rocket) {
-			return false
-		}
-		rocket.LastProcessedMessageNumber = msgNum
-		rocket.UpdatedAt = ctx.Envelope.GetMessageTime()
-
-		// If rocket exploded, clean up its buffer
-		if rocket.Exploded {
-			entry.Buffer = &MessageBuffer{} // Clear the buffer
-			heap.Init(entry.Buffer)         // Initialize the new buffer
-			return true
+		exploded := rocket.Exploded
+		if err := ctx.Type.Apply(rocket, ctx.Envelope); err != nil {
+			return rejectedOutcome(err)
 		}
+		entry.recordApplied(ctx.Type, ctx.Envelope)
+		r.settleExplosion(entry, exploded)
 
 		// Process any buffered messages that can now be applied
-		r.processBufferedMessages(entry)
-		return true
+		drained := r.processBufferedMessages(entry)
+		return ProcessOutcome{Status: StatusApplied, Drained: drained}
 	}
 
 	// If we get here, the message is out of order and needs to be buffered
 	return r.bufferMessage(entry, ctx.Envelope)
 }
 
-// bufferMessage adds a message to the buffer in a thread-safe way
-func (r *InMemoryRepository) bufferMessage(entry *rocketEntry, envelope models.Envelope) bool {
+// bufferMessage adds a message to the buffer in a thread-safe way.
+// A message number that is already buffered is reported as a duplicate when
+// the content matches and as a conflict otherwise; the first copy is kept.
+func (r *InMemoryRepository) bufferMessage(entry *rocketEntry, envelope models.Envelope) ProcessOutcome {
+	for _, buffered := range *entry.Buffer {
+		if buffered.GetMessageNumber() != envelope.GetMessageNumber() {
+			continue
+		}
+		if sameEnvelope(*buffered, envelope) {
+			return ProcessOutcome{Status: StatusDuplicate}
+		}
+		return ProcessOutcome{
+			Status: StatusConflict,
+			Reason: "a different message with this number is already buffered",
+		}
+	}
+
 	// Create a copy of the envelope to avoid data races
 	envCopy := envelope
 	heap.Push(entry.Buffer, &envCopy)
-	return true
+	return ProcessOutcome{Status: StatusBuffered}
+}
+
+// sameEnvelope reports whether two envelopes carry the same metadata and content
+func sameEnvelope(a, b models.Envelope) bool {
+	return a.Metadata.Channel == b.Metadata.Channel &&
+		a.Metadata.MessageNumber == b.Metadata.MessageNumber &&
+		a.Metadata.MessageType == b.Metadata.MessageType &&
+		a.Metadata.MessageTime.Equal(b.Metadata.MessageTime) &&
+		a.Message == b.Message
 }
 
 // processBufferedMessages processes any buffered messages that can now be applied
 // in the correct order. It processes messages in sequence starting from the next
-// expected message number.
-func (r *InMemoryRepository) processBufferedMessages(entry *rocketEntry) {
+// expected message number, and returns how many of them were applied.
+func (r *InMemoryRepository) processBufferedMessages(entry *rocketEntry) int {
 	rocket := entry.State
 	buffer := entry.Buffer
+	applied := 0
 
 	for buffer.Len() > 0 {
 		// Peek at the next message without removing it
@@ -341,95 +638,114 @@ func (r *InMemoryRepository) processBufferedMessages(entry *rocketEntry) {
 			break
 		}
 
-		// Get the update function for this message type
-		updateFunc := r.getUpdateFuncForMessage(*nextMsg)
-		if updateFunc == nil {
-			// Remove the message we can't process
+		// Get the definition of this message type
+		messageType, ok := r.registry.Lookup(nextMsg.GetMessageType())
+		if !ok {
+			// Remove the message we can't process and report it
 			heap.Pop(buffer)
+			r.reject(*nextMsg, messages.ErrUnknownType)
 			continue
 		}
 
 		// Apply the update
-		if !updateFunc(rocket) {
-			// If the update fails, remove the message and continue
+		exploded := rocket.Exploded
+		if err := messageType.Apply(rocket, *nextMsg); err != nil {
+			// If the update fails, remove the message, report it and continue
 			heap.Pop(buffer)
+			r.reject(*nextMsg, err)
 			continue
 		}
 
-		// Update the last processed message number
-		rocket.LastProcessedMessageNumber = expectedMsgNum
-		rocket.UpdatedAt = nextMsg.GetMessageTime()
+		// Update the last processed message number and version
+		entry.recordApplied(messageType, *nextMsg)
+		applied++
 
 		// Remove the processed message from the buffer
 		heap.Pop(buffer)
 
-		// If the rocket exploded, clear the buffer and stop processing
-		if rocket.Exploded {
-			entry.Buffer = &MessageBuffer{}
-			heap.Init(entry.Buffer) // Initialize the new buffer
-			break
-		}
+		// An explosion or a relaunch moves messages between the buffer
+		// and the quarantine
+		r.settleExplosion(entry, exploded)
+		buffer = entry.Buffer
 	}
+
+	return applied
 }
 
-// getUpdateFuncForMessage returns the appropriate update function for a message type
-func (r *InMemoryRepository) getUpdateFuncForMessage(msg models.Envelope) func(*models.RocketState) bool {
-	switch msg.GetMessageType() {
-	case models.MessageTypeRocketLaunched:
-		return func(rocket *models.RocketState) bool {
-			if msg.Message.Type == "" || msg.Message.Mission == "" {
-				return false
-			}
-			rocket.Type = msg.Message.Type
-			rocket.Mission = msg.Message.Mission
-			rocket.Speed = msg.Message.LaunchSpeed
-			rocket.CreatedAt = msg.GetMessageTime()
-			rocket.Exploded = false
-			rocket.Reason = ""
-			return true
-		}
+// reject reports a buffered message that was dropped without being applied
+func (r *InMemoryRepository) reject(envelope models.Envelope, err error) {
+	r.observer.ObserveOutcome(envelope.GetMessageType(), rejectedOutcome(err))
+	r.rejected(envelope, err)
+}
 
-	case models.MessageTypeRocketSpeedIncreased:
-		return func(rocket *models.RocketState) bool {
-			if msg.Message.By <= 0 {
-				return false
+// settleExplosion moves messages between the buffer and the quarantine after
+// a message was applied to a rocket that was exploded or not before. An
+// explosion quarantines the buffered messages that no longer apply, and a
+// relaunch moves the quarantined messages numbered after it back to the
+// buffer, where they are applied once their numbers fit. The entry must be
+// locked.
+func (r *InMemoryRepository) settleExplosion(entry *rocketEntry, exploded bool) {
+	switch {
+	case !exploded && entry.State.Exploded:
+		kept := MessageBuffer{}
+		for _, buffered := range *entry.Buffer {
+			if messageType, ok := r.registry.Lookup(buffered.GetMessageType()); ok && messageType.AppliesAfterExplosion {
+				kept = append(kept, buffered)
+				continue
 			}
-			rocket.Speed += msg.Message.By
-			return true
+			entry.quarantineMessage(*buffered, QuarantineBufferedAtExplosion)
 		}
-
-	case models.MessageTypeRocketSpeedDecreased:
-		return func(rocket *models.RocketState) bool {
-			if msg.Message.By <= 0 {
-				return false
+		heap.Init(&kept)
+		entry.Buffer = &kept
+
+	case exploded && !entry.State.Exploded:
+		kept := []models.QuarantinedMessage{}
+		for _, quarantined := range entry.quarantine {
+			// Messages from before the relaunch, or conflicting with a
+			// buffered one, stay quarantined
+			if quarantined.Envelope.GetMessageNumber() <= entry.State.LastProcessedMessageNumber ||
+				r.bufferMessage(entry, quarantined.Envelope).Status == StatusConflict {
+				kept = append(kept, quarantined)
 			}
-			rocket.Speed -= msg.Message.By
-			if rocket.Speed < 0 {
-				rocket.Speed = 0
-			}
-			return true
 		}
+		entry.quarantine = kept
+	}
+}
 
-	case models.MessageTypeRocketExploded:
-		return func(rocket *models.RocketState) bool {
-			if msg.Message.Reason == "" {
-				return false
-			}
-			rocket.Exploded = true
-			rocket.Reason = msg.Message.Reason
-			return true
+// quarantineMessage sets a message aside, unless a message with the same
+// number already is. When the quarantine is full, the oldest message is
+// dropped. The entry must be locked.
+func (e *rocketEntry) quarantineMessage(envelope models.Envelope, reason string) {
+	for _, quarantined := range e.quarantine {
+		if quarantined.Envelope.GetMessageNumber() == envelope.GetMessageNumber() {
+			return
 		}
+	}
+	if len(e.quarantine) == QuarantineCapacity {
+		e.quarantine = e.quarantine[1:]
+		e.quarantineDropped++
+	}
+	e.quarantine = append(e.quarantine, models.QuarantinedMessage{
+		Envelope:      envelope,
+		Reason:        reason,
+		QuarantinedAt: time.Now().UTC(),
+	})
+}
 
-	case models.MessageTypeRocketMissionChanged:
-		return func(rocket *models.RocketState) bool {
-			if msg.Message.NewMission == "" {
-				return false
-			}
-			rocket.Mission = msg.Message.NewMission
-			return true
-		}
+// Quarantine returns a copy of the rocket's quarantine sorted by message number
+func (r *InMemoryRepository) Quarantine(ctx context.Context, id string) (QuarantineState, error) {
+	entry, err := r.lockedEntry(ctx, id)
+	if err != nil {
+		return QuarantineState{}, err
+	}
+	state := QuarantineState{Messages: slices.Clone(entry.quarantine), Dropped: entry.quarantineDropped}
+	entry.Mu.Unlock()
 
-	default:
-		return nil // Unknown message type
+	if state.Messages == nil {
+		state.Messages = []models.QuarantinedMessage{}
 	}
+	slices.SortStableFunc(state.Messages, func(a, b models.QuarantinedMessage) int {
+		return a.Envelope.GetMessageNumber() - b.Envelope.GetMessageNumber()
+	})
+	return state, nil
 }
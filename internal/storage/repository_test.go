@@ -2,9 +2,12 @@ package storage
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/rah-0/lunar/internal/messages"
 	"github.com/rah-0/lunar/internal/models"
 )
 
@@ -21,11 +24,11 @@ func TestProcessMessageOutOfOrder(t *testing.T) {
 	speedIncreaseMsg := createSpeedIncreaseMessage(rocketID, 2, launchTime.Add(1*time.Second), 1000)
 
 	// Process message 2 first (out of order)
-	processed := repo.ProcessMessage(context.Background(), speedIncreaseMsg)
+	outcome := repo.ProcessMessage(context.Background(), speedIncreaseMsg)
 
 	// Should be accepted and buffered
-	if !processed {
-		t.Errorf("Expected speed increase message to be buffered, but it was rejected")
+	if outcome.Status != StatusBuffered {
+		t.Errorf("Expected speed increase message to be buffered, got %q", outcome.Status)
 	}
 
 	// Verify rocket was created but speed is NOT increased yet (message is buffered)
@@ -42,11 +45,14 @@ func TestProcessMessageOutOfOrder(t *testing.T) {
 
 	// Now send message 1 - should be processed and then message 2 should be applied from the buffer
 	launchMsg := createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "TEST-MISSION")
-	processed = repo.ProcessMessage(context.Background(), launchMsg)
+	outcome = repo.ProcessMessage(context.Background(), launchMsg)
 
-	// Should be processed
-	if !processed {
-		t.Errorf("Expected launch message to be processed, but it was rejected")
+	// Should be applied, draining the buffered message
+	if outcome.Status != StatusApplied {
+		t.Errorf("Expected launch message to be applied, got %q", outcome.Status)
+	}
+	if outcome.Drained != 1 {
+		t.Errorf("Expected 1 buffered message to be drained, got %d", outcome.Drained)
 	}
 
 	// Verify rocket state is updated from BOTH messages (message 1 and then buffered message 2)
@@ -69,11 +75,11 @@ func TestProcessMessageOutOfOrder(t *testing.T) {
 
 	// Now try to process a duplicate message (same number as already processed)
 	duplicateMsg := createLaunchMessage(rocketID, 1, launchTime, "Duplicate-Rocket", 999, "DUPLICATE")
-	processed = repo.ProcessMessage(context.Background(), duplicateMsg)
+	outcome = repo.ProcessMessage(context.Background(), duplicateMsg)
 
 	// Should be ignored as a duplicate
-	if processed {
-		t.Errorf("Expected duplicate message to be ignored, but it was processed")
+	if outcome.Status != StatusDuplicate {
+		t.Errorf("Expected duplicate message to be ignored, got %q", outcome.Status)
 	}
 
 	// Verify rocket state was not changed by duplicate message
@@ -94,9 +100,9 @@ func TestProcessDuplicateMessages(t *testing.T) {
 	launchMsg := createLaunchMessage(rocketID, 1, launchTime, "Falcon-Heavy", 700, "DUPLICATE-TEST")
 
 	// Process original message
-	processed := repo.ProcessMessage(context.Background(), launchMsg)
-	if !processed {
-		t.Errorf("Expected launch message to be processed, but it was rejected")
+	outcome := repo.ProcessMessage(context.Background(), launchMsg)
+	if outcome.Status != StatusApplied {
+		t.Errorf("Expected launch message to be applied, got %q", outcome.Status)
 	}
 
 	// Verify initial state
@@ -107,9 +113,9 @@ func TestProcessDuplicateMessages(t *testing.T) {
 	}
 
 	// Try to process the same message again
-	processed = repo.ProcessMessage(context.Background(), launchMsg)
-	if processed {
-		t.Errorf("Expected duplicate message to be rejected, but it was processed")
+	outcome = repo.ProcessMessage(context.Background(), launchMsg)
+	if outcome.Status != StatusDuplicate {
+		t.Errorf("Expected duplicate message to be rejected, got %q", outcome.Status)
 	}
 
 	// Verify rocket state hasn't changed
@@ -120,9 +126,9 @@ func TestProcessDuplicateMessages(t *testing.T) {
 
 	// Process new message with higher message number
 	speedIncreaseMsg := createSpeedIncreaseMessage(rocketID, 2, launchTime.Add(1*time.Second), 300)
-	processed = repo.ProcessMessage(context.Background(), speedIncreaseMsg)
-	if !processed {
-		t.Errorf("Expected speed increase message to be processed, but it was rejected")
+	outcome = repo.ProcessMessage(context.Background(), speedIncreaseMsg)
+	if outcome.Status != StatusApplied {
+		t.Errorf("Expected speed increase message to be applied, got %q", outcome.Status)
 	}
 
 	// Verify rocket state updated
@@ -132,9 +138,9 @@ func TestProcessDuplicateMessages(t *testing.T) {
 	}
 
 	// Try to process a duplicate of the second message
-	processed = repo.ProcessMessage(context.Background(), speedIncreaseMsg)
-	if processed {
-		t.Errorf("Expected duplicate speed increase message to be rejected, but it was processed")
+	outcome = repo.ProcessMessage(context.Background(), speedIncreaseMsg)
+	if outcome.Status != StatusDuplicate {
+		t.Errorf("Expected duplicate speed increase message to be rejected, got %q", outcome.Status)
 	}
 }
 
@@ -170,9 +176,9 @@ func TestRocketLifecycle(t *testing.T) {
 
 	// Explode rocket
 	explodeMsg := createExplodeMessage(rocketID, 4, launchTime.Add(3*time.Second), "ENGINE_FAILURE")
-	processed := repo.ProcessMessage(context.Background(), explodeMsg)
-	if !processed {
-		t.Error("Expected explosion message to be processed, but it was rejected")
+	outcome := repo.ProcessMessage(context.Background(), explodeMsg)
+	if outcome.Status != StatusApplied {
+		t.Errorf("Expected explosion message to be applied, got %q", outcome.Status)
 	}
 
 	// Check state
@@ -188,10 +194,10 @@ func TestRocketLifecycle(t *testing.T) {
 
 	// Try to increase speed after explosion (should be rejected)
 	finalSpeedMsg := createSpeedIncreaseMessage(rocketID, 5, launchTime.Add(4*time.Second), 200)
-	speedProcessed := repo.ProcessMessage(context.Background(), finalSpeedMsg)
+	outcome = repo.ProcessMessage(context.Background(), finalSpeedMsg)
 
-	if speedProcessed {
-		t.Errorf("Expected message to be rejected after explosion")
+	if outcome.Status != StatusIgnoredAfterExplosion {
+		t.Errorf("Expected message to be ignored after explosion, got %q", outcome.Status)
 	}
 
 	// Check final state
@@ -201,8 +207,254 @@ func TestRocketLifecycle(t *testing.T) {
 	}
 }
 
+func TestProcessMessageOutcomes(t *testing.T) {
+	repo := NewInMemoryRepository()
+	rocketID := "test-rocket-outcomes"
+	launchTime := time.Now()
+
+	// Buffer message 3, then send a different message 3
+	buffered := createSpeedIncreaseMessage(rocketID, 3, launchTime.Add(2*time.Second), 100)
+	if outcome := repo.ProcessMessage(context.Background(), buffered); outcome.Status != StatusBuffered {
+		t.Fatalf("Expected message 3 to be buffered, got %q", outcome.Status)
+	}
+	if outcome := repo.ProcessMessage(context.Background(), buffered); outcome.Status != StatusDuplicate {
+		t.Errorf("Expected identical buffered message to be a duplicate, got %q", outcome.Status)
+	}
+	conflicting := createSpeedIncreaseMessage(rocketID, 3, launchTime.Add(2*time.Second), 999)
+	outcome := repo.ProcessMessage(context.Background(), conflicting)
+	if outcome.Status != StatusConflict || outcome.Reason == "" {
+		t.Errorf("Expected conflict with a reason, got %q (%q)", outcome.Status, outcome.Reason)
+	}
+
+	// A launch without mission is rejected with a reason
+	invalid := createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "")
+	outcome = repo.ProcessMessage(context.Background(), invalid)
+	if outcome.Status != StatusRejected || outcome.Reason == "" {
+		t.Errorf("Expected rejection with a reason, got %q (%q)", outcome.Status, outcome.Reason)
+	}
+
+	// Unknown message types are rejected
+	unknown := createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "OUTCOMES")
+	unknown.Metadata.MessageType = "RocketTeleported"
+	if outcome := repo.ProcessMessage(context.Background(), unknown); outcome.Status != StatusRejected {
+		t.Errorf("Expected unknown message type to be rejected, got %q", outcome.Status)
+	}
+
+	// A cancelled context is reported as such
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	launch := createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "OUTCOMES")
+	if outcome := repo.ProcessMessage(ctx, launch); outcome.Status != StatusCancelled {
+		t.Errorf("Expected cancelled outcome, got %q", outcome.Status)
+	}
+
+	// Launch and message 2 drain the original message 3
+	repo.ProcessMessage(context.Background(), launch)
+	second := createSpeedIncreaseMessage(rocketID, 2, launchTime.Add(time.Second), 50)
+	outcome = repo.ProcessMessage(context.Background(), second)
+	if outcome.Status != StatusApplied || outcome.Drained != 1 {
+		t.Errorf("Expected applied with 1 drained, got %q with %d drained", outcome.Status, outcome.Drained)
+	}
+
+	rocket, _ := repo.GetRocket(context.Background(), rocketID)
+	if rocket.Speed != 650 {
+		t.Errorf("Expected speed 650 from the first copy of message 3, got %d", rocket.Speed)
+	}
+}
+
+// emptyPayload is the body of a message type without fields
+type emptyPayload struct{}
+
+func (emptyPayload) Validate() error                { return nil }
+func (emptyPayload) Content() models.MessageContent { return models.MessageContent{} }
+
+func TestCustomMessageType(t *testing.T) {
+	registry := messages.Builtin()
+	registry.MustRegister(messages.Type{
+		Name:       "RocketSpeedReset",
+		NewPayload: func() messages.Payload { return &emptyPayload{} },
+		Apply: func(rocket *models.RocketState, _ models.Envelope) error {
+			rocket.Speed = 0
+			return nil
+		},
+	})
+	repo := NewInMemoryRepository()
+	repo.SetRegistry(registry)
+
+	rocketID := "test-rocket-custom"
+	launchTime := time.Now()
+	repo.ProcessMessage(context.Background(), createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "CUSTOM"))
+
+	reset := createSpeedIncreaseMessage(rocketID, 2, launchTime.Add(time.Second), 0)
+	reset.Metadata.MessageType = "RocketSpeedReset"
+	if outcome := repo.ProcessMessage(context.Background(), reset); outcome.Status != StatusApplied {
+		t.Fatalf("Expected the custom type to be applied, got %q (%s)", outcome.Status, outcome.Reason)
+	}
+
+	rocket, _ := repo.GetRocket(context.Background(), rocketID)
+	if rocket.Speed != 0 || rocket.LastProcessedMessageNumber != 2 {
+		t.Errorf("Expected speed reset by message 2, got speed %d at message %d", rocket.Speed, rocket.LastProcessedMessageNumber)
+	}
+}
+
+func TestBufferedMessageRejected(t *testing.T) {
+	repo := NewInMemoryRepository()
+	var rejected []models.Envelope
+	var reasons []error
+	repo.SetRejectionHandler(func(envelope models.Envelope, err error) {
+		rejected = append(rejected, envelope)
+		reasons = append(reasons, err)
+	})
+
+	rocketID := "test-rocket-rejected"
+	launchTime := time.Now()
+
+	// A speed change of zero is buffered out of order, and fails once applied
+	invalid := createSpeedIncreaseMessage(rocketID, 2, launchTime.Add(time.Second), 0)
+	if outcome := repo.ProcessMessage(context.Background(), invalid); outcome.Status != StatusBuffered {
+		t.Fatalf("Expected the message to be buffered, got %q", outcome.Status)
+	}
+	if len(rejected) != 0 {
+		t.Fatalf("Expected nothing rejected while buffered, got %d", len(rejected))
+	}
+
+	outcome := repo.ProcessMessage(context.Background(), createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "REJECTED"))
+	if outcome.Status != StatusApplied || outcome.Drained != 0 {
+		t.Errorf("Expected the launch applied with nothing drained, got %q with %d drained", outcome.Status, outcome.Drained)
+	}
+	if len(rejected) != 1 || rejected[0].GetMessageNumber() != 2 || reasons[0] == nil {
+		t.Fatalf("Expected message 2 to be reported as rejected, got %+v (%v)", rejected, reasons)
+	}
+
+	rocket, _ := repo.GetRocket(context.Background(), rocketID)
+	if rocket.Speed != 500 || rocket.LastProcessedMessageNumber != 1 {
+		t.Errorf("Expected the rocket untouched by message 2, got speed %d at message %d", rocket.Speed, rocket.LastProcessedMessageNumber)
+	}
+}
+
 // Helper functions to create test messages
 
+func TestRocketVersion(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	rocketID := "versioned-rocket"
+	launchTime := time.Now()
+
+	version := func() uint64 {
+		t.Helper()
+		rocket, exists := repo.GetRocket(ctx, rocketID)
+		if !exists {
+			t.Fatalf("Expected rocket to exist")
+		}
+		return rocket.Version
+	}
+
+	// Buffered messages do not change the version until they are applied
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage(rocketID, 2, launchTime.Add(time.Second), 100))
+	if v := version(); v != 0 {
+		t.Errorf("Expected version 0 while only buffered, got %d", v)
+	}
+
+	repo.ProcessMessage(ctx, createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	if v := version(); v != 2 {
+		t.Errorf("Expected version 2 after two applied messages, got %d", v)
+	}
+
+	// Duplicates are not updates
+	repo.ProcessMessage(ctx, createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	if v := version(); v != 2 {
+		t.Errorf("Expected version 2 after a duplicate, got %d", v)
+	}
+
+	summaries, _ := repo.ListRockets(ctx, "", "")
+	if len(summaries) != 1 || summaries[0].Version != 2 {
+		t.Errorf("Expected the summary to carry version 2, got %+v", summaries)
+	}
+}
+
+func TestWaitForVersion(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	rocketID := "awaited-rocket"
+	launchTime := time.Now()
+
+	if _, err := repo.WaitForVersion(ctx, rocketID, 0); !errors.Is(err, ErrRocketNotFound) {
+		t.Errorf("Expected ErrRocketNotFound, got %v", err)
+	}
+
+	repo.ProcessMessage(ctx, createLaunchMessage(rocketID, 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+
+	// A version that is already exceeded returns at once
+	rocket, err := repo.WaitForVersion(ctx, rocketID, 0)
+	if err != nil || rocket.Version != 1 {
+		t.Fatalf("Expected version 1, got %+v (%v)", rocket, err)
+	}
+
+	// Waiters are woken up by the next applied update
+	done := make(chan *models.RocketState)
+	go func() {
+		rocket, _ := repo.WaitForVersion(ctx, rocketID, 1)
+		done <- rocket
+	}()
+	time.Sleep(10 * time.Millisecond)
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage(rocketID, 2, launchTime.Add(time.Second), 100))
+	select {
+	case rocket := <-done:
+		if rocket == nil || rocket.Version != 2 || rocket.Speed != 600 {
+			t.Errorf("Expected version 2 at speed 600, got %+v", rocket)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Waiter was not woken up by the update")
+	}
+
+	// The wait ends with the context
+	timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := repo.WaitForVersion(timeoutCtx, rocketID, 2); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the wait to time out, got %v", err)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+
+	changed := repo.Changed()
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 2, launchTime.Add(time.Second), 100))
+	select {
+	case <-changed:
+		t.Fatalf("Buffered messages must not report a change")
+	default:
+	}
+
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	select {
+	case <-changed:
+	default:
+		t.Fatalf("Expected an applied message to report a change")
+	}
+	if repo.Changed() == changed {
+		t.Errorf("Expected a new channel for the next change")
+	}
+}
+
+func TestFilterRocketSummaries(t *testing.T) {
+	summaries := []models.RocketSummary{
+		{ID: "a", Type: "Falcon-9", Mission: "ARTEMIS", Status: "active"},
+		{ID: "b", Type: "Falcon-Heavy", Mission: "ARTEMIS", Status: "exploded"},
+		{ID: "c", Type: "Falcon-9", Mission: "APOLLO", Status: "exploded"},
+	}
+
+	if got := FilterRocketSummaries(summaries, RocketFilter{}); len(got) != 3 {
+		t.Errorf("Expected an empty filter to match everything, got %v", got)
+	}
+	got := FilterRocketSummaries(summaries, RocketFilter{Type: "falcon-9", Status: "Exploded"})
+	if len(got) != 1 || got[0].ID != "c" {
+		t.Errorf("Expected only rocket c, got %v", got)
+	}
+}
+
 func createLaunchMessage(rocketID string, msgNum int, msgTime time.Time, rocketType string, speed int, mission string) models.Envelope {
 	var env models.Envelope
 	env.Metadata.Channel = rocketID
@@ -252,3 +504,87 @@ func createExplodeMessage(rocketID string, msgNum int, msgTime time.Time, reason
 	}
 	return env
 }
+
+func TestQuarantine(t *testing.T) {
+	repo := NewInMemoryRepository()
+	ctx := context.Background()
+	launchTime := time.Now()
+	numbers := func(messages []models.QuarantinedMessage) []int {
+		var n []int
+		for _, message := range messages {
+			n = append(n, message.Envelope.GetMessageNumber())
+		}
+		return n
+	}
+
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 4, launchTime, 50))
+	repo.ProcessMessage(ctx, createMissionChangeMessage("rocket-1", 6, launchTime, "APOLLO"))
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 2, launchTime, 100))
+
+	// The explosion sets the buffered messages aside instead of dropping them
+	if outcome := repo.ProcessMessage(ctx, createExplodeMessage("rocket-1", 3, launchTime, "ENGINE_FAILURE")); outcome.Status != StatusApplied || outcome.Drained != 0 {
+		t.Errorf("Expected the explosion to be applied, got %+v", outcome)
+	}
+
+	// So are newer messages arriving afterwards, once each
+	outcome := repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 7, launchTime, 10))
+	if outcome.Status != StatusIgnoredAfterExplosion || outcome.Reason == "" {
+		t.Errorf("Expected message 7 to be quarantined, got %+v", outcome)
+	}
+	repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 7, launchTime, 10))
+	if outcome := repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 2, launchTime, 100)); outcome.Status != StatusIgnoredAfterExplosion || outcome.Reason != "" {
+		t.Errorf("Expected an old message to be ignored without quarantine, got %+v", outcome)
+	}
+
+	state, err := repo.Quarantine(ctx, "rocket-1")
+	quarantine := state.Messages
+	if err != nil || fmt.Sprint(numbers(quarantine)) != "[4 6 7]" {
+		t.Fatalf("Expected messages 4, 6 and 7 in quarantine, got %v, %v", numbers(quarantine), err)
+	}
+	if quarantine[0].Reason != QuarantineBufferedAtExplosion || quarantine[2].Reason != QuarantineArrivedAfterExplosion {
+		t.Errorf("Unexpected reasons: %+v", quarantine)
+	}
+
+	// A relaunch applies the quarantined messages numbered after it once they fit
+	if outcome := repo.ProcessMessage(ctx, createLaunchMessage("rocket-1", 4, launchTime, "Falcon-9", 1000, "ARTEMIS")); outcome.Status != StatusApplied || outcome.Drained != 0 {
+		t.Errorf("Expected the relaunch to be applied, got %+v", outcome)
+	}
+	state, _ = repo.Quarantine(ctx, "rocket-1")
+	if fmt.Sprint(numbers(state.Messages)) != "[4]" {
+		t.Errorf("Expected message 4 of the exploded flight to stay quarantined, got %v", numbers(state.Messages))
+	}
+	if outcome := repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-1", 5, launchTime, 5)); outcome.Status != StatusApplied || outcome.Drained != 2 {
+		t.Errorf("Expected message 5 to drain messages 6 and 7, got %+v", outcome)
+	}
+	rocket, _ := repo.GetRocket(ctx, "rocket-1")
+	if rocket.Exploded || rocket.Mission != "APOLLO" || rocket.Speed != 1015 || rocket.LastProcessedMessageNumber != 7 {
+		t.Errorf("Unexpected rocket after relaunch: %+v", rocket)
+	}
+
+	// A relaunch waiting in the buffer is kept at the explosion and applied
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-2", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-2", 3, launchTime, "Falcon-9", 200, "APOLLO"))
+	if outcome := repo.ProcessMessage(ctx, createExplodeMessage("rocket-2", 2, launchTime, "ENGINE_FAILURE")); outcome.Status != StatusApplied || outcome.Drained != 1 {
+		t.Errorf("Expected the explosion to drain the relaunch, got %+v", outcome)
+	}
+	if rocket, _ := repo.GetRocket(ctx, "rocket-2"); rocket.Exploded || rocket.Mission != "APOLLO" {
+		t.Errorf("Expected rocket-2 to be relaunched, got %+v", rocket)
+	}
+
+	// A full quarantine drops its oldest message for each new one
+	repo.ProcessMessage(ctx, createLaunchMessage("rocket-3", 1, launchTime, "Falcon-9", 500, "ARTEMIS"))
+	repo.ProcessMessage(ctx, createExplodeMessage("rocket-3", 2, launchTime, "ENGINE_FAILURE"))
+	for n := 3; n < QuarantineCapacity+5; n++ {
+		repo.ProcessMessage(ctx, createSpeedIncreaseMessage("rocket-3", n, launchTime, 10))
+	}
+	state, _ = repo.Quarantine(ctx, "rocket-3")
+	if len(state.Messages) != QuarantineCapacity || state.Dropped != 2 || state.Messages[0].Envelope.GetMessageNumber() != 5 {
+		t.Errorf("Expected the latest %d messages from 5 and 2 dropped, got %d from %d and %d dropped",
+			QuarantineCapacity, len(state.Messages), state.Messages[0].Envelope.GetMessageNumber(), state.Dropped)
+	}
+
+	if _, err := repo.Quarantine(ctx, "missing"); !errors.Is(err, ErrRocketNotFound) {
+		t.Errorf("Expected ErrRocketNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package transport
+
+import (
+	"crypto/tls"
+	"net/http"
+	"sync/atomic"
+)
+
+// AnyChannel in an allow-list grants every channel
+const AnyChannel = "*"
+
+// ChannelPolicy restricts which channels a client certificate may post to,
+// keyed by the certificate subject common name. When the policy has entries,
+// verified certificates whose subject is not listed are denied. Requests
+// without a verified client certificate may only post to the channels that
+// no allow-list names, since the lists reserve those for their subjects.
+type ChannelPolicy struct {
+	lists atomic.Pointer[allowLists]
+}
+
+// allowLists holds the allowed channels of each subject, and the channels
+// named in any of them
+type allowLists struct {
+	allowed  map[string]map[string]bool
+	reserved map[string]bool
+}
+
+// NewChannelPolicy creates a policy from subject to allowed channels
+func NewChannelPolicy(allowed map[string][]string) *ChannelPolicy {
+	p := &ChannelPolicy{}
+	p.Update(allowed)
+	return p
+}
+
+// Update atomically replaces the allow-lists
+func (p *ChannelPolicy) Update(allowed map[string][]string) {
+	lists := &allowLists{
+		allowed:  make(map[string]map[string]bool, len(allowed)),
+		reserved: make(map[string]bool),
+	}
+	for subject, channels := range allowed {
+		set := make(map[string]bool, len(channels))
+		for _, channel := range channels {
+			set[channel] = true
+			if channel != AnyChannel {
+				lists.reserved[channel] = true
+			}
+		}
+		lists.allowed[subject] = set
+	}
+	p.lists.Store(lists)
+}
+
+// Allows reports whether the request may post to channel, along with the
+// certificate subject the decision was based on
+func (p *ChannelPolicy) Allows(r *http.Request, channel string) (subject string, ok bool) {
+	return p.AllowsConn(r.TLS, channel)
+}
+
+// AllowsConn is Allows for the state of a TLS connection, which is nil
+// for plaintext connections
+func (p *ChannelPolicy) AllowsConn(state *tls.ConnectionState, channel string) (subject string, ok bool) {
+	if p == nil {
+		return "", true
+	}
+	lists := p.lists.Load()
+	subject, verified := ConnSubject(state)
+	if !verified {
+		return "", !lists.reserved[channel]
+	}
+
+	allowed := lists.allowed
+	if len(allowed) == 0 {
+		return subject, true
+	}
+	channels, listed := allowed[subject]
+	if !listed {
+		return subject, false
+	}
+	return subject, channels[AnyChannel] || channels[channel]
+}
+
+// ClientSubject returns the common name of the verified client certificate, if any
+func ClientSubject(r *http.Request) (string, bool) {
+	return ConnSubject(r.TLS)
+}
+
+// ConnSubject returns the common name of the verified client certificate of
+// a TLS connection, if any
+func ConnSubject(state *tls.ConnectionState) (string, bool) {
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	return state.VerifiedChains[0][0].Subject.CommonName, true
+}
@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Client certificate modes
+const (
+	ClientAuthNone     = "none"     // Client certificates are not requested
+	ClientAuthOptional = "optional" // Verified when presented
+	ClientAuthRequire  = "require"  // Every client must present a valid certificate
+)
+
+// ParseClientAuth converts a client certificate mode into a tls.ClientAuthType
+func ParseClientAuth(mode string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(mode) {
+	case ClientAuthNone, "":
+		return tls.NoClientCert, nil
+	case ClientAuthOptional:
+		return tls.VerifyClientCertIfGiven, nil
+	case ClientAuthRequire:
+		return tls.RequireAndVerifyClientCert, nil
+	default:
+		return 0, fmt.Errorf("unknown client auth mode %q (expected none, optional or require)", mode)
+	}
+}
+
+// CertReloader serves a certificate, key and optional client CA bundle from
+// disk and picks up changes to those files without a restart
+type CertReloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	logger   *slog.Logger
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	clientCA *x509.CertPool
+	modTimes []time.Time
+}
+
+// NewCertReloader loads the files once; caFile may be empty when mTLS is not used
+func NewCertReloader(certFile, keyFile, caFile string, logger *slog.Logger) (*CertReloader, error) {
+	r := &CertReloader{certFile: certFile, keyFile: keyFile, caFile: caFile, logger: logger}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reloads the files if any of them changed since the last load.
+// On error the previous certificate stays in use.
+func (r *CertReloader) Reload() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	r.mu.RLock()
+	changed := !equalTimes(modTimes, r.modTimes)
+	r.mu.RUnlock()
+	if !changed {
+		return nil
+	}
+
+	if err := r.load(); err != nil {
+		return err
+	}
+	r.logger.Info("TLS certificates reloaded", "cert", r.certFile)
+	return nil
+}
+
+// Watch polls the files every interval until ctx is done
+func (r *CertReloader) Watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Reload(); err != nil {
+				r.logger.Error("TLS certificate reload failed, keeping the previous certificate", "error", err)
+			}
+		}
+	}
+}
+
+// TLSConfig returns a server configuration that always uses the latest
+// certificate and client CA bundle. It offers HTTP/2 and HTTP/1.1 through
+// ALPN, since the configuration chosen per client replaces the one the
+// server would otherwise have added them to.
+func (r *CertReloader) TLSConfig(clientAuth tls.ClientAuthType) *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+		ClientAuth: clientAuth,
+	}
+	config := base.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+
+		client := base.Clone()
+		client.Certificates = []tls.Certificate{*r.cert}
+		client.ClientCAs = r.clientCA
+		return client, nil
+	}
+	return config
+}
+
+// load reads all files and swaps them in together
+func (r *CertReloader) load() error {
+	modTimes, err := r.stat()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading TLS key pair: %w", err)
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("reading client CA file: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("client CA file contains no PEM certificates")
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.clientCA = pool
+	r.modTimes = modTimes
+	return nil
+}
+
+// stat returns the modification times of the watched files
+func (r *CertReloader) stat() ([]time.Time, error) {
+	var times []time.Time
+	for _, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, info.ModTime())
+	}
+	return times, nil
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
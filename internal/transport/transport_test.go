@@ -0,0 +1,299 @@
+package transport
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA is a throwaway certificate authority for issuing test certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate CA key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "lunar test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create CA certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue returns PEM encoded certificate and key signed by the CA
+func (ca *testCA) issue(t *testing.T, commonName string, serial int64, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writePEM(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+// clientFor returns an HTTPS client trusting ca and presenting the given certificate, if any
+func clientFor(t *testing.T, ca *testCA, certPEM, keyPEM []byte) *http.Client {
+	t.Helper()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	config := &tls.Config{RootCAs: roots}
+	if certPEM != nil {
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			t.Fatalf("Failed to load client key pair: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: config, DisableKeepAlives: true}}
+}
+
+func TestParseClientAuth(t *testing.T) {
+	for mode, expected := range map[string]tls.ClientAuthType{
+		"":         tls.NoClientCert,
+		"none":     tls.NoClientCert,
+		"optional": tls.VerifyClientCertIfGiven,
+		"REQUIRE":  tls.RequireAndVerifyClientCert,
+	} {
+		got, err := ParseClientAuth(mode)
+		if err != nil || got != expected {
+			t.Errorf("Expected %v for %q, got %v (%v)", expected, mode, got, err)
+		}
+	}
+	if _, err := ParseClientAuth("always"); err == nil {
+		t.Errorf("Expected an error for an unknown mode")
+	}
+}
+
+func TestMutualTLSChannelPolicy(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "lunar", 2, x509.ExtKeyUsageServerAuth)
+	certFile, keyFile, caFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.pem")
+	writePEM(t, certFile, serverCert)
+	writePEM(t, keyFile, serverKey)
+	writePEM(t, caFile, ca.pem)
+
+	reloader, err := NewCertReloader(certFile, keyFile, caFile, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+
+	policy := NewChannelPolicy(map[string][]string{
+		"emitter-1": {"channel-a"},
+		"operator":  {AnyChannel},
+	})
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := policy.Allows(r, r.URL.Query().Get("channel")); !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = reloader.TLSConfig(tls.RequireAndVerifyClientCert)
+	server.StartTLS()
+	defer server.Close()
+
+	emitterCert, emitterKey := ca.issue(t, "emitter-1", 3, x509.ExtKeyUsageClientAuth)
+	operatorCert, operatorKey := ca.issue(t, "operator", 4, x509.ExtKeyUsageClientAuth)
+	strangerCert, strangerKey := ca.issue(t, "stranger", 5, x509.ExtKeyUsageClientAuth)
+
+	tests := []struct {
+		name     string
+		client   *http.Client
+		channel  string
+		expected int
+	}{
+		{"allowed channel", clientFor(t, ca, emitterCert, emitterKey), "channel-a", http.StatusOK},
+		{"other channel", clientFor(t, ca, emitterCert, emitterKey), "channel-b", http.StatusForbidden},
+		{"wildcard", clientFor(t, ca, operatorCert, operatorKey), "channel-b", http.StatusOK},
+		{"unlisted subject", clientFor(t, ca, strangerCert, strangerKey), "channel-a", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := tt.client.Get(server.URL + "/?channel=" + tt.channel)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, resp.StatusCode)
+			}
+		})
+	}
+
+	// Without a verified client certificate, as with optional client
+	// authentication or plaintext, channels named in an allow-list are denied
+	for channel, expected := range map[string]bool{"channel-a": false, "channel-b": true} {
+		if _, ok := policy.AllowsConn(nil, channel); ok != expected {
+			t.Errorf("Expected %s to be allowed without a certificate: %t, got %t", channel, expected, ok)
+		}
+		if _, ok := policy.AllowsConn(&tls.ConnectionState{}, channel); ok != expected {
+			t.Errorf("Expected %s to be allowed with an unverified connection: %t, got %t", channel, expected, ok)
+		}
+	}
+
+	// Without a client certificate the handshake fails
+	if resp, err := clientFor(t, ca, nil, nil).Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("Expected the handshake to fail without a client certificate")
+	}
+
+	// Certificates signed by another CA are refused
+	other := newTestCA(t)
+	foreignCert, foreignKey := other.issue(t, "emitter-1", 6, x509.ExtKeyUsageClientAuth)
+	if resp, err := clientFor(t, ca, foreignCert, foreignKey).Get(server.URL); err == nil {
+		resp.Body.Close()
+		t.Errorf("Expected the handshake to fail with a certificate from another CA")
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	cert, key := ca.issue(t, "lunar", 10, x509.ExtKeyUsageServerAuth)
+	writePEM(t, certFile, cert)
+	writePEM(t, keyFile, key)
+
+	reloader, err := NewCertReloader(certFile, keyFile, "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = reloader.TLSConfig(tls.NoClientCert)
+	server.StartTLS()
+	defer server.Close()
+
+	servedSerial := func() int64 {
+		t.Helper()
+		resp, err := clientFor(t, ca, nil, nil).Get(server.URL)
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.TLS.PeerCertificates[0].SerialNumber.Int64()
+	}
+
+	if serial := servedSerial(); serial != 10 {
+		t.Fatalf("Expected serial 10, got %d", serial)
+	}
+
+	// A rotated certificate is served once the files change
+	cert, key = ca.issue(t, "lunar", 11, x509.ExtKeyUsageServerAuth)
+	writePEM(t, certFile, cert)
+	writePEM(t, keyFile, key)
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, future, future)
+	if err := reloader.Reload(); err != nil {
+		t.Fatalf("Expected reload to succeed, got %v", err)
+	}
+	if serial := servedSerial(); serial != 11 {
+		t.Errorf("Expected serial 11 after reload, got %d", serial)
+	}
+
+	// A broken key pair is rejected and the previous certificate kept
+	writePEM(t, keyFile, []byte("not a key"))
+	future = future.Add(time.Minute)
+	os.Chtimes(keyFile, future, future)
+	if err := reloader.Reload(); err == nil {
+		t.Errorf("Expected reload to fail with an invalid key")
+	}
+	if serial := servedSerial(); serial != 11 {
+		t.Errorf("Expected serial 11 to be kept, got %d", serial)
+	}
+}
+
+func TestTLSConfigNegotiatesHTTP2(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := filepath.Join(dir, "server.pem"), filepath.Join(dir, "server.key")
+	cert, key := ca.issue(t, "lunar", 20, x509.ExtKeyUsageServerAuth)
+	writePEM(t, certFile, cert)
+	writePEM(t, keyFile, key)
+
+	reloader, err := NewCertReloader(certFile, keyFile, "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatalf("Failed to load certificates: %v", err)
+	}
+
+	// Served like the service does, with http.Server.ServeTLS
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &http.Server{
+		Handler:   http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		TLSConfig: reloader.TLSConfig(tls.NoClientCert),
+	}
+	go server.ServeTLS(listener, "", "")
+	defer server.Close()
+
+	client := clientFor(t, ca, nil, nil)
+	client.Transport.(*http.Transport).ForceAttemptHTTP2 = true
+	resp, err := client.Get("https://" + listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.ProtoMajor != 2 || resp.TLS.NegotiatedProtocol != "h2" {
+		t.Errorf("Expected HTTP/2 to be negotiated, got %s over %q", resp.Proto, resp.TLS.NegotiatedProtocol)
+	}
+}
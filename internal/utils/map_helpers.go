@@ -1,5 +1,11 @@
 package utils
 
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
 
 func GetStringValue(data map[string]any, key string) string {
 	if value, ok := data[key].(string); ok {
@@ -10,15 +16,25 @@ func GetStringValue(data map[string]any, key string) string {
 
 
 func GetIntValue(data map[string]any, key string) int {
-	if v, ok := data[key]; ok {
-		switch value := v.(type) {
-		case float64:
-			return int(value)
-		case int:
-			return value
-		case int64:
-			return int(value)
-		}
+	n, _ := CoerceInt(data[key])
+	return n
+}
+
+// CoerceInt converts JSON-decoded numbers, and integers sent as strings, to int
+func CoerceInt(v any) (int, bool) {
+	switch value := v.(type) {
+	case float64:
+		return int(value), true
+	case int:
+		return value, true
+	case int64:
+		return int(value), true
+	case json.Number:
+		n, err := strconv.Atoi(value.String())
+		return n, err == nil
+	case string:
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		return n, err == nil
 	}
-	return 0
+	return 0, false
 }
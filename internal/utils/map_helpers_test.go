@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -109,6 +110,22 @@ func TestGetIntValue(t *testing.T) {
 			key:           "speed",
 			expectedValue: 50,
 		},
+		{
+			name: "Value is a numeric string",
+			data: map[string]any{
+				"speed": " 250",
+			},
+			key:           "speed",
+			expectedValue: 250,
+		},
+		{
+			name: "Value is json.Number",
+			data: map[string]any{
+				"speed": json.Number("75"),
+			},
+			key:           "speed",
+			expectedValue: 75,
+		},
 	}
 
 	// Run tests